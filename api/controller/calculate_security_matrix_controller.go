@@ -1,10 +1,16 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
+	middleware "github.com/nuno-bastos/gin-gonic-wire-api/api/middleware"
+	logging "github.com/nuno-bastos/gin-gonic-wire-api/logging"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
 	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
 )
 
@@ -21,13 +27,58 @@ func NewCalculateGoSecurityMatrixController(service services.SecurityMatrixServi
 // CalculateGoSecurityMatrix handles the HTTP POST request to calculate the security matrix.
 // It delegates the security matrix calculation to the service layer, which writes the calculated rules to the database,
 // and responds with a success message.
+// When data quality enforcement rejects the inputs, it responds with the data quality report instead.
+// When the server shuts down before the calculation completes, the calculation is rolled back and it responds with 503,
+// with 504 when a timeout of the calculation elapsed. Other failures respond with 500 and a generic message, the error
+// being logged with the request and run IDs.
+// Every response carries the run_id of the calculation, the ID found on the log lines of the run.
 //
 // Parameters:
 // - c: Context object representing the HTTP request and response.
 func (p *CalculateSecurityMatrixController) CalculateGoSecurityMatrix(c *gin.Context) {
-	p._service.CalculateGoSecurityMatrix(c.Request.Context())
+	requested_by := "anonymous"
+	if caller, found := middleware.GetCaller(c); found {
+		requested_by = caller.Principal.String() + " (user " + caller.User.Id + ")"
+	} else if principal, found := middleware.GetPrincipal(c); found {
+		requested_by = principal.String()
+	}
+	run_id := uuid.NewString()
+	ctx := logging.WithRunId(c.Request.Context(), run_id)
+	middleware.RequestLogger(c).InfoContext(ctx, "Security matrix calculation requested", "requested_by", requested_by)
+
+	if err := p._service.CalculateGoSecurityMatrix(ctx); err != nil {
+		var dataQualityErr *service.DataQualityError
+		if errors.As(err, &dataQualityErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":  err.Error(),
+				"run_id": run_id,
+				"report": dataQualityErr.Report,
+			})
+			return
+		}
+
+		// The timeouts are named, the other errors are only logged: they may hold SQL or driver messages
+		var timeout_err *service.TimeoutError
+		if errors.As(err, &timeout_err) {
+			respondWithError(c, ctx, http.StatusGatewayTimeout, timeout_err.Error()+", the calculation was rolled back", err, gin.H{"run_id": run_id})
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			respondWithError(c, ctx, http.StatusGatewayTimeout, "the calculation timed out and was rolled back", err, gin.H{"run_id": run_id})
+			return
+		}
+
+		if errors.Is(err, context.Canceled) {
+			respondWithError(c, ctx, http.StatusServiceUnavailable, "the calculation was interrupted and rolled back", err, gin.H{"run_id": run_id})
+			return
+		}
+
+		respondWithError(c, ctx, http.StatusInternalServerError, "the calculation failed", err, gin.H{"run_id": run_id})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Security Matrix Rules generated and written successfully :)",
+		"run_id":  run_id,
 	})
 }
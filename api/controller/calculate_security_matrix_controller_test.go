@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	middleware "github.com/nuno-bastos/gin-gonic-wire-api/api/middleware"
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	logging "github.com/nuno-bastos/gin-gonic-wire-api/logging"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+// failingSecurityMatrixService fails every calculation with its error.
+type failingSecurityMatrixService struct {
+	services.SecurityMatrixService
+	err error
+}
+
+func (p *failingSecurityMatrixService) CalculateGoSecurityMatrix(ctx context.Context) error {
+	return p.err
+}
+
+func TestCalculateGoSecurityMatrixDoesNotReturnInternalErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver_err := errors.New(`mssql: Invalid object name 'dbo.Employees' in SELECT * FROM "dbo"."Employees"`)
+
+	for _, test := range []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"driver error", fmt.Errorf("reading the employees: %w", driver_err), http.StatusInternalServerError, "the calculation failed"},
+		{"interrupted", fmt.Errorf("%w: %w", driver_err, context.Canceled), http.StatusServiceUnavailable, "the calculation was interrupted and rolled back"},
+		{"timed out", fmt.Errorf("%w: %w", driver_err, &service.TimeoutError{Stage: "read phase", Timeout: time.Second}),
+			http.StatusGatewayTimeout, "the read phase timed out after 1s, the calculation was rolled back"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var output bytes.Buffer
+			logger, err := logging.NewLoggerTo(&output, &db.Config{})
+			require.NoError(t, err)
+
+			engine := gin.New()
+			engine.Use(middleware.RequestId(logger))
+			engine.POST("/calculate", NewCalculateGoSecurityMatrixController(&failingSecurityMatrixService{err: test.err}).CalculateGoSecurityMatrix)
+
+			request := httptest.NewRequest(http.MethodPost, "/calculate", nil)
+			request.Header.Set(middleware.RequestIdHeader, "job-42")
+			response := httptest.NewRecorder()
+			engine.ServeHTTP(response, request)
+
+			// The client gets a generic message and the IDs to look the error up in the logs
+			assert.Equal(t, test.status, response.Code)
+			assert.NotContains(t, response.Body.String(), "dbo.Employees")
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+			assert.Equal(t, test.message, body["error"])
+			assert.Equal(t, "job-42", body["request_id"])
+			assert.NotEmpty(t, body["run_id"])
+
+			assert.Contains(t, output.String(), "dbo.Employees")
+			assert.Contains(t, output.String(), `"request_id":"job-42"`)
+		})
+	}
+}
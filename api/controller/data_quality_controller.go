@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+type DataQualityController struct {
+	_service services.DataQualityService
+}
+
+func NewDataQualityController(service services.DataQualityService) *DataQualityController {
+	return &DataQualityController{
+		_service: service,
+	}
+}
+
+// GetDataQualityReport handles the HTTP GET request returning the data quality report.
+// It lists every referential-integrity issue between Users, Employees, Profiles and ManagerChains.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+func (p *DataQualityController) GetDataQualityReport(c *gin.Context) {
+	report, err := p._service.GenerateDataQualityReport(c.Request.Context())
+	if err != nil {
+		respondWithError(c, c.Request.Context(), http.StatusInternalServerError, "error generating the data quality report", err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	middleware "github.com/nuno-bastos/gin-gonic-wire-api/api/middleware"
+	logging "github.com/nuno-bastos/gin-gonic-wire-api/logging"
+)
+
+// respondWithError logs the error of a failed request and responds with a generic message and the request ID,
+// under which the error is found in the logs. The error itself is never sent: it may hold SQL statements or
+// messages of the database driver.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+// - ctx: The context of the failed operation, whose correlation IDs are logged.
+// - status: The HTTP status of the response.
+// - message: The message of the response, also logged.
+// - err: The error of the operation, only logged.
+// - fields: Extra fields of the response (e.g. the run_id), nil when there is none.
+func respondWithError(c *gin.Context, ctx context.Context, status int, message string, err error, fields gin.H) {
+	middleware.RequestLogger(c).ErrorContext(ctx, "Request failed", "status", status, "message", message, "error", err)
+
+	body := gin.H{
+		"error":      message,
+		"request_id": logging.RequestId(ctx),
+	}
+	for key, value := range fields {
+		body[key] = value
+	}
+	c.JSON(status, body)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+type HealthController struct {
+	_service services.HealthService
+}
+
+func NewHealthController(service services.HealthService) *HealthController {
+	return &HealthController{
+		_service: service,
+	}
+}
+
+// Healthz handles the liveness probe: it answers as long as the process serves HTTP requests.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+func (p *HealthController) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
+
+// Readyz handles the readiness probe: 200 when the database answers and the tables are migrated,
+// 503 Service Unavailable with the failed checks otherwise.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+func (p *HealthController) Readyz(c *gin.Context) {
+	report := p._service.CheckReadiness(c.Request.Context())
+	if !report.Ready() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not ready",
+			"checks": report.Checks,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ready",
+		"checks": report.Checks,
+	})
+}
+
+// GetFreshness handles the HTTP GET request reporting when the last successful calculation finished,
+// how many rules it wrote and whether the matrix is stale. Staleness is reported in the body, with 200.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+func (p *HealthController) GetFreshness(c *gin.Context) {
+	report, err := p._service.CheckFreshness(c.Request.Context())
+	if err != nil {
+		respondWithError(c, c.Request.Context(), http.StatusInternalServerError, "error checking the freshness", err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+)
+
+type MetricsController struct {
+	handler http.Handler
+}
+
+func NewMetricsController(_metrics *metrics.Metrics) *MetricsController {
+	return &MetricsController{
+		handler: promhttp.HandlerFor(_metrics.Registry, promhttp.HandlerOpts{Registry: _metrics.Registry}),
+	}
+}
+
+// GetMetrics serves the metrics in the Prometheus exposition format.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+func (p *MetricsController) GetMetrics(c *gin.Context) {
+	p.handler.ServeHTTP(c.Writer, c.Request)
+}
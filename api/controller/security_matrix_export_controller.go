@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	middleware "github.com/nuno-bastos/gin-gonic-wire-api/api/middleware"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+type SecurityMatrixExportController struct {
+	_service services.SecurityMatrixExportService
+}
+
+func NewSecurityMatrixExportController(service services.SecurityMatrixExportService) *SecurityMatrixExportController {
+	return &SecurityMatrixExportController{
+		_service: service,
+	}
+}
+
+// ExportGoSecurityMatrix handles the HTTP GET request streaming the stored security matrix as a file download.
+// The query parameters select the format ("format": csv, jsonl or parquet, csv by default) and the extra columns
+// ("enrich": user email and employee number, "decode_access": a boolean column per access level bit).
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+func (p *SecurityMatrixExportController) ExportGoSecurityMatrix(c *gin.Context) {
+	options, err := exportOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", options.Format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="GoMatrixRule.`+string(options.Format)+`"`)
+	c.Status(http.StatusOK)
+
+	// The status is sent with the first bytes: a failure while streaming can only cut the download short
+	if err := p._service.Export(c.Request.Context(), c.Writer, options); err != nil {
+		middleware.RequestLogger(c).ErrorContext(c.Request.Context(), "Security matrix export interrupted", "error", err)
+		c.Abort()
+	}
+}
+
+// GetUserSecurityMatrixRules handles the HTTP GET request returning the stored rules of the viewer user
+// named by the "user_id" path parameter: the employees the user sees and with which access level.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+func (p *SecurityMatrixExportController) GetUserSecurityMatrixRules(c *gin.Context) {
+	user_id := c.Param("user_id")
+
+	rules, err := p._service.UserRules(c.Request.Context(), user_id)
+	if err != nil {
+		respondWithError(c, c.Request.Context(), http.StatusInternalServerError, "error reading the rules of the user", err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id": user_id,
+		"rules":   rules,
+	})
+}
+
+// exportOptions parses the export options from the query parameters.
+func exportOptions(c *gin.Context) (security_model.ExportOptions, error) {
+	var options security_model.ExportOptions
+
+	format, err := security_model.ParseExportFormat(c.DefaultQuery("format", string(security_model.ExportFormatCSV)))
+	if err != nil {
+		return options, err
+	}
+	options.Format = format
+
+	for name, value := range map[string]*bool{"enrich": &options.Enrich, "decode_access": &options.DecodeAccess} {
+		if query, found := c.GetQuery(name); found {
+			if *value, err = strconv.ParseBool(query); err != nil {
+				return options, err
+			}
+		}
+	}
+
+	return options, nil
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+type SecurityMatrixParityController struct {
+	_service services.SecurityMatrixParityService
+}
+
+func NewSecurityMatrixParityController(service services.SecurityMatrixParityService) *SecurityMatrixParityController {
+	return &SecurityMatrixParityController{
+		_service: service,
+	}
+}
+
+// GetSecurityMatrixParity handles the HTTP GET request comparing the legacy matrix with GoMatrixRule.
+// The "source" query parameter selects the compared matrix: "stored" (default) reads GoMatrixRule,
+// "calculated" calculates a fresh matrix in memory without writing it.
+// It responds with the parity report, whose mismatches are grouped by user and by contributing filter.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+func (p *SecurityMatrixParityController) GetSecurityMatrixParity(c *gin.Context) {
+	source, err := security_model.ParseParitySource(c.DefaultQuery("source", string(security_model.ParitySourceStored)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	report, err := p._service.CheckParity(c.Request.Context(), source)
+	if err != nil {
+		respondWithError(c, c.Request.Context(), http.StatusInternalServerError, "error checking the parity", err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog returns the middleware logging every request once served, with its principal ("-" when unauthenticated).
+// Server errors are logged at the error level.
+//
+// Parameters:
+// - _logger: The logger of the application.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func AccessLog(_logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started_at := time.Now()
+		c.Next()
+
+		principal := "-"
+		if authenticated, found := GetPrincipal(c); found {
+			principal = authenticated.String()
+		}
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attributes := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("duration", time.Since(started_at)),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("principal", principal),
+		}
+		if len(c.Errors) > 0 {
+			attributes = append(attributes, slog.String("errors", c.Errors.String()))
+		}
+
+		_logger.LogAttrs(c.Request.Context(), level, "Request served", attributes...)
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// APIKeyHeader is the request header carrying an API key.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator authenticates requests carrying one of the configured static API keys.
+type APIKeyAuthenticator struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	name   string
+	digest [sha256.Size]byte
+}
+
+// NewAPIKeyAuthenticator creates an APIKeyAuthenticator accepting the given keys.
+//
+// Parameters:
+// - keys: The accepted keys and the names of their principals.
+//
+// Returns:
+// - *APIKeyAuthenticator: The authenticator.
+// - error: An error if a key or a name is empty, or a key is configured twice.
+func NewAPIKeyAuthenticator(keys []db.APIKeyConfig) (*APIKeyAuthenticator, error) {
+	authenticator := &APIKeyAuthenticator{}
+	seen := make(map[string]bool)
+
+	for i, key := range keys {
+		if key.Name == "" || key.Key == "" {
+			return nil, fmt.Errorf("auth.api_keys[%d] needs both a name and a key", i)
+		}
+		if seen[key.Key] {
+			return nil, fmt.Errorf("auth.api_keys[%d] (%s) reuses the key of another entry", i, key.Name)
+		}
+		seen[key.Key] = true
+
+		authenticator.keys = append(authenticator.keys, apiKey{name: key.Name, digest: sha256.Sum256([]byte(key.Key))})
+	}
+
+	return authenticator, nil
+}
+
+// Scheme returns the scheme announced for API keys.
+func (a *APIKeyAuthenticator) Scheme() string {
+	return `ApiKey header="` + APIKeyHeader + `"`
+}
+
+// Authenticate returns the principal named after the key of the X-API-Key header.
+// Keys are compared through their SHA-256 digests in constant time.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	var name string
+	for _, configured := range a.keys {
+		if subtle.ConstantTimeCompare(digest[:], configured.digest[:]) == 1 {
+			name = configured.name
+		}
+	}
+	if name == "" {
+		return nil, errors.New("unknown API key")
+	}
+
+	return &Principal{Subject: name, Method: AuthMethodAPIKey}, nil
+}
@@ -0,0 +1,135 @@
+// Package middleware provides the gin middleware of the API.
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// Authentication methods of a Principal.
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+// principalKey is the gin context key of the authenticated Principal.
+const principalKey = "principal"
+
+// ErrNoCredentials is returned by an Authenticator when the request carries no credentials it handles.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string                 // Name of the API key, or "sub" claim of the JWT
+	Method  string                 // AuthMethodAPIKey or AuthMethodJWT
+	Claims  map[string]interface{} // Claims of the JWT, nil for API keys
+}
+
+// String identifies the principal in logs, e.g. "jwt:alice".
+func (p *Principal) String() string {
+	return p.Method + ":" + p.Subject
+}
+
+// Authenticator authenticates requests carrying one kind of credentials.
+type Authenticator interface {
+	// Scheme is the authentication scheme announced in the WWW-Authenticate header of a 401 response.
+	Scheme() string
+	// Authenticate returns the principal of the request, ErrNoCredentials when the request carries none
+	// of the credentials handled by the Authenticator, or an error when they are invalid.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// ProvideAuthenticators creates the authenticators enabled by the configuration: API keys when any is configured,
+// JWT bearer tokens when a JWKS URL is configured.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - []Authenticator: The enabled authenticators, none when the API is open.
+// - error: An error if the configuration is invalid.
+func ProvideAuthenticators(config *db.Config) ([]Authenticator, error) {
+	var authenticators []Authenticator
+
+	if len(config.Auth.APIKeys) > 0 {
+		authenticator, err := NewAPIKeyAuthenticator(config.Auth.APIKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+
+	if config.Auth.JWT.JWKSURL != "" {
+		authenticators = append(authenticators, NewJWTAuthenticator(config.Auth.JWT, http.DefaultClient))
+	}
+
+	return authenticators, nil
+}
+
+// Authenticate returns the middleware authenticating every request with the first authenticator whose
+// credentials it carries, and putting the Principal on the gin context (see GetPrincipal).
+// Requests without credentials, or with invalid ones, are rejected with 401 Unauthorized. Why credentials are invalid
+// is logged, not returned to the client.
+//
+// Parameters:
+// - authenticators: The enabled authenticators.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func Authenticate(authenticators []Authenticator) gin.HandlerFunc {
+	var schemes []string
+	for _, authenticator := range authenticators {
+		schemes = append(schemes, authenticator.Scheme())
+	}
+	challenge := strings.Join(schemes, ", ")
+
+	return func(c *gin.Context) {
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(c.Request)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				RequestLogger(c).WarnContext(c.Request.Context(), "Authentication failed",
+					"scheme", authenticator.Scheme(), "client_ip", c.ClientIP(), "error", err)
+				c.Header("WWW-Authenticate", challenge)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error": "invalid credentials",
+				})
+				return
+			}
+
+			c.Set(principalKey, principal)
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", challenge)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+		})
+	}
+}
+
+// GetPrincipal returns the Principal authenticated for the request, if any.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+//
+// Returns:
+// - *Principal: The authenticated principal.
+// - bool: false when the request is not authenticated (e.g. the API is open).
+func GetPrincipal(c *gin.Context) (*Principal, bool) {
+	value, found := c.Get(principalKey)
+	if !found {
+		return nil, false
+	}
+
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
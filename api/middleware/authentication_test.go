@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/test/jwkstest"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "security-matrix-api"
+)
+
+// newEngine serves /whoami behind the authentication middleware, answering with the principal.
+func newEngine(t *testing.T, config *db.Config) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	authenticators, err := ProvideAuthenticators(config)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	engine.Use(Authenticate(authenticators))
+	engine.GET("/whoami", func(c *gin.Context) {
+		principal, found := GetPrincipal(c)
+		require.True(t, found)
+		c.JSON(http.StatusOK, principal)
+	})
+
+	return engine
+}
+
+func request(engine *gin.Engine, headers map[string]string) *httptest.ResponseRecorder {
+	return requestPath(engine, "/whoami", headers)
+}
+
+func requestPath(engine *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func bearer(token string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
+func claims(subject string, expires_in time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Subject:   subject,
+		Issuer:    testIssuer,
+		Audience:  jwt.ClaimStrings{testAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expires_in)),
+	}
+}
+
+func jwtConfig(jwks *jwkstest.Server) *db.Config {
+	return &db.Config{Auth: db.AuthConfig{
+		APIKeys: []db.APIKeyConfig{{Name: "scheduler", Key: "s3cr3t"}},
+		JWT:     db.JWTConfig{JWKSURL: jwks.URL(), Issuer: testIssuer, Audience: testAudience},
+	}}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	engine := newEngine(t, &db.Config{Auth: db.AuthConfig{APIKeys: []db.APIKeyConfig{
+		{Name: "scheduler", Key: "s3cr3t"},
+		{Name: "reporting", Key: "an0ther"},
+	}}})
+
+	response := request(engine, map[string]string{APIKeyHeader: "an0ther"})
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.JSONEq(t, `{"Subject":"reporting","Method":"api_key","Claims":null}`, response.Body.String())
+
+	response = request(engine, map[string]string{APIKeyHeader: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.JSONEq(t, `{"error":"invalid credentials"}`, response.Body.String())
+
+	response = request(engine, nil)
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Equal(t, `ApiKey header="X-API-Key"`, response.Header().Get("WWW-Authenticate"))
+}
+
+func TestAPIKeyConfigurationErrors(t *testing.T) {
+	for name, keys := range map[string][]db.APIKeyConfig{
+		"missing name": {{Key: "s3cr3t"}},
+		"missing key":  {{Name: "scheduler"}},
+		"reused key":   {{Name: "scheduler", Key: "s3cr3t"}, {Name: "reporting", Key: "s3cr3t"}},
+	} {
+		_, err := ProvideAuthenticators(&db.Config{Auth: db.AuthConfig{APIKeys: keys}})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	engine := newEngine(t, jwtConfig(jwks))
+
+	for _, key := range jwks.Keys() {
+		response := request(engine, bearer(jwks.Sign(t, key, claims("alice", time.Hour))))
+		require.Equal(t, http.StatusOK, response.Code, key.Method.Alg()+": "+response.Body.String())
+		assert.Contains(t, response.Body.String(), `"Subject":"alice","Method":"jwt"`)
+	}
+	assert.Equal(t, 1, jwks.Fetches(), "the key set is cached")
+
+	// API keys keep working next to bearer tokens
+	response := request(engine, map[string]string{APIKeyHeader: "s3cr3t"})
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = request(engine, nil)
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Equal(t, `ApiKey header="X-API-Key", Bearer`, response.Header().Get("WWW-Authenticate"))
+}
+
+func TestJWTAuthenticationRejectsInvalidTokens(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	engine := newEngine(t, jwtConfig(jwks))
+	key := jwks.Keys()[0]
+
+	expired := claims("alice", -time.Minute)
+	wrong_audience := claims("alice", time.Hour)
+	wrong_audience.Audience = jwt.ClaimStrings{"another-api"}
+	wrong_issuer := claims("alice", time.Hour)
+	wrong_issuer.Issuer = "https://evil.example.com"
+	no_expiry := claims("alice", time.Hour)
+	no_expiry.ExpiresAt = nil
+	no_subject := claims("", time.Hour)
+
+	valid := jwks.Sign(t, key, claims("alice", time.Hour))
+	other_jwks := jwkstest.NewServer(t)
+
+	for name, token := range map[string]string{
+		"expired":        jwks.Sign(t, key, expired),
+		"wrong audience": jwks.Sign(t, key, wrong_audience),
+		"wrong issuer":   jwks.Sign(t, key, wrong_issuer),
+		"no expiry":      jwks.Sign(t, key, no_expiry),
+		"no subject":     jwks.Sign(t, key, no_subject),
+		"bad signature":  valid[:len(valid)-4] + "AAAA",
+		"foreign key":    other_jwks.Sign(t, other_jwks.Keys()[0], claims("alice", time.Hour)),
+		"not a jwt":      "not-a-jwt",
+	} {
+		response := request(engine, bearer(token))
+		assert.Equal(t, http.StatusUnauthorized, response.Code, name)
+		assert.JSONEq(t, `{"error":"invalid credentials"}`, response.Body.String(), name)
+	}
+}
+
+func TestJWTAuthenticationRejectsUnexpectedAlgorithms(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	config := jwtConfig(jwks)
+	config.Auth.JWT.Algorithms = []string{"ES256"}
+	engine := newEngine(t, config)
+
+	for _, key := range jwks.Keys() {
+		response := request(engine, bearer(jwks.Sign(t, key, claims("alice", time.Hour))))
+		if key.Method == jwt.SigningMethodES256 {
+			assert.Equal(t, http.StatusOK, response.Code)
+		} else {
+			assert.Equal(t, http.StatusUnauthorized, response.Code, key.Method.Alg())
+		}
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	authenticator := NewJWTAuthenticator(jwtConfig(jwks).Auth.JWT, http.DefaultClient)
+	now := time.Now()
+	authenticator.keys.now = func() time.Time { return now }
+
+	authenticate := func(token string) error {
+		request := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		_, err := authenticator.Authenticate(request)
+		return err
+	}
+
+	require.NoError(t, authenticate(jwks.Sign(t, jwks.Keys()[0], claims("alice", time.Hour))))
+	jwks.Rotate(t)
+	rotated := jwks.Sign(t, jwks.Keys()[0], claims("alice", time.Hour))
+
+	// Unknown key ids do not refetch the key set more than once per period
+	assert.ErrorContains(t, authenticate(rotated), "unknown key id")
+	assert.Equal(t, 1, jwks.Fetches())
+
+	now = now.Add(jwksMinRefetchInterval)
+	require.NoError(t, authenticate(rotated))
+	assert.Equal(t, 2, jwks.Fetches())
+
+	// The key set is refreshed in the background once the refresh interval elapsed
+	now = now.Add(15 * time.Minute)
+	require.NoError(t, authenticate(rotated))
+	assert.Eventually(t, func() bool { return jwks.Fetches() == 3 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestJWKSServesTheCachedKeysWhileRefreshing(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	authenticator := NewJWTAuthenticator(jwtConfig(jwks).Auth.JWT, http.DefaultClient)
+	var now atomic.Pointer[time.Time]
+	advance := func(duration time.Duration) {
+		next := now.Load().Add(duration)
+		now.Store(&next)
+	}
+	start := time.Now()
+	now.Store(&start)
+	authenticator.keys.now = func() time.Time { return *now.Load() }
+	token := jwks.Sign(t, jwks.Keys()[0], claims("alice", time.Hour))
+
+	authenticate := func() error {
+		request := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		_, err := authenticator.Authenticate(request)
+		return err
+	}
+	// refreshed waits for the background refresh to be over
+	refreshed := func() {
+		require.Eventually(t, func() bool {
+			authenticator.keys.mutex.Lock()
+			defer authenticator.keys.mutex.Unlock()
+			return authenticator.keys.fetching == nil
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	require.NoError(t, authenticate())
+
+	// A slow identity provider does not delay the requests
+	jwks.SetDelay(time.Second)
+	advance(15 * time.Minute)
+	started := time.Now()
+	require.NoError(t, authenticate())
+	require.NoError(t, authenticate())
+	assert.Less(t, time.Since(started), 500*time.Millisecond)
+	refreshed()
+	assert.Equal(t, 2, jwks.Fetches())
+
+	// Nor does a failing one, whose last key set keeps being served
+	jwks.SetDelay(0)
+	jwks.SetStatus(http.StatusServiceUnavailable)
+	advance(time.Hour)
+	require.NoError(t, authenticate())
+	refreshed()
+	assert.Equal(t, 3, jwks.Fetches())
+	assert.NoError(t, authenticate())
+	assert.Equal(t, 3, jwks.Fetches(), "failed fetches are not retried more than once per period")
+}
+
+func TestJWKSRejectsOversizedKeySets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[],"padding":"` + strings.Repeat("x", jwksMaxSize) + `"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := newJWKS(server.URL, http.DefaultClient, time.Minute).key(context.Background(), "key-1")
+
+	assert.ErrorContains(t, err, "returned more than 1048576 bytes")
+}
+
+func TestAccessLogIncludesPrincipal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var output bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&output, nil))
+
+	engine := gin.New()
+	engine.Use(AccessLog(logger))
+	authenticators, err := ProvideAuthenticators(&db.Config{Auth: db.AuthConfig{APIKeys: []db.APIKeyConfig{{Name: "scheduler", Key: "s3cr3t"}}}})
+	require.NoError(t, err)
+	engine.Use(Authenticate(authenticators))
+	engine.GET("/whoami", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	request(engine, map[string]string{APIKeyHeader: "s3cr3t"})
+	request(engine, nil)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 2)
+	var served, refused map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &served))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &refused))
+
+	assert.Equal(t, "Request served", served["msg"])
+	assert.Equal(t, "api_key:scheduler", served["principal"])
+	assert.Equal(t, "/whoami", served["route"])
+	assert.EqualValues(t, http.StatusNoContent, served["status"])
+	assert.Equal(t, "-", refused["principal"])
+	assert.EqualValues(t, http.StatusUnauthorized, refused["status"])
+}
@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// Fields of Security.Users a JWT claim can be matched with.
+const (
+	UserFieldEmail = "email"
+	UserFieldId    = "id"
+)
+
+// emailVerifiedClaim is the claim that must be true for an email to identify the user, as in OpenID Connect.
+const emailVerifiedClaim = "email_verified"
+
+// callerKey is the gin context key of the authorized Caller.
+const callerKey = "caller"
+
+// Caller is the Security.Users row an authenticated principal acts as.
+type Caller struct {
+	Principal *Principal
+	User      *model.User // With its profiles
+}
+
+// HasProfileType reports whether the user holds a profile of one of the given types.
+func (p *Caller) HasProfileType(profile_type_ids ...string) bool {
+	for _, profile := range p.User.Profiles {
+		for _, profile_type_id := range profile_type_ids {
+			if profile.ProfileTypeId == profile_type_id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Authorizer maps authenticated principals to their Security.Users row, whose profiles grant access to the API.
+type Authorizer struct {
+	userRepo      interfaces.UserRepository
+	apiKeyUsers   map[string]string // UserId of each API key, by name
+	userClaim     string            // JWT claim identifying the user
+	userField     string            // User field the claim is matched with
+	findUserByJWT func(ctx context.Context, value string) (*model.User, error)
+}
+
+// NewAuthorizer creates an Authorizer. API keys act as the user configured with them, JWT principals as the user
+// whose email (or id) is the value of the user claim (the "email" claim by default). Emails must be verified by the
+// issuer: tokens matched by email without an email_verified claim set to true act as no user.
+//
+// Parameters:
+// - _userRepo: The UserRepository the users are read from.
+// - config: The application configuration.
+//
+// Returns:
+// - *Authorizer: The authorizer.
+// - error: An error if auth.jwt.user_field is neither email nor id.
+func NewAuthorizer(_userRepo interfaces.UserRepository, config *db.Config) (*Authorizer, error) {
+	authorizer := &Authorizer{
+		userRepo:    _userRepo,
+		apiKeyUsers: make(map[string]string),
+		userClaim:   config.Auth.JWT.UserClaim,
+		userField:   config.Auth.JWT.UserField,
+	}
+	if authorizer.userClaim == "" {
+		authorizer.userClaim = "email"
+	}
+	if authorizer.userField == "" {
+		authorizer.userField = UserFieldEmail
+	}
+
+	for _, api_key := range config.Auth.APIKeys {
+		authorizer.apiKeyUsers[api_key.Name] = api_key.UserId
+	}
+
+	switch authorizer.userField {
+	case UserFieldEmail:
+		authorizer.findUserByJWT = _userRepo.FindUserByEmail
+	case UserFieldId:
+		authorizer.findUserByJWT = _userRepo.FindUserById
+	default:
+		return nil, fmt.Errorf("unknown auth.jwt.user_field %q, expected %s or %s", config.Auth.JWT.UserField, UserFieldEmail, UserFieldId)
+	}
+
+	return authorizer, nil
+}
+
+// Identify returns the middleware putting the Caller of the authenticated principal on the gin context (see GetCaller).
+// It must run after Authenticate. Principals without a Security.Users row are rejected with 403 Forbidden, errors
+// reading the users with 500 Internal Server Error and a generic message, the detail being logged.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func (p *Authorizer) Identify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, found := GetPrincipal(c)
+		if !found {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "authentication required",
+			})
+			return
+		}
+
+		user, err := p.findUser(c.Request.Context(), principal)
+		if err != nil {
+			RequestLogger(c).ErrorContext(c.Request.Context(), "Authorization failed", "principal", principal.String(), "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "error identifying the caller",
+			})
+			return
+		}
+		if user == nil {
+			RequestLogger(c).WarnContext(c.Request.Context(), "Authorization refused: not a known user", "principal", principal.String())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": principal.String() + " is not a known user",
+			})
+			return
+		}
+
+		c.Set(callerKey, &Caller{Principal: principal, User: user})
+		c.Next()
+	}
+}
+
+// findUser returns the user the principal acts as, nil if there is none.
+func (p *Authorizer) findUser(ctx context.Context, principal *Principal) (*model.User, error) {
+	switch principal.Method {
+	case AuthMethodAPIKey:
+		user_id := p.apiKeyUsers[principal.Subject]
+		if user_id == "" {
+			return nil, nil
+		}
+		return p.userRepo.FindUserById(ctx, user_id)
+
+	case AuthMethodJWT:
+		value, _ := principal.Claims[p.userClaim].(string)
+		if value == "" {
+			return nil, nil
+		}
+		// Anyone can put an address they do not own in the email claim of a token: only the verified ones map to a user
+		if p.userField == UserFieldEmail && principal.Claims[emailVerifiedClaim] != true {
+			return nil, nil
+		}
+		user, err := p.findUserByJWT(ctx, value)
+		if err != nil {
+			return nil, fmt.Errorf("error finding the user by %s: %w", p.userField, err)
+		}
+		return user, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// RequireProfileType returns the middleware rejecting with 403 Forbidden the callers holding
+// no profile of the given types, and the requests without a caller. It must run after Identify.
+//
+// Parameters:
+// - profile_type_ids: The accepted profile types, see service.NewProfileTypes.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func RequireProfileType(profile_type_ids ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, found := GetCaller(c)
+		if !found || !caller.HasProfileType(profile_type_ids...) {
+			forbid(c, caller, "this endpoint requires another profile")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSelfOrAdmin returns the middleware rejecting with 403 Forbidden the callers asking for another user
+// than themselves, as named by the path parameter, unless they hold an ADMIN profile, and the requests without a caller.
+// It must run after Identify.
+//
+// Parameters:
+// - user_id_param: The path parameter holding the requested UserId.
+// - admin_profile_type_id: The ProfileTypeId of the ADMIN profiles, see service.NewProfileTypes.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func RequireSelfOrAdmin(user_id_param string, admin_profile_type_id string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, found := GetCaller(c)
+		if !found || (caller.User.Id != c.Param(user_id_param) && !caller.HasProfileType(admin_profile_type_id)) {
+			forbid(c, caller, "only administrators may query the access of other users")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func forbid(c *gin.Context, caller *Caller, reason string) {
+	if caller != nil {
+		RequestLogger(c).WarnContext(c.Request.Context(), "Authorization refused",
+			"principal", caller.Principal.String(), "user_id", caller.User.Id, "method", c.Request.Method, "path", c.Request.URL.Path)
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error": reason,
+	})
+}
+
+// GetCaller returns the Caller authorized for the request, if any.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+//
+// Returns:
+// - *Caller: The authorized caller.
+// - bool: false when the request is not authorized (e.g. the authentication is disabled).
+func GetCaller(c *gin.Context) (*Caller, bool) {
+	value, found := c.Get(callerKey)
+	if !found {
+		return nil, false
+	}
+
+	caller, ok := value.(*Caller)
+	return caller, ok
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	"github.com/nuno-bastos/gin-gonic-wire-api/test/jwkstest"
+)
+
+// newAuthorizedEngine serves /whoami behind authentication and authorization, answering with the caller's UserId,
+// and /admin to ADMIN profiles only.
+func newAuthorizedEngine(t *testing.T, config *db.Config) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore(memory.Data{Users: []*model.User{
+		{Id: "USR00000000000000001", Email: "admin@example.com", Profiles: []*model.Profile{{ProfileTypeId: service.ProfileTypeId.ADMIN}}},
+		{Id: "USR00000000000000002", Email: "manager@example.com", EmployeeId: 2, Profiles: []*model.Profile{{ProfileTypeId: service.ProfileTypeId.MANAGER}}},
+	}})
+
+	authenticators, err := ProvideAuthenticators(config)
+	require.NoError(t, err)
+	authorizer, err := NewAuthorizer(memory.NewUserRepository(store), config)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	engine.Use(Authenticate(authenticators), authorizer.Identify())
+	engine.GET("/whoami", func(c *gin.Context) {
+		caller, found := GetCaller(c)
+		require.True(t, found)
+		c.String(http.StatusOK, caller.User.Id)
+	})
+	engine.GET("/admin", RequireProfileType(service.ProfileTypeId.ADMIN), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	return engine
+}
+
+func TestAuthorizerMapsAPIKeysToUsers(t *testing.T) {
+	engine := newAuthorizedEngine(t, &db.Config{Auth: db.AuthConfig{APIKeys: []db.APIKeyConfig{
+		{Name: "admin", Key: "admin-key", UserId: "USR00000000000000001"},
+		{Name: "manager", Key: "manager-key", UserId: "USR00000000000000002"},
+		{Name: "orphan", Key: "orphan-key"},
+		{Name: "ghost", Key: "ghost-key", UserId: "USR99999999999999999"},
+	}}})
+
+	response := request(engine, map[string]string{APIKeyHeader: "manager-key"})
+	require.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "USR00000000000000002", response.Body.String())
+
+	for _, key := range []string{"orphan-key", "ghost-key"} {
+		assert.Equal(t, http.StatusForbidden, request(engine, map[string]string{APIKeyHeader: key}).Code, key)
+	}
+
+	for key, status := range map[string]int{"admin-key": http.StatusNoContent, "manager-key": http.StatusForbidden} {
+		assert.Equal(t, status, requestPath(engine, "/admin", map[string]string{APIKeyHeader: key}).Code, key)
+	}
+}
+
+func TestAuthorizerMapsTokensByConfiguredClaim(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	config := jwtConfig(jwks)
+	config.Auth.JWT.UserClaim = "sub"
+	config.Auth.JWT.UserField = UserFieldId
+	engine := newAuthorizedEngine(t, config)
+
+	signed := func(subject string) map[string]string {
+		return bearer(jwks.Sign(t, jwks.Keys()[0], claims(subject, time.Hour)))
+	}
+
+	response := request(engine, signed("USR00000000000000001"))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, "USR00000000000000001", response.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, request(engine, signed("admin@example.com")).Code)
+}
+
+func TestAuthorizerMapsOnlyVerifiedEmails(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	engine := newAuthorizedEngine(t, jwtConfig(jwks))
+
+	signed := func(email_verified any) map[string]string {
+		registered := claims("alice", time.Hour)
+		token_claims := jwt.MapClaims{
+			"sub": registered.Subject, "iss": registered.Issuer, "aud": []string(registered.Audience),
+			"iat": registered.IssuedAt.Unix(), "exp": registered.ExpiresAt.Unix(), "email": "admin@example.com",
+		}
+		if email_verified != nil {
+			token_claims["email_verified"] = email_verified
+		}
+		return bearer(jwks.Sign(t, jwks.Keys()[0], token_claims))
+	}
+
+	response := request(engine, signed(true))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, "USR00000000000000001", response.Body.String())
+
+	for name, email_verified := range map[string]any{"unverified": false, "as a string": "true", "missing": nil} {
+		assert.Equal(t, http.StatusForbidden, request(engine, signed(email_verified)).Code, name)
+	}
+}
+
+func TestAuthorizerRejectsUnknownUserField(t *testing.T) {
+	_, err := NewAuthorizer(memory.NewUserRepository(memory.NewStore(memory.Data{})), &db.Config{Auth: db.AuthConfig{
+		JWT: db.JWTConfig{UserField: "employee_number"},
+	}})
+	assert.ErrorContains(t, err, "employee_number")
+}
+
+func TestAuthorizationRefusesRequestsWithoutCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/admin", RequireProfileType(service.ProfileTypeId.ADMIN), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	engine.GET("/rules/:user_id", RequireSelfOrAdmin("user_id", service.ProfileTypeId.ADMIN), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	assert.Equal(t, http.StatusForbidden, requestPath(engine, "/admin", nil).Code)
+	assert.Equal(t, http.StatusForbidden, requestPath(engine, "/rules/USR00000000000000001", nil).Code)
+}
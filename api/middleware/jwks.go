@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwksMinRefetchInterval limits how often an unknown key id, or a failed fetch, triggers a fetch of the key set,
+// so tokens with made-up key ids cannot flood the identity provider.
+const jwksMinRefetchInterval = 30 * time.Second
+
+// Bounds of a fetch of the key set, independent of the requests waiting for it.
+const (
+	jwksFetchTimeout = 10 * time.Second
+	jwksMaxSize      = 1 << 20 // Bytes of the JWKS document
+)
+
+// jwks caches the public keys of a JSON Web Key Set (RFC 7517), fetched from its URL.
+// The set is fetched again once the refresh interval elapsed, or when a token is signed with an unknown key id,
+// which is how key rotations are picked up. Fetches run in the background, one at a time and without holding the
+// cache, so a slow identity provider only delays the requests needing a key that is not cached yet; while a set is
+// cached, an expired one keeps being served during its refresh, and after a failed one.
+type jwks struct {
+	url              string
+	client           *http.Client
+	refreshInterval  time.Duration
+	minRefetchPeriod time.Duration
+	fetchTimeout     time.Duration
+
+	mutex       sync.Mutex
+	keys        map[string]crypto.PublicKey // Keyed by key id, the last set fetched successfully
+	fetchedAt   time.Time
+	attemptedAt time.Time     // Start of the last fetch, successful or not
+	fetchErr    error         // Error of the last fetch
+	fetching    chan struct{} // Closed once the running fetch is over, nil when none runs
+	now         func() time.Time
+}
+
+// jsonWebKey is a key of a JWKS document, only the members of the supported key types are decoded.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newJWKS(url string, client *http.Client, refresh_interval time.Duration) *jwks {
+	return &jwks{
+		url:              url,
+		client:           client,
+		refreshInterval:  refresh_interval,
+		minRefetchPeriod: jwksMinRefetchInterval,
+		fetchTimeout:     jwksFetchTimeout,
+		now:              time.Now,
+	}
+}
+
+// key returns the public key with the given id. An empty id matches the only key of a set holding one.
+func (p *jwks) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mutex.Lock()
+	cached, expired := p.keys != nil, p.now().Sub(p.fetchedAt) >= p.refreshInterval
+	p.mutex.Unlock()
+
+	var fetching <-chan struct{}
+	if expired {
+		fetching = p.refresh()
+	}
+	if cached {
+		// The cached set is served while it is refreshed
+		fetching = nil
+	}
+	keys, err := p.current(ctx, fetching)
+	if err != nil {
+		return nil, err
+	}
+	if key, found := lookup(keys, kid); found {
+		return key, nil
+	}
+
+	// The key may have been rotated since the last fetch
+	if fetching := p.refresh(); fetching != nil {
+		if keys, err = p.current(ctx, fetching); err != nil {
+			return nil, err
+		}
+		if key, found := lookup(keys, kid); found {
+			return key, nil
+		}
+	}
+
+	if kid == "" {
+		return nil, errors.New("the token has no key id and the key set does not hold exactly one key")
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" {
+		if len(keys) != 1 {
+			return nil, false
+		}
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	key, found := keys[kid]
+	return key, found
+}
+
+// refresh starts fetching the key set in the background, unless a fetch is running or the last one started less than
+// minRefetchPeriod ago. It returns a channel closed once the running fetch is over, nil when no fetch runs.
+func (p *jwks) refresh() <-chan struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.fetching != nil {
+		return p.fetching
+	}
+	if !p.attemptedAt.IsZero() && p.now().Sub(p.attemptedAt) < p.minRefetchPeriod {
+		return nil
+	}
+
+	fetching := make(chan struct{})
+	p.fetching, p.attemptedAt = fetching, p.now()
+	go func() {
+		defer close(fetching)
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.fetchTimeout)
+		defer cancel()
+		keys, err := p.fetch(ctx)
+
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if err == nil {
+			p.keys, p.fetchedAt = keys, p.now()
+		}
+		p.fetchErr, p.fetching = err, nil
+	}()
+
+	return fetching
+}
+
+// current waits for the given fetch, if any, and returns the cached keys, or the error of the last fetch when no
+// key set was ever fetched.
+func (p *jwks) current(ctx context.Context, fetching <-chan struct{}) (map[string]crypto.PublicKey, error) {
+	if fetching != nil {
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error fetching the JWKS: %w", ctx.Err())
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.keys == nil {
+		if p.fetchErr != nil {
+			return nil, p.fetchErr
+		}
+		return nil, errors.New("error fetching the JWKS: not fetched yet")
+	}
+	return p.keys, nil
+}
+
+// fetch downloads and parses the key set, of at most jwksMaxSize bytes. Keys of unsupported types, or meant for
+// encryption, are skipped.
+func (p *jwks) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the JWKS: %w", err)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the JWKS: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching the JWKS: %s returned %s", p.url, response.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, jwksMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the JWKS: %w", err)
+	}
+	if len(body) > jwksMaxSize {
+		return nil, fmt.Errorf("error fetching the JWKS: %s returned more than %d bytes", p.url, jwksMaxSize)
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("error decoding the JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(document.Keys))
+	for _, web_key := range document.Keys {
+		if web_key.Use != "" && web_key.Use != "sig" {
+			continue
+		}
+		key, err := web_key.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding key %q of the JWKS: %w", web_key.Kid, err)
+		}
+		if key != nil {
+			keys[web_key.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+// publicKey decodes the key, nil when its type is not supported.
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes) == 0 {
+		return nil, errors.New("missing key parameter")
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// JWTAuthenticator authenticates requests carrying an OIDC/JWT bearer token signed by a key of the configured JWKS.
+type JWTAuthenticator struct {
+	keys   *jwks
+	parser *jwt.Parser
+}
+
+// NewJWTAuthenticator creates a JWTAuthenticator.
+// Unless configured, RS256 and ES256 signatures are accepted and the key set is refreshed every 15 minutes.
+//
+// Parameters:
+// - config: The JWKS URL, the expected issuer and audience, the accepted algorithms and the clock leeway.
+// - client: The HTTP client fetching the key set.
+//
+// Returns:
+// - *JWTAuthenticator: The authenticator.
+func NewJWTAuthenticator(config db.JWTConfig, client *http.Client) *JWTAuthenticator {
+	algorithms := config.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256", "ES256"}
+	}
+	refresh_interval := config.RefreshInterval
+	if refresh_interval <= 0 {
+		refresh_interval = 15 * time.Minute
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithLeeway(config.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	return &JWTAuthenticator{
+		keys:   newJWKS(config.JWKSURL, client, refresh_interval),
+		parser: jwt.NewParser(options...),
+	}
+}
+
+// Scheme returns the scheme announced for bearer tokens.
+func (a *JWTAuthenticator) Scheme() string {
+	return "Bearer"
+}
+
+// Authenticate returns the principal named after the "sub" claim of the bearer token of the Authorization header.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(strings.TrimSpace(token), claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.keys.key(r.Context(), kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		return nil, errors.New("the token has no subject")
+	}
+
+	return &Principal{Subject: subject, Method: AuthMethodJWT, Claims: claims}, nil
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	logging "github.com/nuno-bastos/gin-gonic-wire-api/logging"
+)
+
+// RequestIdHeader carries the ID of a request, set by the caller or generated, and is echoed in the response.
+const RequestIdHeader = "X-Request-Id"
+
+// maxRequestIdLength bounds the request IDs accepted from callers.
+const maxRequestIdLength = 128
+
+const loggerKey = "logger"
+
+// RequestId returns the middleware identifying every request by the X-Request-Id header of the caller, or by a new
+// UUID when the header is missing or unusable. The ID is echoed in the response header and put on the request context,
+// so that every line logged for the request carries it, and the logger is put on the gin context (see RequestLogger).
+//
+// Parameters:
+// - _logger: The logger of the application.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func RequestId(_logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request_id := c.GetHeader(RequestIdHeader)
+		if !validRequestId(request_id) {
+			request_id = uuid.NewString()
+		}
+
+		c.Header(RequestIdHeader, request_id)
+		c.Request = c.Request.WithContext(logging.WithRequestId(c.Request.Context(), request_id))
+		c.Set(loggerKey, _logger)
+		c.Next()
+	}
+}
+
+// RequestLogger returns the logger of the request, the default logger when the RequestId middleware did not run.
+// Log with the request context (e.g. InfoContext(c.Request.Context(), ...)) for the lines to carry the request ID.
+//
+// Parameters:
+// - c: Context object representing the HTTP request and response.
+//
+// Returns:
+// - *slog.Logger: The logger.
+func RequestLogger(c *gin.Context) *slog.Logger {
+	if value, found := c.Get(loggerKey); found {
+		if request_logger, ok := value.(*slog.Logger); ok {
+			return request_logger
+		}
+	}
+
+	return slog.Default()
+}
+
+// validRequestId accepts the non-empty IDs of at most maxRequestIdLength printable ASCII characters.
+func validRequestId(request_id string) bool {
+	if request_id == "" || len(request_id) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(request_id); i++ {
+		if request_id[i] < 0x21 || request_id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	logging "github.com/nuno-bastos/gin-gonic-wire-api/logging"
+)
+
+func TestRequestIdIsCarriedByTheLogLines(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var output bytes.Buffer
+	logger, err := logging.NewLoggerTo(&output, &db.Config{})
+	require.NoError(t, err)
+
+	engine := gin.New()
+	engine.Use(RequestId(logger), AccessLog(logger))
+	engine.GET("/whoami", func(c *gin.Context) {
+		RequestLogger(c).InfoContext(c.Request.Context(), "Handled")
+		c.Status(http.StatusNoContent)
+	})
+
+	// The ID of the caller is kept
+	response := request(engine, map[string]string{RequestIdHeader: "job-42"})
+	assert.Equal(t, "job-42", response.Header().Get(RequestIdHeader))
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, "job-42", record["request_id"], record["msg"])
+	}
+
+	// Missing or unusable IDs are replaced
+	for _, header := range []map[string]string{nil, {RequestIdHeader: "two words"}, {RequestIdHeader: strings.Repeat("x", 129)}} {
+		response := request(engine, header)
+		_, err := uuid.Parse(response.Header().Get(RequestIdHeader))
+		assert.NoError(t, err, header)
+	}
+}
+
+func TestRequestLoggerDefaultsToTheDefaultLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/whoami", func(c *gin.Context) {
+		assert.Same(t, slog.Default(), RequestLogger(c))
+		c.Status(http.StatusNoContent)
+	})
+
+	assert.Equal(t, http.StatusNoContent, request(engine, nil).Code)
+}
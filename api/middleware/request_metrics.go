@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+)
+
+// unmatchedRoute labels the requests that match no route, so that arbitrary paths do not create new series.
+const unmatchedRoute = "unmatched"
+
+// RequestMetrics returns the middleware counting and timing the HTTP requests by method, route template and status code.
+//
+// Parameters:
+// - _metrics: The metrics receiving the observations.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func RequestMetrics(_metrics *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started_at := time.Now()
+		_metrics.HTTPInFlight.Inc()
+		defer _metrics.HTTPInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		_metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		_metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(started_at).Seconds())
+	}
+}
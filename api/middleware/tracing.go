@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
+
+	tracing "github.com/nuno-bastos/gin-gonic-wire-api/tracing"
+)
+
+// untracedPaths are polled by orchestrators and Prometheus, their spans would only be noise.
+var untracedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Tracing returns the middleware starting a server span per HTTP request, named after its route template.
+// The span continues the W3C trace context of the request, if any, and is put on the request context
+// so that the spans of the services and of the database queries are its children.
+//
+// Parameters:
+// - tracerProvider: The provider of the spans.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func Tracing(tracerProvider trace.TracerProvider) gin.HandlerFunc {
+	return otelgin.Middleware(tracing.DefaultServiceName,
+		otelgin.WithTracerProvider(tracerProvider),
+		otelgin.WithPropagators(tracing.Propagator),
+		otelgin.WithFilter(func(request *http.Request) bool {
+			return !untracedPaths[request.URL.Path]
+		}),
+	)
+}
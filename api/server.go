@@ -1,30 +1,251 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 
 	controller "github.com/nuno-bastos/gin-gonic-wire-api/api/controller"
+	middleware "github.com/nuno-bastos/gin-gonic-wire-api/api/middleware"
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
 )
 
+// interruptGracePeriod is the time given to the requests cancelled at the end of the shutdown timeout to roll back
+// and answer before their connections are closed.
+const interruptGracePeriod = 5 * time.Second
+
 type ServerHTTP struct {
-	engine *gin.Engine
+	engine  *gin.Engine
+	options ServerOptions
+	logger  *slog.Logger
+}
+
+// ServerOptions defines the address, the routing and the timeouts of the HTTP server.
+type ServerOptions struct {
+	Address           string
+	BasePath          string   // Prefix of the API routes, the probes and the metrics being served at the root
+	TrustedProxies    []string // Proxies whose X-Forwarded-For header gives the client IP
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration // Bounds the calculation requests: keep it above the duration of a calculation
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // Time given to the in-flight requests to finish once stopping, before they are cancelled
+	AuthDisabled      bool          // Serve the API without authenticators, as explicitly set by auth.disabled
+}
+
+// NewServerOptions creates the ServerOptions from the application configuration.
+// Unless configured, the server listens on :8080, serves the API under /github.com/nuno-bastos/gin-gonic-wire-api,
+// trusts the proxies running on the same host, reads the request headers within 10s and the request within 30s,
+// serves a request within 15 minutes, closes the connections idle for 2 minutes and drains the in-flight requests
+// for 20s when stopping.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - ServerOptions: The server options.
+func NewServerOptions(config *db.Config) ServerOptions {
+	options := ServerOptions{
+		Address:           config.ServerAddress,
+		BasePath:          config.Server.BasePath,
+		TrustedProxies:    config.Server.TrustedProxies,
+		ReadHeaderTimeout: config.Server.ReadHeaderTimeout,
+		ReadTimeout:       config.Server.ReadTimeout,
+		WriteTimeout:      config.Server.WriteTimeout,
+		IdleTimeout:       config.Server.IdleTimeout,
+		ShutdownTimeout:   config.Server.ShutdownTimeout,
+		AuthDisabled:      config.Auth.Disabled,
+	}
+
+	if options.Address == "" {
+		options.Address = ":8080"
+	}
+	if options.BasePath == "" {
+		options.BasePath = "/github.com/nuno-bastos/gin-gonic-wire-api"
+	}
+	if options.TrustedProxies == nil {
+		options.TrustedProxies = []string{"127.0.0.1", "::1"}
+	}
+	if options.ReadHeaderTimeout <= 0 {
+		options.ReadHeaderTimeout = 10 * time.Second
+	}
+	if options.ReadTimeout <= 0 {
+		options.ReadTimeout = 30 * time.Second
+	}
+	if options.WriteTimeout <= 0 {
+		options.WriteTimeout = 15 * time.Minute
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = 2 * time.Minute
+	}
+	if options.ShutdownTimeout <= 0 {
+		options.ShutdownTimeout = 20 * time.Second
+	}
+
+	return options
 }
 
-func StartServer(calculateGoSecurityMatrixController *controller.CalculateSecurityMatrixController) *ServerHTTP {
+// StartServer creates the HTTP server and its routes.
+// The probes (/healthz, /readyz), the freshness report (/freshness) and the Prometheus metrics (/metrics) are served
+// outside the base path of the API, without authentication, for orchestrators and monitoring.
+// When authenticators are configured, every route requires an authenticated principal acting as a Security.Users row:
+// the calculation and the reports over the whole matrix are reserved to ADMIN profiles, and the rules of a user
+// can only be queried by that user or by an ADMIN. Authentication and authorization are only skipped, leaving the
+// API open, when the authentication is explicitly disabled (AuthDisabled).
+// Every request is identified by a request ID carried by its log lines, logged once served, counted in the metrics,
+// and traced unless it is a probe or a scrape of the metrics.
+// It fails if no authenticator or no authorizer is given while the authentication is not disabled, or if a trusted
+// proxy is neither an IP address nor a CIDR range.
+func StartServer(
+	calculateGoSecurityMatrixController *controller.CalculateSecurityMatrixController,
+	dataQualityController *controller.DataQualityController,
+	securityMatrixExportController *controller.SecurityMatrixExportController,
+	securityMatrixParityController *controller.SecurityMatrixParityController,
+	healthController *controller.HealthController,
+	metricsController *controller.MetricsController,
+	_metrics *metrics.Metrics,
+	tracerProvider trace.TracerProvider,
+	authenticators []middleware.Authenticator,
+	authorizer *middleware.Authorizer,
+	_options ServerOptions,
+	_profileTypes service.ProfileTypes,
+	_logger *slog.Logger,
+) (*ServerHTTP, error) {
 	engine := gin.New()
 
 	engine.ForwardedByClientIP = true
-	engine.SetTrustedProxies([]string{"localhost"})
-	engine.Use(gin.Logger())
+	if err := engine.SetTrustedProxies(_options.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("error setting the trusted proxies: %w", err)
+	}
+	engine.Use(
+		middleware.RequestId(_logger),
+		middleware.RequestMetrics(_metrics),
+		middleware.Tracing(tracerProvider),
+		middleware.AccessLog(_logger), // Within the server span, so that the access log lines carry its trace ID
+	)
+
+	engine.GET("/healthz", healthController.Healthz)
+	engine.GET("/readyz", healthController.Readyz)
+	engine.GET("/freshness", healthController.GetFreshness)
+	engine.GET("/metrics", metricsController.GetMetrics)
 
-	api := engine.Group("/github.com/nuno-bastos/gin-gonic-wire-api") // base api URL
-	api.POST("/CalculateGoSecurityMatrix", calculateGoSecurityMatrixController.CalculateGoSecurityMatrix)
+	api := engine.Group(_options.BasePath)
+	var admin_only, self_or_admin []gin.HandlerFunc
+	if _options.AuthDisabled {
+		_logger.Warn("Authentication is disabled (auth.disabled), the API is open to anyone reaching it")
+	} else {
+		if len(authenticators) == 0 || authorizer == nil {
+			return nil, errors.New("no authenticator is configured (auth.api_keys, auth.jwt.jwks_url), set auth.disabled to serve the API without authentication")
+		}
+		api.Use(middleware.Authenticate(authenticators), authorizer.Identify())
 
-	return &ServerHTTP{engine: engine}
+		// The profiles are required whatever authenticated the request: a request without a caller is refused
+		admin_only = append(admin_only, middleware.RequireProfileType(_profileTypes.ADMIN))
+		self_or_admin = append(self_or_admin, middleware.RequireSelfOrAdmin("user_id", _profileTypes.ADMIN))
+	}
+	admin := api.Group("", admin_only...)
+	lookup := api.Group("", self_or_admin...)
+
+	admin.POST("/CalculateGoSecurityMatrix", calculateGoSecurityMatrixController.CalculateGoSecurityMatrix)
+	admin.GET("/DataQualityReport", dataQualityController.GetDataQualityReport)
+	admin.GET("/ExportGoSecurityMatrix", securityMatrixExportController.ExportGoSecurityMatrix)
+	admin.GET("/SecurityMatrixParity", securityMatrixParityController.GetSecurityMatrixParity)
+	lookup.GET("/SecurityMatrixRules/:user_id", securityMatrixExportController.GetUserSecurityMatrixRules)
+
+	return &ServerHTTP{engine: engine, options: _options, logger: _logger}, nil
 }
 
-func (sh *ServerHTTP) Start() {
-	sh.engine.Run(":8080")
+// Start serves HTTP requests until SIGINT or SIGTERM, then shuts down gracefully (see Run).
+func (sh *ServerHTTP) Start() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return sh.Run(ctx)
+}
+
+// Run listens on the configured address and serves HTTP requests until ctx is cancelled, then shuts down gracefully
+// (see Serve).
+//
+// Parameters:
+// - ctx: Cancelled to stop the server, typically on SIGTERM.
+//
+// Returns:
+// - error: An error if the server cannot listen or fails, nil once it stopped gracefully.
+func (sh *ServerHTTP) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", sh.options.Address)
+	if err != nil {
+		return err
+	}
+
+	return sh.Serve(ctx, listener)
+}
+
+// Serve serves HTTP requests on the listener until ctx is cancelled, then shuts down gracefully: it stops accepting
+// connections and gives the in-flight requests ShutdownTimeout to finish. The requests still running then are
+// cancelled, which rolls back the calculations they run, and given a few more seconds to answer.
+//
+// Parameters:
+// - ctx: Cancelled to stop the server, typically on SIGTERM.
+// - listener: The listener accepting the connections, closed by Serve.
+//
+// Returns:
+// - error: An error if the server fails or the requests cannot be drained, nil once it stopped gracefully.
+func (sh *ServerHTTP) Serve(ctx context.Context, listener net.Listener) error {
+	// The requests outlive the cancellation of ctx until they are interrupted
+	requests_ctx, interrupt := context.WithCancel(context.WithoutCancel(ctx))
+	defer interrupt()
+
+	server := &http.Server{
+		Handler:           sh.engine.Handler(),
+		ReadHeaderTimeout: sh.options.ReadHeaderTimeout,
+		ReadTimeout:       sh.options.ReadTimeout,
+		WriteTimeout:      sh.options.WriteTimeout,
+		IdleTimeout:       sh.options.IdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return requests_ctx },
+		ErrorLog:          slog.NewLogLogger(sh.logger.Handler(), slog.LevelWarn),
+	}
+
+	serve_err := make(chan error, 1)
+	go func() { serve_err <- server.Serve(listener) }()
+	sh.logger.Info("Server listening", "address", listener.Addr().String())
+
+	select {
+	case err := <-serve_err:
+		return err
+	case <-ctx.Done():
+	}
+
+	sh.logger.Info("Server shutting down, draining the in-flight requests", "timeout", sh.options.ShutdownTimeout)
+	interrupt_timer := time.AfterFunc(sh.options.ShutdownTimeout, func() {
+		sh.logger.Warn("Shutdown timeout elapsed, interrupting the requests still in flight")
+		interrupt()
+	})
+	defer interrupt_timer.Stop()
+
+	shutdown_ctx, cancel := context.WithTimeout(context.Background(), sh.options.ShutdownTimeout+interruptGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(shutdown_ctx); err != nil {
+		server.Close()
+		return fmt.Errorf("error draining the in-flight requests: %w", err)
+	}
+	if err := <-serve_err; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	sh.logger.Info("Server stopped")
+	return nil
 }
 
 func (sh *ServerHTTP) GetEngine() *gin.Engine {
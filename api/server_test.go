@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// newTestServer serves a route answering after the given delay, and a route waiting for its request to be cancelled.
+func newTestServer(shutdown_timeout time.Duration, delay time.Duration, started chan<- struct{}) *ServerHTTP {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		time.Sleep(delay)
+		c.String(http.StatusOK, "done")
+	})
+	engine.GET("/blocking", func(c *gin.Context) {
+		started <- struct{}{}
+		<-c.Request.Context().Done()
+		c.String(http.StatusServiceUnavailable, "interrupted")
+	})
+
+	options := NewServerOptions(&db.Config{Server: db.ServerConfig{ShutdownTimeout: shutdown_timeout}})
+	return &ServerHTTP{engine: engine, options: options, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+// serveInBackground serves on a random local port until ctx is cancelled, and returns the base URL and Serve's outcome.
+func serveInBackground(t *testing.T, ctx context.Context, server *ServerHTTP) (string, <-chan error) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, listener) }()
+
+	return "http://" + listener.Addr().String(), served
+}
+
+// get requests the URL in the background and returns the response status, or 0 if the request failed.
+func get(url string) <-chan int {
+	status := make(chan int, 1)
+	go func() {
+		response, err := http.Get(url)
+		if err != nil {
+			status <- 0
+			return
+		}
+		defer response.Body.Close()
+		status <- response.StatusCode
+	}()
+
+	return status
+}
+
+func TestServeDrainsTheInFlightRequests(t *testing.T) {
+	started := make(chan struct{}, 1)
+	ctx, stop := context.WithCancel(context.Background())
+	url, served := serveInBackground(t, ctx, newTestServer(5*time.Second, 200*time.Millisecond, started))
+
+	status := get(url + "/slow")
+	<-started
+	stop()
+
+	assert.Equal(t, http.StatusOK, <-status)
+	require.NoError(t, <-served)
+
+	// No new connection is accepted
+	_, err := http.Get(url + "/slow")
+	assert.Error(t, err)
+}
+
+func TestServeInterruptsTheRequestsLeftAtTheShutdownTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	ctx, stop := context.WithCancel(context.Background())
+	url, served := serveInBackground(t, ctx, newTestServer(100*time.Millisecond, 0, started))
+
+	status := get(url + "/blocking")
+	<-started
+	stopped_at := time.Now()
+	stop()
+
+	// The request is cancelled once the shutdown timeout elapsed, and still answers
+	assert.Equal(t, http.StatusServiceUnavailable, <-status)
+	require.NoError(t, <-served)
+	assert.GreaterOrEqual(t, time.Since(stopped_at), 100*time.Millisecond)
+}
+
+func TestNewServerOptionsDefaults(t *testing.T) {
+	options := NewServerOptions(&db.Config{})
+
+	assert.Equal(t, ServerOptions{
+		Address:           ":8080",
+		BasePath:          "/github.com/nuno-bastos/gin-gonic-wire-api",
+		TrustedProxies:    []string{"127.0.0.1", "::1"},
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      15 * time.Minute,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   20 * time.Second,
+	}, options)
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	wire "github.com/nuno-bastos/gin-gonic-wire-api/wire_di"
+)
+
+var calculateCommand = command{
+	description: "Calculate and write the security matrix once, then exit",
+	overrides:   concatFlags(databaseFlags, calculationFlags, tracingFlags, loggingFlags),
+	setup: func(flags *flag.FlagSet) func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+		return runCalculate
+	},
+}
+
+// runCalculate runs the calculation. When it is refused by the data quality checks,
+// the report is written to stderr as JSON and ExitDataQuality is returned. When interrupted by SIGINT or SIGTERM,
+// or once a timeout of calculation.timeouts elapsed, its rules are rolled back and the run is recorded as interrupted.
+func runCalculate(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+	calculator, cleanup, err := wire.InjectCalculator(ctx, config)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error - Cannot create the calculator: %v\n", err)
+		return ExitError
+	}
+	defer cleanup()
+
+	started := time.Now()
+	err = calculator.CalculateGoSecurityMatrix(ctx)
+
+	var data_quality_err *service.DataQualityError
+	if errors.As(err, &data_quality_err) {
+		fmt.Fprintf(stderr, "Error - %v\n", err)
+		encoder := json.NewEncoder(stderr)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(data_quality_err.Report)
+		return ExitDataQuality
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintf(stderr, "Error - Calculation timed out, its rules were rolled back: %v\n", err)
+		return ExitError
+	}
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintf(stderr, "Error - Calculation interrupted, its rules were rolled back: %v\n", err)
+		return ExitError
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error - Calculation failed: %v\n", err)
+		return ExitError
+	}
+
+	fmt.Fprintf(stdout, "Security matrix calculated in %s\n", time.Since(started).Round(time.Millisecond))
+	return ExitOK
+}
@@ -0,0 +1,136 @@
+// Package cli implements the command-line interface of the application: each subcommand loads config.yaml,
+// applies its command-line overrides and assembles its dependencies with its own Wire injector.
+//
+// Usage:
+//
+//	gin-gonic-wire-api [serve]   [flags]   start the HTTP server (default)
+//	gin-gonic-wire-api calculate [flags]   calculate and write the security matrix once, then exit
+//	gin-gonic-wire-api migrate   [flags]   create or update the database tables
+//	gin-gonic-wire-api export    [flags]   export the security matrix
+//	gin-gonic-wire-api parity    [flags]   compare the legacy matrix with GoMatrixRule
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// Exit codes of the command-line interface.
+const (
+	ExitOK          = 0 // The subcommand succeeded
+	ExitError       = 1 // The subcommand failed
+	ExitUsage       = 2 // Unknown subcommand, invalid flags or invalid configuration
+	ExitDataQuality = 3 // The calculation was refused by the data quality checks
+	ExitMismatch    = 4 // The parity check found mismatches
+)
+
+// command is a subcommand of the command-line interface.
+type command struct {
+	description string
+	overrides   []configFlag // Flags overriding config.yaml
+
+	// setup registers the flags specific to the subcommand and returns the function running it.
+	setup func(flags *flag.FlagSet) func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"serve":     serveCommand,
+	"calculate": calculateCommand,
+	"migrate":   migrateCommand,
+	"export":    exportCommand,
+	"parity":    parityCommand,
+}
+
+// Run runs the subcommand named by the first argument (serve when there is none) and returns the process exit code.
+// The subcommand is cancelled on SIGINT and SIGTERM.
+//
+// Parameters:
+// - args: The command-line arguments, without the program name.
+// - stdout: Where the subcommand writes its output.
+// - stderr: Where usage and errors are written.
+//
+// Returns:
+// - int: The exit code, see ExitOK and the following constants.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (len(args[0]) > 0 && args[0][0] == '-' && !isHelp(args[0])) {
+		args = append([]string{"serve"}, args...)
+	}
+	if isHelp(args[0]) {
+		printUsage(stdout)
+		return ExitOK
+	}
+
+	name := args[0]
+	cmd, found := commands[name]
+	if !found {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+		printUsage(stderr)
+		return ExitUsage
+	}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n%s.\n\nFlags:\n", os.Args[0], name, cmd.description)
+		flags.PrintDefaults()
+	}
+
+	config_path := flags.String("config", "", "configuration file (default ./config/config.yaml)")
+	overrides := registerOverrides(flags, cmd.overrides)
+	run := cmd.setup(flags)
+
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %v\n", flags.Args())
+		flags.Usage()
+		return ExitUsage
+	}
+
+	config, err := db.LoadConfigFileWithOverrides(*config_path, overrides)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error loading configuration: %v\n", err)
+		var config_err *db.ConfigError
+		if errors.As(err, &config_err) {
+			return ExitUsage
+		}
+		return ExitError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return run(ctx, config, stdout, stderr)
+}
+
+func isHelp(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func printUsage(output io.Writer) {
+	fmt.Fprintf(output, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(output, "  %-10s %s\n", name, commands[name].description)
+	}
+
+	fmt.Fprintf(output, "\nRun '%s <command> -h' for the flags of a command. Without a command, the HTTP server starts.\n", os.Args[0])
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"flag"
+	"strconv"
+)
+
+// configFlag is a command-line flag overriding a config.yaml key.
+type configFlag struct {
+	name   string // Flag name
+	key    string // Configuration key, e.g. "calculation.workers"
+	usage  string
+	isBool bool
+}
+
+// databaseFlags override the database connection settings.
+var databaseFlags = []configFlag{
+	{name: "database-driver", key: "database_driver", usage: "database driver: sqlserver, postgres or sqlite"},
+	{name: "database-dsn", key: "database_dsn", usage: "database data source name"},
+}
+
+// calculationFlags override the calculation settings.
+var calculationFlags = []configFlag{
+	{name: "manager-chain-source", key: "manager_chain_source", usage: "where the manager chains are read from: manager_chains or direct_managers"},
+	{name: "materialize-manager-chains", key: "materialize_manager_chains", usage: "write the derived manager chains back to Core.ManagerChains", isBool: true},
+	{name: "enforce-data-quality", key: "enforce_data_quality", usage: "refuse to calculate while the data quality checks find issues", isBool: true},
+	{name: "rule-write-mode", key: "rule_write_mode", usage: "how rules are inserted: auto, bulk_copy or batched_insert"},
+	{name: "workers", key: "calculation.workers", usage: "number of worker goroutines"},
+	{name: "users-channel-buffer", key: "calculation.users_channel_buffer", usage: "buffer of the channel feeding users to workers"},
+	{name: "rules-channel-buffer", key: "calculation.rules_channel_buffer", usage: "buffer of the channel feeding rules to the writer"},
+	{name: "batch-size", key: "calculation.batch_size", usage: "rows per INSERT statement of the batched insert path"},
+}
+
+// serverFlags override the HTTP server settings.
+var serverFlags = []configFlag{
+	{name: "address", key: "server_address", usage: "address the HTTP server listens on"},
+	{name: "base-path", key: "server.base_path", usage: "path prefix of the API routes"},
+	{name: "shutdown-timeout", key: "server.shutdown_timeout", usage: "time given to the in-flight requests to finish when stopping, e.g. 20s"},
+}
+
+// tracingFlags override where the spans are exported.
+var tracingFlags = []configFlag{
+	{name: "trace-exporter", key: "tracing.exporter", usage: "where the spans are exported: none, stdout or otlp"},
+	{name: "trace-endpoint", key: "tracing.endpoint", usage: "host:port of the OTLP/HTTP collector"},
+	{name: "trace-sample-ratio", key: "tracing.sample_ratio", usage: "fraction of the new traces recorded, from 0 to 1"},
+}
+
+// loggingFlags override the level and the format of the log lines.
+var loggingFlags = []configFlag{
+	{name: "log-level", key: "logging.level", usage: "lowest level logged: debug, info, warn or error"},
+	{name: "log-format", key: "logging.format", usage: "format of the log lines: json or text"},
+}
+
+// parityFlags override the location of the legacy matrix table and the detail of the parity report.
+var parityFlags = []configFlag{
+	{name: "legacy-table", key: "parity.legacy_table", usage: "schema-qualified name of the legacy matrix table"},
+	{name: "user-column", key: "parity.user_column", usage: "column of the viewer user id in the legacy matrix table"},
+	{name: "employee-column", key: "parity.employee_column", usage: "column of the viewed employee id in the legacy matrix table"},
+	{name: "access-level-column", key: "parity.access_level_column", usage: "column of the read access level in the legacy matrix table"},
+	{name: "max-samples", key: "parity.max_samples_per_user", usage: "mismatches listed per user in the report"},
+}
+
+// overrideValue records the value of a configFlag into the overrides, only when the flag is set.
+type overrideValue struct {
+	flag      configFlag
+	overrides map[string]string
+}
+
+func (v *overrideValue) String() string {
+	if v == nil || v.overrides == nil {
+		return ""
+	}
+	return v.overrides[v.flag.key]
+}
+
+func (v *overrideValue) Set(value string) error {
+	if v.flag.isBool {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return err
+		}
+	}
+
+	v.overrides[v.flag.key] = value
+	return nil
+}
+
+func (v *overrideValue) IsBoolFlag() bool {
+	return v.flag.isBool
+}
+
+// registerOverrides registers the flags and returns the overrides they fill, keyed by configuration key.
+func registerOverrides(flags *flag.FlagSet, config_flags []configFlag) map[string]string {
+	overrides := make(map[string]string)
+	for _, config_flag := range config_flags {
+		flags.Var(&overrideValue{flag: config_flag, overrides: overrides}, config_flag.name, config_flag.usage+" (overrides "+config_flag.key+")")
+	}
+
+	return overrides
+}
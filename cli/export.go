@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	wire "github.com/nuno-bastos/gin-gonic-wire-api/wire_di"
+)
+
+var exportCommand = command{
+	description: "Export the security matrix",
+	overrides:   concatFlags(databaseFlags, loggingFlags),
+	setup: func(flags *flag.FlagSet) func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+		format := flags.String("format", string(security_model.ExportFormatCSV), fmt.Sprintf("export format: %v", security_model.ExportFormats))
+		output := flags.String("output", "-", "output file, - for stdout")
+		enrich := flags.Bool("enrich", false, "add the user email and the employee number of every rule")
+		decode_access := flags.Bool("decode-access", false, "add a boolean column per bit of AccessLevelRead")
+
+		return func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+			export_format, err := security_model.ParseExportFormat(*format)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return ExitUsage
+			}
+			options := security_model.ExportOptions{Format: export_format, Enrich: *enrich, DecodeAccess: *decode_access}
+
+			exporter, cleanup, err := wire.InjectExporter(ctx, config)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error - Cannot create the exporter: %v\n", err)
+				return ExitError
+			}
+			defer cleanup()
+
+			if err := export(ctx, exporter.Export, options, *output, stdout); err != nil {
+				fmt.Fprintf(stderr, "Error - Export failed: %v\n", err)
+				return ExitError
+			}
+
+			return ExitOK
+		}
+	},
+}
+
+// export writes the export to the output file, or to stdout when output is "-".
+// A partially written file is removed when the export fails.
+func export(ctx context.Context, exportTo func(ctx context.Context, writer io.Writer, options security_model.ExportOptions) error, options security_model.ExportOptions, output string, stdout io.Writer) error {
+	if output == "-" {
+		writer := bufio.NewWriter(stdout)
+		if err := exportTo(ctx, writer, options); err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	err = exportTo(ctx, writer, options)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if close_err := file.Close(); err == nil {
+		err = close_err
+	}
+	if err != nil {
+		os.Remove(output)
+	}
+
+	return err
+}
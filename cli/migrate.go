@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	wire "github.com/nuno-bastos/gin-gonic-wire-api/wire_di"
+)
+
+var migrateCommand = command{
+	description: "Create or update the database tables",
+	overrides:   concatFlags(databaseFlags, loggingFlags),
+	setup: func(flags *flag.FlagSet) func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+		input_tables := flags.Bool("input-tables", false, "also create the input tables (Core.Employees, Core.ManagerChains, ...), e.g. for a local database")
+
+		return func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+			migrator, cleanup, err := wire.InjectMigrator(ctx, config)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error - Cannot create the migrator: %v\n", err)
+				return ExitError
+			}
+			defer cleanup()
+
+			if err := migrator.Migrate(*input_tables); err != nil {
+				fmt.Fprintf(stderr, "Error - Migration failed: %v\n", err)
+				return ExitError
+			}
+
+			fmt.Fprintln(stdout, "Database migrated")
+			return ExitOK
+		}
+	},
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	wire "github.com/nuno-bastos/gin-gonic-wire-api/wire_di"
+)
+
+var parityCommand = command{
+	description: "Compare the legacy matrix with GoMatrixRule or a fresh calculation",
+	overrides:   concatFlags(databaseFlags, calculationFlags, parityFlags, loggingFlags),
+	setup: func(flags *flag.FlagSet) func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+		source := flags.String("source", string(security_model.ParitySourceStored), "matrix compared with the legacy one: stored (GoMatrixRule) or calculated (in memory, nothing is written)")
+
+		return func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+			parity_source, err := security_model.ParseParitySource(*source)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return ExitUsage
+			}
+
+			checker, cleanup, err := wire.InjectParityChecker(ctx, config)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error - Cannot create the parity checker: %v\n", err)
+				return ExitError
+			}
+			defer cleanup()
+
+			report, err := checker.CheckParity(ctx, parity_source)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error - Parity check failed: %v\n", err)
+				return ExitError
+			}
+
+			encoder := json.NewEncoder(stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				fmt.Fprintf(stderr, "Error - Cannot write the report: %v\n", err)
+				return ExitError
+			}
+
+			if report.HasMismatches() {
+				fmt.Fprintf(stderr, "%d mismatches between %s and the %s matrix\n", report.Mismatches, report.LegacyTable, parity_source)
+				return ExitMismatch
+			}
+
+			return ExitOK
+		}
+	},
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	wire "github.com/nuno-bastos/gin-gonic-wire-api/wire_di"
+)
+
+var serveCommand = command{
+	description: "Start the HTTP server",
+	overrides:   concatFlags(databaseFlags, calculationFlags, serverFlags, tracingFlags, loggingFlags),
+	setup: func(flags *flag.FlagSet) func(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+		return runServe
+	},
+}
+
+func runServe(ctx context.Context, config *db.Config, stdout, stderr io.Writer) int {
+	server, cleanup, err := wire.InjectServer(ctx, config)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error - Cannot Start Server: %v\n", err)
+		return ExitError
+	}
+	defer cleanup()
+
+	// Stops on SIGINT or SIGTERM, once the in-flight requests are drained or interrupted
+	if err := server.Run(ctx); err != nil {
+		fmt.Fprintf(stderr, "Error - Server stopped: %v\n", err)
+		return ExitError
+	}
+
+	return ExitOK
+}
+
+// concatFlags concatenates groups of configuration flags.
+func concatFlags(groups ...[]configFlag) []configFlag {
+	var config_flags []configFlag
+	for _, group := range groups {
+		config_flags = append(config_flags, group...)
+	}
+
+	return config_flags
+}
@@ -0,0 +1,340 @@
+// Command autotune searches the calculation pipeline settings (worker count, channel buffers and
+// insert batch size) that perform best on this machine, and writes them back to the configuration file.
+//
+// Usage:
+//
+//	go run ./cmd/autotune -config ./config/config.yaml [-dataset synthetic|database] [-employees 5000] [-tune-writes]
+//
+// The pipeline settings are measured by running the calculation (without writing) against a synthetic org chart
+// (see the generator package) or the real data of the configured database. With -tune-writes, the batch size of the
+// batched insert path is measured by writing the calculated rules to the GoMatrixRule table of the database given by
+// -write-dsn, a scratch database of the configured driver. Writing them to the configured database replaces its
+// security matrix, so it is refused unless -overwrite-matrix is given. The batch size does not apply to the bulk copy
+// that rule_write_mode auto selects on SQL Server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/generator"
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	repository "github.com/nuno-bastos/gin-gonic-wire-api/repo"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	filters "github.com/nuno-bastos/gin-gonic-wire-api/service/filters"
+)
+
+func main() {
+	config_path := flag.String("config", "./config/config.yaml", "configuration file to read and update")
+	dataset := flag.String("dataset", "synthetic", "dataset to tune against: synthetic or database")
+	employees := flag.Int("employees", 5000, "number of employees of the synthetic dataset")
+	seed := flag.Int64("seed", 1, "seed of the synthetic dataset")
+	rounds := flag.Int("rounds", 3, "measurements per candidate, the median is kept")
+	tune_writes := flag.Bool("tune-writes", false, "also tune the insert batch size by writing rules to the GoMatrixRule table of -write-dsn")
+	write_dsn := flag.String("write-dsn", "", "DSN of the scratch database written by -tune-writes, of the configured driver")
+	overwrite_matrix := flag.Bool("overwrite-matrix", false, "let -tune-writes write to the configured database without -write-dsn, replacing its security matrix")
+	dry_run := flag.Bool("dry-run", false, "print the best values without updating the configuration file")
+	flag.Parse()
+
+	config, err := db.LoadConfigFile(*config_path)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// The rules written by -tune-writes replace the security matrix of the database they are written to
+	if *tune_writes && *write_dsn == "" && !*overwrite_matrix {
+		log.Fatalf("-tune-writes replaces the GoMatrixRule table it writes to: set -write-dsn to a scratch database, " +
+			"or -overwrite-matrix to write to the configured database and run a recalculation afterwards")
+	}
+
+	var gormDB *gorm.DB
+	if *dataset == "database" || (*tune_writes && *write_dsn == "") {
+		if gormDB, err = db.ConnectDatabase(context.Background(), config, noop.NewTracerProvider(), slog.Default()); err != nil {
+			log.Fatalf("Error connecting to the database: %v", err)
+		}
+	}
+	write_db := gormDB
+	if *tune_writes && *write_dsn != "" {
+		write_config := *config
+		write_config.DatabaseDSN = *write_dsn
+		if write_db, err = db.ConnectDatabase(context.Background(), &write_config, noop.NewTracerProvider(), slog.Default()); err != nil {
+			log.Fatalf("Error connecting to the scratch database: %v", err)
+		}
+	}
+
+	// The configured profile types split the profiles between the manager and employee filters, as in production
+	profile_types := service.NewProfileTypes(config)
+
+	var manager_chains []model.ManagerChain
+	var users []*model.User
+	switch *dataset {
+	case "synthetic":
+		options := generator.DefaultOptions()
+		options.Employees, options.Seed, options.ProfileTypes = *employees, *seed, profile_types
+
+		var organization *generator.Organization
+		if organization, err = generator.Generate(options); err == nil {
+			store := memory.NewStore(organization.Data())
+			manager_chains, users, err = loadDataset(
+				memory.NewManagerChainRepository(store),
+				memory.NewDirectManagerRepository(store),
+				memory.NewUserRepository(store),
+				service.ManagerChainOptions{},
+			)
+		}
+	case "database":
+		manager_chains, users, err = loadDataset(
+			repository.NewManagerChainRepository(gormDB),
+			repository.NewDirectManagerRepository(gormDB),
+			repository.NewUserRepository(gormDB),
+			service.NewManagerChainOptions(config),
+		)
+	default:
+		err = fmt.Errorf("unknown dataset %q", *dataset)
+	}
+	if err != nil {
+		log.Fatalf("Error preparing the dataset: %v", err)
+	}
+
+	input, err := service.BuildCalculationInput(manager_chains, users)
+	if err != nil {
+		log.Fatalf("Error building the calculation input: %v", err)
+	}
+	users_profiles := service.GroupProfilesByUser(users, profile_types)
+	log.Printf("Dataset: %d users, %d manager chains", len(users_profiles), len(manager_chains))
+
+	tuner := &autotuner{rounds: *rounds, profileTypes: profile_types, usersProfiles: users_profiles, input: input}
+
+	// Coordinate search: the worker count first, then each channel buffer with the best worker count
+	best := service.NewPipelineOptions(&db.Config{})
+	best.Workers = tuner.best("workers", workerCandidates(), func(value int) service.PipelineOptions {
+		return service.PipelineOptions{Workers: value, UsersChannelBuffer: value, RulesChannelBuffer: value}
+	})
+	best.UsersChannelBuffer = tuner.best("users_channel_buffer", bufferCandidates(best.Workers), func(value int) service.PipelineOptions {
+		return service.PipelineOptions{Workers: best.Workers, UsersChannelBuffer: value, RulesChannelBuffer: best.Workers}
+	})
+	best.RulesChannelBuffer = tuner.best("rules_channel_buffer", bufferCandidates(best.Workers), func(value int) service.PipelineOptions {
+		return service.PipelineOptions{Workers: best.Workers, UsersChannelBuffer: best.UsersChannelBuffer, RulesChannelBuffer: value}
+	})
+
+	tuned := map[string]int{
+		"calculation.workers":              best.Workers,
+		"calculation.users_channel_buffer": best.UsersChannelBuffer,
+		"calculation.rules_channel_buffer": best.RulesChannelBuffer,
+	}
+
+	if *tune_writes {
+		rules := tuner.collectRules(best)
+		tuned["calculation.batch_size"] = tuneBatchSize(write_db, config, rules, *rounds)
+
+		if write_db.Dialector.Name() == "sqlserver" && config.RuleWriteMode != repository.RuleWriteModeBatchedInsert {
+			log.Printf("Note: the batch size does not affect the bulk copy SQL Server writes the rules with, "+
+				"it only applies with rule_write_mode %q", repository.RuleWriteModeBatchedInsert)
+		}
+	}
+
+	if *dry_run {
+		log.Printf("Best calculation settings: %v", tuned)
+		return
+	}
+
+	if err := writeTunedSettings(*config_path, tuned); err != nil {
+		log.Fatalf("Error writing configuration file: %v", err)
+	}
+	log.Printf("Best calculation settings written to %s: %v", *config_path, tuned)
+}
+
+// writeTunedSettings sets the tuned settings in the configuration file, leaving its other settings as they are.
+// The file is read by its own viper instance, without the environment variables bound by db.LoadConfigFile,
+// so that no value supplied by the environment (e.g. the DSN and its password) is written to the file.
+//
+// Parameters:
+// - path: The configuration file.
+// - tuned: The tuned values, keyed by their configuration key.
+//
+// Returns:
+// - error: An error if the file cannot be read or written.
+func writeTunedSettings(path string, tuned map[string]int) error {
+	file_config := viper.New()
+	file_config.SetConfigFile(path)
+	if err := file_config.ReadInConfig(); err != nil {
+		return err
+	}
+
+	for key, value := range tuned {
+		file_config.Set(key, value)
+	}
+
+	return file_config.WriteConfig()
+}
+
+// loadDataset loads the calculation inputs from the given repositories.
+func loadDataset(
+	manager_chain_repo interfaces.ManagerChainRepository,
+	direct_manager_repo interfaces.DirectManagerRepository,
+	user_repo interfaces.UserRepository,
+	manager_chain_options service.ManagerChainOptions,
+) ([]model.ManagerChain, []*model.User, error) {
+	ctx := context.Background()
+
+	manager_chains, err := service.FetchManagerChains(ctx, manager_chain_repo, direct_manager_repo, manager_chain_options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users, err := user_repo.FetchEmployeesWithAssociatedProfiles(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return manager_chains, users, nil
+}
+
+// autotuner measures the calculation pipeline for candidate PipelineOptions.
+type autotuner struct {
+	rounds        int
+	profileTypes  service.ProfileTypes
+	usersProfiles []*security_model.UserProfiles
+	input         security_model.SecurityMatrixCalculationFilterInput
+}
+
+// best measures every candidate value and returns the fastest one.
+func (t *autotuner) best(name string, candidates []int, options func(value int) service.PipelineOptions) int {
+	best_value, best_duration := candidates[0], time.Duration(0)
+
+	for _, value := range candidates {
+		duration := median(t.rounds, func() time.Duration {
+			start := time.Now()
+			t.collectRules(options(value))
+			return time.Since(start)
+		})
+		log.Printf("%s=%d: %v", name, value, duration)
+
+		if best_duration == 0 || duration < best_duration {
+			best_value, best_duration = value, duration
+		}
+	}
+
+	log.Printf("Best %s: %d", name, best_value)
+	return best_value
+}
+
+// collectRules runs the calculation pipeline with the given options and returns every final rule.
+func (t *autotuner) collectRules(options service.PipelineOptions) []security_model.GoMatrixRule {
+	calculator := service.NewSecurityMatrixCalculatorService(
+		nil, nil, nil, nil, nil, nil, nil,
+		filters.ProvideManagerFilters(filters.NewAllowReportsFilter(), filters.NewDenyManagersFilter(), filters.NewDenyProfileLevelsFilter()),
+		filters.ProvideEmployeeFilters(filters.NewAllowSelfFilter(), filters.NewDenySelfFilter()),
+		service.ManagerChainOptions{},
+		service.DataQualityOptions{},
+		options,
+		t.profileTypes,
+		service.RetryOptions{},
+		service.TimeoutOptions{},
+		service.FilterFailureOptions{},
+		metrics.NewMetrics(),
+		noop.NewTracerProvider(),
+		slog.Default(),
+	)
+
+	var rules []security_model.GoMatrixRule
+	rules_channel, wait := calculator.StreamRules(context.Background(), t.usersProfiles, t.input)
+	for user_rules := range rules_channel {
+		rules = append(rules, user_rules...)
+	}
+	if _, err := wait(); err != nil {
+		log.Fatalf("Error calculating the rules: %v", err)
+	}
+
+	return rules
+}
+
+// tuneBatchSize measures WriteRules on the batched insert path for candidate batch sizes and returns the fastest one.
+// Every measurement replaces the GoMatrixRule table of the given database.
+func tuneBatchSize(gormDB *gorm.DB, config *db.Config, rules []security_model.GoMatrixRule, rounds int) int {
+	best_value, best_duration := 0, time.Duration(0)
+
+	for _, batch_size := range []int{50, 100, 156, 200, 300, 400, 500, 600, 700} {
+		candidate := *config
+		candidate.RuleWriteMode = repository.RuleWriteModeBatchedInsert
+		candidate.Calculation.BatchSize = batch_size
+
+		rule_repo, err := repository.NewSecurityMatrixRuleRepository(gormDB, &candidate)
+		if err != nil {
+			log.Printf("batch_size=%d: skipped (%v)", batch_size, err)
+			continue
+		}
+
+		duration := median(rounds, func() time.Duration {
+			start := time.Now()
+			if err := rule_repo.WriteRules(context.Background(), rules); err != nil {
+				log.Fatalf("Error writing rules with batch_size=%d: %v", batch_size, err)
+			}
+			return time.Since(start)
+		})
+		log.Printf("batch_size=%d: %v", batch_size, duration)
+
+		if best_duration == 0 || duration < best_duration {
+			best_value, best_duration = batch_size, duration
+		}
+	}
+
+	log.Printf("Best batch_size: %d", best_value)
+	return best_value
+}
+
+// workerCandidates returns powers of two up to twice the number of CPUs, plus the number of CPUs itself.
+func workerCandidates() []int {
+	num_cpu := runtime.NumCPU()
+	candidates := []int{num_cpu}
+	for workers := 1; workers <= 2*num_cpu; workers *= 2 {
+		if workers != num_cpu {
+			candidates = append(candidates, workers)
+		}
+	}
+	sort.Ints(candidates)
+
+	return candidates
+}
+
+// bufferCandidates returns channel buffer sizes relative to the number of workers.
+func bufferCandidates(workers int) []int {
+	candidates := []int{1}
+	for _, multiplier := range []int{1, 2, 4, 16} {
+		if workers*multiplier > candidates[len(candidates)-1] {
+			candidates = append(candidates, workers*multiplier)
+		}
+	}
+
+	return candidates
+}
+
+// median runs measure the given number of times and returns the median duration.
+func median(rounds int, measure func() time.Duration) time.Duration {
+	if rounds < 1 {
+		rounds = 1
+	}
+
+	durations := make([]time.Duration, rounds)
+	for i := range durations {
+		durations[i] = measure()
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	return durations[rounds/2]
+}
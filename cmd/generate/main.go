@@ -0,0 +1,82 @@
+// Command generate generates a synthetic organization and writes it to a fixture file or to the configured database.
+//
+// Usage:
+//
+//	go run ./cmd/generate -employees 20000 -divisions 4 -output ./org.yaml
+//	go run ./cmd/generate -employees 20000 -config ./config/config.yaml -database
+//
+// Writing to the database replaces the content of the Employees, ManagerChains, DirectManagers, Profiles, Users
+// and ProfileUsers tables: only use it against a development or benchmark database.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/fixture"
+	"github.com/nuno-bastos/gin-gonic-wire-api/generator"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+)
+
+func main() {
+	options := generator.DefaultOptions()
+	flag.IntVar(&options.Employees, "employees", options.Employees, "number of employees")
+	flag.IntVar(&options.MaxDepth, "max-depth", options.MaxDepth, "maximum number of management levels below the root employee, 0 for unlimited")
+	flag.IntVar(&options.MinSpan, "min-span", options.MinSpan, "minimum number of direct reports per manager")
+	flag.IntVar(&options.MaxSpan, "max-span", options.MaxSpan, "maximum number of direct reports per manager")
+	flag.IntVar(&options.ManagerLevels, "manager-levels", options.ManagerLevels, "number of MANAGER profile levels")
+	flag.IntVar(&options.Divisions, "divisions", options.Divisions, "number of divisions")
+	flag.Float64Var(&options.InactiveRatio, "inactive-ratio", options.InactiveRatio, "share of inactive employees")
+	flag.Float64Var(&options.ExtraManagerProfileRatio, "extra-manager-profile-ratio", options.ExtraManagerProfileRatio, "share of managers also holding the MANAGER profile one level below their own")
+	flag.Float64Var(&options.NonEmployeeUserRatio, "non-employee-user-ratio", options.NonEmployeeUserRatio, "users who are not employees, relative to the number of employees")
+	flag.Int64Var(&options.Seed, "seed", options.Seed, "seed of the random generator")
+	output := flag.String("output", "", "fixture file to write (.yaml, .yml or .json)")
+	to_database := flag.Bool("database", false, "write to the configured database (replaces the input tables)")
+	config_path := flag.String("config", "./config/config.yaml", "configuration file of the database")
+	flag.Parse()
+
+	if *output == "" && !*to_database {
+		log.Fatal("Nothing to do: set -output and/or -database")
+	}
+
+	// The organization written to the database holds the configured profile types
+	var config *db.Config
+	if *to_database {
+		var err error
+		if config, err = db.LoadConfigFile(*config_path); err != nil {
+			log.Fatalf("Error loading configuration: %v", err)
+		}
+		options.ProfileTypes = service.NewProfileTypes(config)
+	}
+
+	organization, err := generator.Generate(options)
+	if err != nil {
+		log.Fatalf("Error generating the organization: %v", err)
+	}
+	log.Printf("Generated %d employees, %d manager chains, %d profiles and %d users",
+		len(organization.Employees), len(organization.ManagerChains), len(organization.Profiles), len(organization.Users))
+
+	if *output != "" {
+		if err := fixture.Save(*output, organization.Fixture()); err != nil {
+			log.Fatalf("Error writing the fixture: %v", err)
+		}
+		log.Printf("Fixture written to %s", *output)
+	}
+
+	if *to_database {
+		gormDB, err := db.ConnectDatabase(context.Background(), config, noop.NewTracerProvider(), slog.Default())
+		if err != nil {
+			log.Fatalf("Error connecting to the database: %v", err)
+		}
+
+		if err := organization.Write(context.Background(), gormDB); err != nil {
+			log.Fatalf("Error writing the organization to the database: %v", err)
+		}
+		log.Printf("Organization written to the %s database", gormDB.Dialector.Name())
+	}
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigFile writes the configuration file and returns its path.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "database_dsn: file-dsn\n"+
+		"calculation:\n  workers: 2\n  batch_size: 100\n"+
+		"server:\n  base_path: /file\n"+
+		"auth:\n  disabled: true\n")
+
+	t.Setenv("SECURITY_MATRIX_CALCULATION_WORKERS", "4")
+	t.Setenv("SECURITY_MATRIX_CALCULATION_TIMEOUTS_TOTAL", "10m")
+	t.Setenv("SECURITY_MATRIX_SERVER_BASE_PATH", "/env")
+	t.Setenv("SECURITY_MATRIX_SERVER_TRUSTED_PROXIES", "10.0.0.0/8,192.168.1.1")
+	t.Setenv("SECURITY_MATRIX_PROFILE_TYPES_ADMIN", "PFTADMIN")
+
+	config, err := LoadConfigFileWithOverrides(path, map[string]string{"server.base_path": "/flag"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "file-dsn", config.DatabaseDSN)
+	assert.Equal(t, 100, config.Calculation.BatchSize)
+	assert.Equal(t, 4, config.Calculation.Workers)                                       // The environment overrides the file
+	assert.Equal(t, "/flag", config.Server.BasePath)                                     // The flags override the environment
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, config.Server.TrustedProxies) // Comma-separated lists
+	assert.Equal(t, "PFTADMIN", config.ProfileTypes.Admin)
+	assert.Equal(t, 10*time.Minute, config.Calculation.Timeouts.Total)
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	working_directory, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir())) // No ./config/config.yaml
+	t.Cleanup(func() { os.Chdir(working_directory) })
+
+	t.Setenv("SECURITY_MATRIX_DATABASE_DRIVER", DriverSQLite)
+	t.Setenv("SECURITY_MATRIX_DATABASE_DSN", "file::memory:")
+	t.Setenv("SECURITY_MATRIX_SERVER_SHUTDOWN_TIMEOUT", "45s")
+	t.Setenv("SECURITY_MATRIX_AUTH_DISABLED", "true")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, DriverSQLite, config.DatabaseDriver)
+	assert.Equal(t, "file::memory:", config.DatabaseDSN)
+	assert.Equal(t, 45*time.Second, config.Server.ShutdownTimeout)
+	assert.True(t, config.Auth.Disabled)
+
+	// An explicit file must exist
+	_, err = LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestLoadConfigListsEveryProblem(t *testing.T) {
+	path := writeConfigFile(t, "database_driver: oracle\n"+
+		"calculation:\n  workers: -1\n  timeouts:\n    write: -1m\n  filter_failure_policy: ignore\n  snapshot_isolation: chaos\n"+
+		"server:\n  base_path: api\n  trusted_proxies: [localhost]\n"+
+		"auth:\n  api_keys:\n    - name: admin\n      key: secret\n    - name: admin\n"+
+		"profile_types:\n  admin: PFT1\n  manager: PFT1\n"+
+		"tracing:\n  sample_ratio: 2\n")
+
+	_, err := LoadConfigFile(path)
+
+	var config_err *ConfigError
+	require.True(t, errors.As(err, &config_err), "%v", err)
+	assert.Equal(t, []string{
+		`database_driver: unknown value "oracle", expected sqlserver, postgres, sqlite`,
+		"database_dsn: is required",
+		"calculation.workers: must not be negative, got -1",
+		"calculation.timeouts.write: must not be negative, got -1m0s",
+		`calculation.filter_failure_policy: unknown value "ignore", expected abort, skip_user`,
+		`calculation.snapshot_isolation: unknown value "chaos", expected snapshot, serializable, read_committed`,
+		`server.base_path: must start and must not end with a slash, got "api"`,
+		`server.trusted_proxies: "localhost" is neither an IP address nor a CIDR range`,
+		"auth.api_keys[1]: name and key are required",
+		`auth.api_keys[1]: the name "admin" is used by another key`,
+		`profile_types.manager: "PFT1" is also the id of profile_types.admin`,
+		"tracing.sample_ratio: must be between 0 and 1, got 2",
+	}, config_err.Problems)
+	assert.Contains(t, err.Error(), "invalid configuration:\n  - database_driver")
+}
+
+func TestValidateAcceptsTheDefaults(t *testing.T) {
+	config := Config{DatabaseDSN: "sqlserver://localhost", Auth: AuthConfig{Disabled: true}}
+	assert.NoError(t, config.Validate())
+}
+
+func TestValidateRequiresAnAuthenticator(t *testing.T) {
+	api_keys := []APIKeyConfig{{Name: "scheduler", Key: "s3cr3t"}}
+	tests := []struct {
+		name     string
+		auth     AuthConfig
+		problems []string
+	}{
+		{
+			name:     "none",
+			auth:     AuthConfig{},
+			problems: []string{"auth: no authenticator is configured, set auth.api_keys or auth.jwt.jwks_url, or auth.disabled: true to serve the API without authentication"},
+		},
+		{name: "disabled", auth: AuthConfig{Disabled: true}},
+		{name: "api keys", auth: AuthConfig{APIKeys: api_keys}},
+		{name: "jwt", auth: AuthConfig{JWT: JWTConfig{JWKSURL: "https://issuer.example.com/jwks.json"}}},
+		{
+			name:     "disabled with api keys",
+			auth:     AuthConfig{APIKeys: api_keys, Disabled: true},
+			problems: []string{"auth.disabled: must not be set along with auth.api_keys or auth.jwt.jwks_url"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{DatabaseDSN: "sqlserver://localhost", Auth: test.auth}
+
+			err := config.Validate()
+
+			if test.problems == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var config_err *ConfigError
+			require.True(t, errors.As(err, &config_err), "%v", err)
+			assert.Equal(t, test.problems, config_err.Problems)
+		})
+	}
+}
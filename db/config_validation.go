@@ -0,0 +1,201 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Supported values of the manager_chain_source configuration.
+const (
+	ManagerChainSourceManagerChains  = "manager_chains"
+	ManagerChainSourceDirectManagers = "direct_managers"
+)
+
+// Accepted values of the settings checked by Validate, "" standing for the default. The packages reading the settings
+// define them as constants (e.g. repo.RuleWriteModeBulkCopy, tracing.ExporterOTLP).
+var (
+	databaseDrivers       = []string{"", DriverSQLServer, DriverPostgres, DriverSQLite}
+	managerChainSources   = []string{"", ManagerChainSourceManagerChains, ManagerChainSourceDirectManagers}
+	ruleWriteModes        = []string{"", "auto", "bulk_copy", "batched_insert"}
+	filterFailurePolicies = []string{"", "abort", "skip_user"}
+	snapshotIsolations    = []string{"", "snapshot", "serializable", "read_committed"}
+	jwtUserFields         = []string{"", "email", "id"}
+	tracingExporters      = []string{"", "none", "stdout", "otlp"}
+	logFormats            = []string{"", "json", "text"}
+)
+
+// ConfigError lists every problem found in the configuration.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// configProblems collects the problems found while validating the configuration.
+type configProblems []string
+
+func (p *configProblems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p *configProblems) oneOf(key string, value string, accepted []string) {
+	for _, accepted_value := range accepted {
+		if value == accepted_value {
+			return
+		}
+	}
+
+	p.add("%s: unknown value %q, expected %s", key, value, strings.Join(accepted[1:], ", "))
+}
+
+func (p *configProblems) notNegative(key string, value time.Duration) {
+	if value < 0 {
+		p.add("%s: must not be negative, got %s", key, value)
+	}
+}
+
+func (p *configProblems) notNegativeInt(key string, value int) {
+	if value < 0 {
+		p.add("%s: must not be negative, got %d", key, value)
+	}
+}
+
+func (p *configProblems) retry(key string, value RetryConfig) {
+	p.notNegativeInt(key+".max_attempts", value.MaxAttempts)
+	p.notNegative(key+".initial_backoff", value.InitialBackoff)
+	p.notNegative(key+".max_backoff", value.MaxBackoff)
+}
+
+// Validate checks every setting of the configuration, zero values standing for the defaults.
+//
+// Returns:
+// - error: a *ConfigError listing every problem found, nil when the configuration is valid.
+func (c *Config) Validate() error {
+	var problems configProblems
+
+	// Database
+	problems.oneOf("database_driver", c.DatabaseDriver, databaseDrivers)
+	if c.DatabaseDSN == "" {
+		problems.add("database_dsn: is required")
+	}
+	problems.notNegativeInt("database.max_open_conns", c.Database.MaxOpenConns)
+	problems.notNegativeInt("database.max_idle_conns", c.Database.MaxIdleConns)
+	problems.notNegative("database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	problems.notNegative("database.conn_max_idle_time", c.Database.ConnMaxIdleTime)
+	problems.retry("database.connect_retry", c.Database.ConnectRetry)
+
+	// Calculation
+	problems.oneOf("manager_chain_source", c.ManagerChainSource, managerChainSources)
+	problems.oneOf("rule_write_mode", c.RuleWriteMode, ruleWriteModes)
+	if c.RuleWriteMode == "bulk_copy" && c.DatabaseDriver != "" && c.DatabaseDriver != DriverSQLServer {
+		problems.add("rule_write_mode: bulk_copy is only supported with the sqlserver driver, not %s", c.DatabaseDriver)
+	}
+	problems.notNegativeInt("calculation.workers", c.Calculation.Workers)
+	problems.notNegativeInt("calculation.users_channel_buffer", c.Calculation.UsersChannelBuffer)
+	problems.notNegativeInt("calculation.rules_channel_buffer", c.Calculation.RulesChannelBuffer)
+	problems.notNegativeInt("calculation.batch_size", c.Calculation.BatchSize)
+	problems.retry("calculation.retry", c.Calculation.Retry)
+	problems.notNegative("calculation.timeouts.read", c.Calculation.Timeouts.Read)
+	problems.notNegative("calculation.timeouts.write", c.Calculation.Timeouts.Write)
+	problems.notNegative("calculation.timeouts.total", c.Calculation.Timeouts.Total)
+	problems.oneOf("calculation.filter_failure_policy", c.Calculation.FilterFailurePolicy, filterFailurePolicies)
+	problems.oneOf("calculation.snapshot_isolation", c.Calculation.SnapshotIsolation, snapshotIsolations)
+
+	// Server
+	if c.ServerAddress != "" {
+		if _, _, err := net.SplitHostPort(c.ServerAddress); err != nil {
+			problems.add("server_address: %v", err)
+		}
+	}
+	problems.notNegative("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	problems.notNegative("server.read_timeout", c.Server.ReadTimeout)
+	problems.notNegative("server.write_timeout", c.Server.WriteTimeout)
+	problems.notNegative("server.idle_timeout", c.Server.IdleTimeout)
+	problems.notNegative("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	if c.Server.BasePath != "" && (!strings.HasPrefix(c.Server.BasePath, "/") || strings.HasSuffix(c.Server.BasePath, "/")) {
+		problems.add("server.base_path: must start and must not end with a slash, got %q", c.Server.BasePath)
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems.add("server.trusted_proxies: %q is neither an IP address nor a CIDR range", proxy)
+			}
+		}
+	}
+	problems.notNegative("staleness_threshold", c.StalenessThreshold)
+
+	// Authentication, which fails closed: an API without authenticators is only served when explicitly disabled
+	has_authenticators := len(c.Auth.APIKeys) > 0 || c.Auth.JWT.JWKSURL != ""
+	if !has_authenticators && !c.Auth.Disabled {
+		problems.add("auth: no authenticator is configured, set auth.api_keys or auth.jwt.jwks_url, or auth.disabled: true to serve the API without authentication")
+	}
+	if has_authenticators && c.Auth.Disabled {
+		problems.add("auth.disabled: must not be set along with auth.api_keys or auth.jwt.jwks_url")
+	}
+	api_key_names := make(map[string]bool)
+	for i, api_key := range c.Auth.APIKeys {
+		if api_key.Name == "" || api_key.Key == "" {
+			problems.add("auth.api_keys[%d]: name and key are required", i)
+		}
+		if api_key_names[api_key.Name] {
+			problems.add("auth.api_keys[%d]: the name %q is used by another key", i, api_key.Name)
+		}
+		api_key_names[api_key.Name] = true
+	}
+	if c.Auth.JWT.JWKSURL != "" {
+		if jwks_url, err := url.Parse(c.Auth.JWT.JWKSURL); err != nil || (jwks_url.Scheme != "https" && jwks_url.Scheme != "http") || jwks_url.Host == "" {
+			problems.add("auth.jwt.jwks_url: must be an absolute http(s) URL, got %q", c.Auth.JWT.JWKSURL)
+		}
+	}
+	problems.oneOf("auth.jwt.user_field", c.Auth.JWT.UserField, jwtUserFields)
+	problems.notNegative("auth.jwt.refresh_interval", c.Auth.JWT.RefreshInterval)
+	problems.notNegative("auth.jwt.leeway", c.Auth.JWT.Leeway)
+
+	// Parity
+	problems.notNegativeInt("parity.max_samples_per_user", c.Parity.MaxSamplesPerUser)
+
+	// Profile types
+	profile_types := map[string]string{}
+	for _, profile_type := range []struct{ key, id string }{
+		{"profile_types.admin", c.ProfileTypes.Admin},
+		{"profile_types.manager", c.ProfileTypes.Manager},
+		{"profile_types.employee", c.ProfileTypes.Employee},
+	} {
+		if profile_type.id == "" {
+			continue
+		}
+		if other, found := profile_types[profile_type.id]; found {
+			problems.add("%s: %q is also the id of %s", profile_type.key, profile_type.id, other)
+		}
+		profile_types[profile_type.id] = profile_type.key
+	}
+
+	// Observability
+	problems.oneOf("tracing.exporter", c.Tracing.Exporter, tracingExporters)
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems.add("tracing.sample_ratio: must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+	if c.Logging.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.Logging.Level)); err != nil {
+			problems.add("logging.level: unknown value %q, expected debug, info, warn or error", c.Logging.Level)
+		}
+	}
+	problems.oneOf("logging.format", strings.ToLower(c.Logging.Format), logFormats)
+	if _, found := gormLogLevels[strings.ToLower(c.Logging.GormLevel)]; c.Logging.GormLevel != "" && !found {
+		problems.add("logging.gorm_level: unknown value %q, expected silent, error, warn or info", c.Logging.GormLevel)
+	}
+	problems.notNegative("logging.slow_query_threshold", c.Logging.SlowQueryThreshold)
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+
+	return nil
+}
@@ -1,69 +1,409 @@
 package db
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
-	"gorm.io/driver/sqlserver"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"gorm.io/gorm/schema"
+	otelgorm "gorm.io/plugin/opentelemetry/tracing"
 )
 
 // Config represents application configuration settings.
 type Config struct {
 	DatabaseDSN string `mapstructure:"database_dsn"`
+	// DatabaseDriver selects the database: "sqlserver" (default), "postgres" or "sqlite".
+	DatabaseDriver string `mapstructure:"database_driver"`
+	// Database tunes the connection pool and the retries of the first connection.
+	Database DatabaseConfig `mapstructure:"database"`
+
+	// ManagerChainSource selects where the manager chains are read from:
+	// "manager_chains" (default) reads the expanded Core.ManagerChains table,
+	// "direct_managers" derives the closure from the Core.DirectManagers edges.
+	ManagerChainSource string `mapstructure:"manager_chain_source"`
+	// MaterializeManagerChains writes the derived closure back to Core.ManagerChains
+	// for other consumers (only used with the "direct_managers" source).
+	MaterializeManagerChains bool `mapstructure:"materialize_manager_chains"`
+
+	// EnforceDataQuality aborts the calculation when the referential-integrity checks
+	// between Users, Employees and Profiles find any issue.
+	EnforceDataQuality bool `mapstructure:"enforce_data_quality"`
+
+	// RuleWriteMode selects how GoMatrixRule rows are inserted: "auto" (default, bulk copy on SQL Server),
+	// "bulk_copy" or "batched_insert".
+	RuleWriteMode string `mapstructure:"rule_write_mode"`
+
+	// Calculation holds the performance settings of the calculation pipeline.
+	Calculation CalculationConfig `mapstructure:"calculation"`
+
+	// ServerAddress is the address the HTTP server listens on (default ":8080").
+	ServerAddress string `mapstructure:"server_address"`
+
+	// Server holds the timeouts of the HTTP server and of its graceful shutdown.
+	Server ServerConfig `mapstructure:"server"`
+
+	// Auth configures the authentication of the API: static API keys and/or JWT bearer tokens.
+	// One of them is required, unless the authentication is explicitly disabled.
+	Auth AuthConfig `mapstructure:"auth"`
+
+	// ProfileTypes holds the ids of the ADMIN, MANAGER and EMPLOYEE profile types.
+	ProfileTypes ProfileTypesConfig `mapstructure:"profile_types"`
+
+	// Parity locates the matrix table of the legacy implementation, compared with GoMatrixRule by the parity checker.
+	Parity ParityConfig `mapstructure:"parity"`
+
+	// StalenessThreshold is the age above which the freshness endpoint reports the matrix as stale (default 24h).
+	StalenessThreshold time.Duration `mapstructure:"staleness_threshold"`
+
+	// Tracing configures the OpenTelemetry traces of the HTTP requests, the calculation and the database queries.
+	Tracing TracingConfig `mapstructure:"tracing"`
+
+	// Logging configures the structured logs of the application and of the database queries.
+	Logging LoggingConfig `mapstructure:"logging"`
+}
+
+// CalculationConfig represents the performance settings of the calculation pipeline.
+// Zero values fall back to the defaults, see cmd/autotune to search the best values for a machine.
+type CalculationConfig struct {
+	Workers            int `mapstructure:"workers"`              // Number of worker goroutines (default: number of CPUs)
+	UsersChannelBuffer int `mapstructure:"users_channel_buffer"` // Buffer of the channel feeding users to workers (default: workers)
+	RulesChannelBuffer int `mapstructure:"rules_channel_buffer"` // Buffer of the channel feeding rules to the writer (default: workers)
+	BatchSize          int `mapstructure:"batch_size"`           // Rows per INSERT statement of the batched insert path (default: 156)
+
+	// Retry bounds the retries of the read phase and of the write transaction failing with a transient error
+	// (default: 3 attempts, 1s backoff up to 30s)
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Timeouts bound the duration of the calculation and of its phases (default: no timeout)
+	Timeouts TimeoutsConfig `mapstructure:"timeouts"`
+
+	// FilterFailurePolicy decides what a filter failure (an error or a panic) does to the calculation:
+	// "abort" fails it (the default), "skip_user" calculates no rule for the user and records them as skipped
+	FilterFailurePolicy string `mapstructure:"filter_failure_policy"`
+
+	// SnapshotIsolation is the isolation of the transaction reading the inputs of a calculation: "snapshot" (the default,
+	// SNAPSHOT on SQL Server, which needs ALLOW_SNAPSHOT_ISOLATION ON), "serializable" (consistent too, but blocking the
+	// writers of the inputs until they are read) or "read_committed" (no consistent snapshot across the reads)
+	SnapshotIsolation string `mapstructure:"snapshot_isolation"`
+}
+
+// TimeoutsConfig bounds the duration of the calculation and of its phases, retries included. Zero disables a timeout.
+type TimeoutsConfig struct {
+	Read  time.Duration `mapstructure:"read"`  // Reading the inputs from their snapshot
+	Write time.Duration `mapstructure:"write"` // Calculating the rules and writing them
+	Total time.Duration `mapstructure:"total"` // The whole calculation
+}
+
+// DatabaseConfig tunes the connection pool of the database. Zero values keep the database/sql defaults:
+// no limit on the open connections, 2 idle connections, connections reused forever.
+type DatabaseConfig struct {
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`     // Open connections, in use or idle
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`     // Idle connections kept in the pool
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`  // Age after which a connection is closed, e.g. to follow a failover
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"` // Idle time after which a connection is closed
+
+	// ConnectRetry bounds the attempts to connect at startup, while the database is not reachable yet
+	// (default: 5 attempts, 1s backoff up to 15s)
+	ConnectRetry RetryConfig `mapstructure:"connect_retry"`
+}
+
+// RetryConfig bounds the attempts of an operation failing with a transient error and the backoff between them.
+type RetryConfig struct {
+	MaxAttempts    int           `mapstructure:"max_attempts"`    // Attempts, the first one included: 1 disables the retries
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"` // Wait after the first failed attempt, doubled after each other one
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`     // Cap of the wait between two attempts
+}
+
+// WithDefaults returns the RetryConfig with its unset values replaced by the given ones.
+//
+// Parameters:
+// - max_attempts: The default number of attempts.
+// - initial_backoff: The default wait after the first failed attempt.
+// - max_backoff: The default cap of the wait between two attempts.
+//
+// Returns:
+// - RetryConfig: The retry configuration.
+func (c RetryConfig) WithDefaults(max_attempts int, initial_backoff time.Duration, max_backoff time.Duration) RetryConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = max_attempts
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = initial_backoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = max_backoff
+	}
+
+	return c
+}
+
+// AuthConfig represents the credentials accepted by the API, and how their principals map to Security.Users rows.
+type AuthConfig struct {
+	APIKeys  []APIKeyConfig `mapstructure:"api_keys"` // Keys accepted in the X-API-Key header
+	JWT      JWTConfig      `mapstructure:"jwt"`      // Bearer tokens, enabled by jwks_url
+	Disabled bool           `mapstructure:"disabled"` // Serve the API to anyone reaching it, without api_keys nor jwt (e.g. for local development)
+}
+
+type APIKeyConfig struct {
+	Name   string `mapstructure:"name"` // Principal of the requests made with the key, e.g. "batch-scheduler"
+	Key    string `mapstructure:"key"`
+	UserId string `mapstructure:"user_id"` // Security.Users row the key acts as, the key is refused by authorization without it
+}
+
+type JWTConfig struct {
+	JWKSURL         string        `mapstructure:"jwks_url"`         // URL of the JSON Web Key Set of the issuer
+	Issuer          string        `mapstructure:"issuer"`           // Required "iss" claim, not checked when empty
+	Audience        string        `mapstructure:"audience"`         // Required "aud" claim, not checked when empty
+	Algorithms      []string      `mapstructure:"algorithms"`       // Accepted signing algorithms (default: RS256, ES256)
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"` // Maximum age of the cached key set (default: 15m)
+	Leeway          time.Duration `mapstructure:"leeway"`           // Clock skew tolerated on exp, nbf and iat (default: 0)
+	UserClaim       string        `mapstructure:"user_claim"`       // Claim identifying the Security.Users row (default: email)
+	UserField       string        `mapstructure:"user_field"`       // User field the claim is matched with: email (default) or id
+}
+
+// ParityConfig locates the legacy matrix table and sets the detail of the parity report.
+type ParityConfig struct {
+	LegacyTable       string `mapstructure:"legacy_table"`         // Schema-qualified table name (default: Security.MatrixRules)
+	UserColumn        string `mapstructure:"user_column"`          // Column of the viewer user id (default: UserId)
+	EmployeeColumn    string `mapstructure:"employee_column"`      // Column of the viewed employee id (default: EmployeeId)
+	AccessLevelColumn string `mapstructure:"access_level_column"`  // Column of the read access level (default: AccessLevelRead)
+	MaxSamplesPerUser int    `mapstructure:"max_samples_per_user"` // Mismatches listed per user in the report (default: 10)
+}
+
+// TracingConfig selects where the OpenTelemetry spans are exported and which traces are recorded.
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`     // "none" (default), "stdout" or "otlp"
+	Endpoint    string  `mapstructure:"endpoint"`     // host:port of the OTLP/HTTP collector (default: OTEL_EXPORTER_OTLP_ENDPOINT, or localhost:4318)
+	Insecure    bool    `mapstructure:"insecure"`     // Send the spans to the collector over plain HTTP
+	ServiceName string  `mapstructure:"service_name"` // service.name resource attribute (default: gin-gonic-wire-api)
+	SampleRatio float64 `mapstructure:"sample_ratio"` // Fraction of the new traces recorded, from 0 (excluded) to 1 (default: 1)
+}
+
+// ServerConfig represents the routing and the timeouts of the HTTP server. Zero values fall back to the defaults.
+type ServerConfig struct {
+	BasePath          string        `mapstructure:"base_path"`           // Prefix of the API routes (default: /github.com/nuno-bastos/gin-gonic-wire-api)
+	TrustedProxies    []string      `mapstructure:"trusted_proxies"`     // IP addresses or CIDR ranges whose X-Forwarded-For is trusted (default: 127.0.0.1, ::1)
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"` // Time allowed to read the request headers (default: 10s)
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`        // Time allowed to read the whole request (default: 30s)
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`       // Time allowed to serve a request, a calculation included (default: 15m)
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`        // Time a keep-alive connection waits for the next request (default: 2m)
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"`    // Time given to the in-flight requests to finish once stopping, before they are cancelled (default: 20s)
 }
 
+// ProfileTypesConfig holds the Security.ProfileTypes ids of each type of profile, for databases using other ids.
+type ProfileTypesConfig struct {
+	Admin    string `mapstructure:"admin"`    // Default: PFT11111111111111111
+	Manager  string `mapstructure:"manager"`  // Default: PFT22222222222222222
+	Employee string `mapstructure:"employee"` // Default: PFT33333333333333333
+}
+
+// LoggingConfig sets the level and format of the logs, and which database queries are logged.
+type LoggingConfig struct {
+	Level              string        `mapstructure:"level"`                // debug, info (default), warn or error
+	Format             string        `mapstructure:"format"`               // json (default) or text
+	GormLevel          string        `mapstructure:"gorm_level"`           // silent, error, warn (default: failed and slow queries) or info (every query)
+	SlowQueryThreshold time.Duration `mapstructure:"slow_query_threshold"` // Duration above which a query is logged as slow (default: 200ms)
+}
+
+// EnvPrefix prefixes the environment variables overriding the configuration: SECURITY_MATRIX_DATABASE_DSN overrides
+// database_dsn, SECURITY_MATRIX_CALCULATION_WORKERS overrides calculation.workers, and so on.
+const EnvPrefix = "SECURITY_MATRIX"
+
 // LoadConfig loads configuration from a file.
 func LoadConfig() (*Config, error) {
-	// Initialize viper
-	viper.SetConfigName("config")   // Name of the configuration file (config.yaml)
-	viper.AddConfigPath("./config") // Search the config directory for the configuration file
-	viper.SetConfigType("yaml")     // Config file format
+	return LoadConfigFile("")
+}
+
+// LoadConfigFile loads configuration from the given file, or from ./config/config.yaml when path is empty.
+func LoadConfigFile(path string) (*Config, error) {
+	return LoadConfigFileWithOverrides(path, nil)
+}
+
+// LoadConfigFileWithOverrides loads configuration like LoadConfigFile, then applies the given values on top of it.
+// The environment variables (see EnvPrefix) override the file, and the given values override both. Without an
+// explicit path, a missing ./config/config.yaml is not an error: the configuration then comes from the environment.
+// Lists are set in the environment as comma-separated values; the API keys can only be set in the file.
+//
+// Parameters:
+// - path: the configuration file, ./config/config.yaml when empty.
+// - overrides: values keyed by their configuration key (e.g. "calculation.workers"), typically set by command-line flags.
+//
+// Returns:
+// - *Config: the configuration.
+// - error: an error if the file cannot be read or a value cannot be decoded, a *ConfigError if any setting is invalid.
+func LoadConfigFileWithOverrides(path string, overrides map[string]string) (*Config, error) {
+	// Initialize viper, dropping the files and overrides of a previous load
+	viper.Reset()
+	if path != "" {
+		viper.SetConfigFile(path) // Explicit configuration file
+	} else {
+		viper.SetConfigName("config")   // Name of the configuration file (config.yaml)
+		viper.AddConfigPath("./config") // Search the config directory for the configuration file
+		viper.SetConfigType("yaml")     // Config file format
+	}
 
 	// Read the configuration file
 	if err := viper.ReadInConfig(); err != nil {
-		log.Fatalf("Error reading config file: %v", err)
+		var not_found viper.ConfigFileNotFoundError
+		if path != "" || !errors.As(err, &not_found) {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+	}
+
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	if err := bindEnv(reflect.TypeOf(Config{}), ""); err != nil {
+		return nil, err
+	}
+
+	for key, value := range overrides {
+		viper.Set(key, value)
 	}
 
 	// Unmarshal the configuration into a struct
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
-		log.Fatalf("Unable to decode config into struct: %v", err)
+		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
 }
 
-// ConnectDatabase initializes and returns a connection to the SQL Server database.
+// bindEnv binds every configuration key of the struct type, nested under the given prefix, to its environment variable.
+// Lists of structs cannot be expressed by a single variable and are left out.
+func bindEnv(struct_type reflect.Type, prefix string) error {
+	for i := 0; i < struct_type.NumField(); i++ {
+		field := struct_type.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			if err := bindEnv(field.Type, key+"."); err != nil {
+				return err
+			}
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			continue
+		default:
+			if err := viper.BindEnv(key); err != nil {
+				return fmt.Errorf("error binding %s to the environment: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ConnectDatabase initializes and returns a connection to the configured database (SQL Server by default).
 // The function uses GORM for ORM capabilities and logs the connection process.
+// The connection pool is sized as set by the database configuration, and the database is pinged until it answers,
+// within database.connect_retry, so that the application can start along with its database. Only the transient
+// errors are retried (see IsTransientConnectError): bad credentials or a malformed DSN fail at once.
+// Every query is traced as a child span of the span in its context (see gorm.DB.WithContext),
+// with its statement but without its values, and logged as set by the logging configuration.
+//
+// Parameters:
+// - ctx: the context of the command, whose cancellation stops the connection attempts.
+// - config: the application configuration holding the database driver, DSN and pool settings.
+// - tracerProvider: the provider of the query spans.
+// - _logger: the structured logger receiving the GORM logs.
 //
 // Returns:
 // - *gorm.DB: a pointer to the database connection instance.
 // - error: an error object if the connection fails, nil otherwise.
-func ConnectDatabase() (*gorm.DB, error) {
+func ConnectDatabase(ctx context.Context, config *Config, tracerProvider trace.TracerProvider, _logger *slog.Logger) (*gorm.DB, error) {
+	dialector, err := OpenDialector(config.DatabaseDriver, config.DatabaseDSN)
+	if err != nil {
+		return nil, err
+	}
+
+	gorm_logger, err := NewGormLogger(_logger, config.Logging)
+	if err != nil {
+		return nil, err
+	}
 
-	// Load configuration from file
-	config, err := LoadConfig()
+	// Open the database connection using the specified DSN (Data Source Name), until the database answers
+	var db *gorm.DB
+	attempts := 0
+	connect_retry := config.Database.ConnectRetry.WithDefaults(5, time.Second, 15*time.Second)
+	err = Retry(ctx, connect_retry, func(err error) bool {
+		// A deadline of the command is not a login timeout of the driver
+		return ctx.Err() == nil && IsTransientConnectError(err)
+	}, func(attempt int, backoff time.Duration, err error) {
+		_logger.WarnContext(ctx, "Database not reachable yet, retrying", "driver", dialector.Name(), "attempt", attempt, "backoff", backoff, "error", err)
+	}, func(ctx context.Context) (err error) {
+		attempts++
+		db, err = openDatabase(ctx, config, gorm_logger)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database after %d attempt(s): %w", attempts, err)
+	}
+
+	if err := db.Use(otelgorm.NewPlugin(otelgorm.WithTracerProvider(tracerProvider), otelgorm.WithoutQueryVariables(), otelgorm.WithoutMetrics())); err != nil {
+		return nil, fmt.Errorf("error registering the tracing plugin: %w", err)
+	}
+
+	_logger.InfoContext(ctx, "Database connection established", "driver", dialector.Name())
+	return db, nil
+}
+
+// openDatabase opens a connection pool to the configured database, sized as configured, and pings the database.
+// The pool is closed when the database does not answer.
+func openDatabase(ctx context.Context, config *Config, gorm_logger logger.Interface) (*gorm.DB, error) {
+	// A new dialector on each attempt, some keep the connection they opened
+	dialector, err := OpenDialector(config.DatabaseDriver, config.DatabaseDSN)
 	if err != nil {
 		return nil, err
 	}
 
-	// Open the database connection using the specified DSN (Data Source Name)
-	db, dbErr := gorm.Open(sqlserver.Open(config.DatabaseDSN), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info), // Set logger to log detailed information
-		NamingStrategy: schema.NamingStrategy{
-			TablePrefix:   "",   // No prefix for table names
-			SingularTable: true, // Use singular table names
-			NoLowerCase:   true, // Preserve original case for table names
-		},
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:               gorm_logger,
+		NamingStrategy:       NewNamingStrategy(dialector.Name()),
+		DisableAutomaticPing: true,
 	})
-	if dbErr != nil {
-		log.Fatal("failed to connect database:", dbErr)
-		return nil, dbErr
+	if err != nil {
+		return nil, err
+	}
+
+	sql_db, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sql_db, config.Database)
+
+	if err := sql_db.PingContext(ctx); err != nil {
+		sql_db.Close()
+		return nil, err
 	}
 
-	log.Println("Database connection established")
 	return db, nil
 }
+
+// configurePool applies the configured limits and lifetimes to the connection pool, the unset ones keeping
+// the database/sql defaults.
+func configurePool(sql_db *sql.DB, config DatabaseConfig) {
+	if config.MaxOpenConns > 0 {
+		sql_db.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		sql_db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		sql_db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	if config.ConnMaxIdleTime > 0 {
+		sql_db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestConnectDatabaseConfiguresThePool(t *testing.T) {
+	config := &Config{
+		DatabaseDriver: DriverSQLite,
+		DatabaseDSN:    filepath.Join(t.TempDir(), "matrix.db"),
+		Database:       DatabaseConfig{MaxOpenConns: 4, MaxIdleConns: 2, ConnMaxLifetime: time.Hour},
+	}
+
+	gorm_db, err := ConnectDatabase(context.Background(), config, noop.NewTracerProvider(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	sql_db, err := gorm_db.DB()
+	require.NoError(t, err)
+	defer sql_db.Close()
+
+	assert.Equal(t, 4, sql_db.Stats().MaxOpenConnections)
+	assert.Equal(t, 1, sql_db.Stats().OpenConnections) // The ping
+}
+
+func TestConnectDatabaseDoesNotRetryPermanentErrors(t *testing.T) {
+	config := &Config{
+		DatabaseDriver: DriverSQLite,
+		DatabaseDSN:    filepath.Join(t.TempDir(), "missing", "matrix.db"), // Cannot be created
+		Database:       DatabaseConfig{ConnectRetry: RetryConfig{MaxAttempts: 3, InitialBackoff: time.Hour}},
+	}
+
+	_, err := ConnectDatabase(context.Background(), config, noop.NewTracerProvider(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.ErrorContains(t, err, "failed to connect database after 1 attempt(s)")
+}
+
+func TestConnectDatabaseStopsWhenTheContextIsCancelled(t *testing.T) {
+	config := &Config{
+		DatabaseDriver: DriverSQLite,
+		DatabaseDSN:    filepath.Join(t.TempDir(), "matrix.db"),
+		Database:       DatabaseConfig{ConnectRetry: RetryConfig{MaxAttempts: 3, InitialBackoff: time.Hour}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ConnectDatabase(ctx, config, noop.NewTracerProvider(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "failed to connect database after 1 attempt(s)")
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Supported values of the database_driver configuration.
+const (
+	DriverSQLServer = "sqlserver"
+	DriverPostgres  = "postgres"
+	DriverSQLite    = "sqlite"
+)
+
+// OpenDialector returns the GORM dialector of the given driver, SQL Server when driver is empty.
+//
+// Parameters:
+// - driver: the database driver name.
+// - dsn: the Data Source Name of the database.
+//
+// Returns:
+// - gorm.Dialector: the dialector to open the connection with.
+// - error: an error object if the driver is not supported, nil otherwise.
+func OpenDialector(driver string, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case "", DriverSQLServer:
+		return sqlserver.Open(dsn), nil
+	case DriverPostgres:
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database_driver %q", driver)
+	}
+}
+
+// NamingStrategy is the GORM naming strategy of the application. Tables keep their original case and
+// singular names, and schema-qualified tables (e.g. "Core.Employees") are mapped to prefixed tables
+// ("Core_Employees") on dialects without schemas.
+type NamingStrategy struct {
+	schema.NamingStrategy
+	UseSchemas bool
+}
+
+// NewNamingStrategy returns the naming strategy of the given dialect.
+func NewNamingStrategy(dialect string) NamingStrategy {
+	return NamingStrategy{
+		NamingStrategy: schema.NamingStrategy{
+			TablePrefix:   "",   // No prefix for table names
+			SingularTable: true, // Use singular table names
+			NoLowerCase:   true, // Preserve original case for table names
+		},
+		UseSchemas: dialect != DriverSQLite,
+	}
+}
+
+// SchemaTableName returns the name of a table living in the given schema.
+func (ns NamingStrategy) SchemaTableName(schemaName, table string) string {
+	if ns.UseSchemas {
+		return schemaName + "." + table
+	}
+
+	return schemaName + "_" + table
+}
+
+// JoinTableName maps schema-qualified many2many join tables the same way as SchemaTableName.
+func (ns NamingStrategy) JoinTableName(joinTable string) string {
+	if !ns.UseSchemas {
+		joinTable = strings.ReplaceAll(joinTable, ".", "_")
+	}
+
+	return ns.NamingStrategy.JoinTableName(joinTable)
+}
+
+// CreateSchemas creates the given schemas when they do not exist yet. It does nothing on dialects without schemas.
+//
+// Parameters:
+// - gormDB: the database connection.
+// - schemas: the names of the schemas to create.
+//
+// Returns:
+// - error: an error object if the creation fails, nil otherwise.
+func CreateSchemas(gormDB *gorm.DB, schemas ...string) error {
+	for _, schema_name := range schemas {
+		var statement string
+		switch gormDB.Dialector.Name() {
+		case DriverSQLServer:
+			statement = fmt.Sprintf("IF SCHEMA_ID('%s') IS NULL EXEC('CREATE SCHEMA [%s]')", schema_name, schema_name)
+		case DriverPostgres:
+			statement = fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s"`, schema_name)
+		default:
+			return nil
+		}
+
+		if err := gormDB.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// defaultSlowQueryThreshold is the duration above which a query is logged as slow when logging.slow_query_threshold is not set.
+const defaultSlowQueryThreshold = 200 * time.Millisecond
+
+// maxLoggedSQLLength bounds the statements written to the logs: a batched INSERT of GoMatrixRule rows runs to thousands of characters.
+const maxLoggedSQLLength = 2000
+
+// gormLogLevels maps the logging.gorm_level setting to the GORM log levels.
+var gormLogLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+// gormLogger routes the GORM logs into the structured logger of the application.
+// The statements are logged with their placeholders, never with their values.
+type gormLogger struct {
+	logger             *slog.Logger
+	level              logger.LogLevel
+	slowQueryThreshold time.Duration
+}
+
+// NewGormLogger creates the GORM logger writing to the given structured logger.
+// Failed queries are logged as errors, queries slower than logging.slow_query_threshold as warnings,
+// and every query at the info level when logging.gorm_level is info.
+//
+// Parameters:
+// - _logger: The structured logger of the application.
+// - config: The logging configuration.
+//
+// Returns:
+// - logger.Interface: The GORM logger.
+// - error: An error if logging.gorm_level is unknown.
+func NewGormLogger(_logger *slog.Logger, config LoggingConfig) (logger.Interface, error) {
+	level := logger.Warn
+	if config.GormLevel != "" {
+		var found bool
+		if level, found = gormLogLevels[strings.ToLower(config.GormLevel)]; !found {
+			return nil, fmt.Errorf("unknown logging.gorm_level %q: expected silent, error, warn or info", config.GormLevel)
+		}
+	}
+
+	slow_query_threshold := config.SlowQueryThreshold
+	if slow_query_threshold <= 0 {
+		slow_query_threshold = defaultSlowQueryThreshold
+	}
+
+	return &gormLogger{
+		logger:             _logger.With("component", "gorm"),
+		level:              level,
+		slowQueryThreshold: slow_query_threshold,
+	}, nil
+}
+
+func (p *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copy := *p
+	copy.level = level
+	return &copy
+}
+
+func (p *gormLogger) Info(ctx context.Context, message string, data ...interface{}) {
+	if p.level >= logger.Info {
+		p.logger.InfoContext(ctx, fmt.Sprintf(message, data...))
+	}
+}
+
+func (p *gormLogger) Warn(ctx context.Context, message string, data ...interface{}) {
+	if p.level >= logger.Warn {
+		p.logger.WarnContext(ctx, fmt.Sprintf(message, data...))
+	}
+}
+
+func (p *gormLogger) Error(ctx context.Context, message string, data ...interface{}) {
+	if p.level >= logger.Error {
+		p.logger.ErrorContext(ctx, fmt.Sprintf(message, data...))
+	}
+}
+
+// Trace logs a query once it has run, depending on its outcome and duration.
+func (p *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if p.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && p.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		p.logger.ErrorContext(ctx, "Query failed", "error", err, "sql", truncateSQL(sql), "rows", rows, "duration", elapsed)
+	case elapsed > p.slowQueryThreshold && p.level >= logger.Warn:
+		sql, rows := fc()
+		p.logger.WarnContext(ctx, "Slow query", "sql", truncateSQL(sql), "rows", rows, "duration", elapsed, "threshold", p.slowQueryThreshold)
+	case p.level >= logger.Info:
+		sql, rows := fc()
+		p.logger.InfoContext(ctx, "Query", "sql", truncateSQL(sql), "rows", rows, "duration", elapsed)
+	}
+}
+
+// ParamsFilter drops the values of the statements, which hold user emails and ids, before they are logged.
+func (p *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+// truncateSQL shortens a statement to maxLoggedSQLLength characters.
+func truncateSQL(sql string) string {
+	if len(sql) <= maxLoggedSQLLength {
+		return sql
+	}
+
+	return fmt.Sprintf("%s... (%d characters)", sql[:maxLoggedSQLLength], len(sql))
+}
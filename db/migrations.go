@@ -0,0 +1,31 @@
+package db
+
+import (
+	"gorm.io/gorm"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+)
+
+// MigrateInputTables creates (or updates) the tables the calculation reads from: the Core and Security schemas
+// with Employees, ManagerChains, DirectManagers, Users, Profiles and ProfileUsers.
+// These tables are owned by other systems in production, this is meant for local development and tests.
+//
+// Parameters:
+// - gormDB: the database connection.
+//
+// Returns:
+// - error: an error object if the migration fails, nil otherwise.
+func MigrateInputTables(gormDB *gorm.DB) error {
+	if err := CreateSchemas(gormDB, "Core", "Security"); err != nil {
+		return err
+	}
+
+	return gormDB.AutoMigrate(
+		&model.Employee{},
+		&model.ManagerChain{},
+		&model.DirectManager{},
+		&model.Profile{},
+		&model.User{},
+		&model.ProfileUser{},
+	)
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// transientSQLServerErrors are the SQL Server error numbers after which the failed operation may succeed if retried.
+var transientSQLServerErrors = map[int32]bool{
+	1205:  true, // Chosen as deadlock victim
+	1222:  true, // Lock request timeout
+	233:   true, // Connection closed by the server
+	64:    true, // Network name no longer available
+	10053: true, // Connection aborted
+	10054: true, // Connection reset by peer
+	10060: true, // Connection timed out
+	4060:  true, // Database unavailable, e.g. during a failover
+	40197: true, // Azure SQL: service error while processing the request
+	40501: true, // Azure SQL: service busy
+	40613: true, // Azure SQL: database unavailable
+	49918: true, // Azure SQL: not enough resources
+	49919: true, // Azure SQL: too many operations in progress
+	49920: true, // Azure SQL: too many operations in progress
+}
+
+// transientSQLStates are the PostgreSQL SQLSTATE codes and classes (two characters) of the transient errors.
+var transientSQLStates = []string{
+	"40001", // Serialization failure
+	"40P01", // Deadlock detected
+	"57P01", // Admin shutdown, e.g. during a failover
+	"08",    // Connection exceptions
+}
+
+// Primary result codes of the SQLite errors raised while another connection holds a lock.
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// IsTransientError reports whether the error is transient: a deadlock, a lock timeout, a dropped or reset connection,
+// or a database briefly unavailable during a failover, after which the failed operation may succeed if retried.
+// The cancellation and the deadline of a context are not transient.
+//
+// Parameters:
+// - err: The error of the failed operation, possibly wrapped.
+//
+// Returns:
+// - bool: true if the operation may be retried.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// SQL Server (mssql.Error)
+	var sql_server_err interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &sql_server_err) {
+		return transientSQLServerErrors[sql_server_err.SQLErrorNumber()]
+	}
+
+	// PostgreSQL (pgconn.PgError)
+	var postgres_err interface{ SQLState() string }
+	if errors.As(err, &postgres_err) {
+		for _, state := range transientSQLStates {
+			if strings.HasPrefix(postgres_err.SQLState(), state) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// SQLite (sqlite.Error), whose extended result codes carry the primary code in their low byte
+	var sqlite_err interface{ Code() int }
+	if errors.As(err, &sqlite_err) {
+		code := sqlite_err.Code() & 0xff
+		return code == sqliteBusy || code == sqliteLocked
+	}
+
+	// Connections dropped or reset under the driver
+	var net_err net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &net_err)
+}
+
+// connectSQLStates are the PostgreSQL SQLSTATE codes refusing the connections of a server that is not ready yet.
+var connectSQLStates = []string{
+	"57P03", // Cannot connect now, e.g. while the server starts up or recovers
+	"53300", // Too many connections
+}
+
+// IsTransientConnectError reports whether a failed connection attempt may succeed if retried: the transient errors
+// (see IsTransientError), the dial and login timeouts of the driver, and a PostgreSQL server not accepting
+// connections yet. Bad credentials, an unknown database or a malformed DSN are not transient.
+// The dial and login timeouts wrap context.DeadlineExceeded: check that the context of the connection is not done.
+//
+// Parameters:
+// - err: The error of the failed connection attempt, possibly wrapped.
+//
+// Returns:
+// - bool: true if the connection may be retried.
+func IsTransientConnectError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var net_err net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &net_err) && net_err.Timeout()) {
+		return true
+	}
+
+	var postgres_err interface{ SQLState() string }
+	if errors.As(err, &postgres_err) && slices.Contains(connectSQLStates, postgres_err.SQLState()) {
+		return true
+	}
+
+	return IsTransientError(err)
+}
+
+// Backoff returns the wait after the given failed attempt (1 for the first one): InitialBackoff, doubled after each
+// failed attempt and capped at MaxBackoff when set.
+//
+// Parameters:
+// - attempt: The number of the failed attempt, from 1.
+//
+// Returns:
+// - time.Duration: The wait before the next attempt.
+func (c RetryConfig) Backoff(attempt int) time.Duration {
+	backoff := c.InitialBackoff
+	for i := 1; i < attempt && (c.MaxBackoff <= 0 || backoff < c.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if c.MaxBackoff > 0 && backoff > c.MaxBackoff {
+		backoff = c.MaxBackoff
+	}
+
+	return backoff
+}
+
+// Retry runs the operation until it succeeds, fails with an error that is not retryable, or MaxAttempts attempts
+// failed, waiting Backoff between two attempts. The waits stop when the context is cancelled.
+//
+// Parameters:
+// - ctx: The context of the operation, whose cancellation stops the retries.
+// - policy: The number of attempts and the backoff between them, a single attempt when MaxAttempts is not positive.
+// - retryable: Reports whether an error may be retried, typically IsTransientError.
+// - on_retry: Called before each wait with the failed attempt, the wait and the error, nil to ignore.
+// - operation: The operation, called with ctx.
+//
+// Returns:
+// - error: nil once an attempt succeeds, otherwise the error of the last attempt, joined with the error of the context
+// if it was cancelled while waiting.
+func Retry(ctx context.Context, policy RetryConfig, retryable func(err error) bool, on_retry func(attempt int, backoff time.Duration, err error), operation func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := operation(ctx)
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		backoff := policy.Backoff(attempt)
+		if on_retry != nil {
+			on_retry(attempt, backoff, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		}
+	}
+}
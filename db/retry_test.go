@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+)
+
+// sqlStateError stands for the pgconn.PgError of the PostgreSQL driver.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "ERROR: SQLSTATE " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+// sqliteError stands for the sqlite.Error of the SQLite driver.
+type sqliteError int
+
+func (e sqliteError) Error() string { return fmt.Sprintf("sqlite error %d", int(e)) }
+func (e sqliteError) Code() int     { return int(e) }
+
+func TestIsTransientError(t *testing.T) {
+	for _, test := range []struct {
+		err       error
+		transient bool
+	}{
+		{mssql.Error{Number: 1205}, true}, // Deadlock victim
+		{fmt.Errorf("error writing rules: %w", mssql.Error{Number: 1205}), true},
+		{mssql.Error{Number: 40613}, true}, // Azure SQL database unavailable
+		{mssql.Error{Number: 2627}, false}, // Primary key violation
+		{mssql.Error{Number: 229}, false},  // Permission denied
+		{sqlStateError("40P01"), true},     // Deadlock detected
+		{sqlStateError("08006"), true},     // Connection failure
+		{sqlStateError("23505"), false},    // Unique violation
+		{sqliteError(5), true},             // SQLITE_BUSY
+		{sqliteError(5 | 2<<8), true},      // SQLITE_BUSY_SNAPSHOT
+		{sqliteError(19), false},           // SQLITE_CONSTRAINT
+		{driver.ErrBadConn, true},
+		{io.ErrUnexpectedEOF, true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{errors.New("record not found"), false},
+		{context.Canceled, false},
+		{fmt.Errorf("%w: %w", driver.ErrBadConn, context.DeadlineExceeded), false},
+		{nil, false},
+	} {
+		assert.Equal(t, test.transient, IsTransientError(test.err), "%v", test.err)
+	}
+}
+
+func TestIsTransientConnectError(t *testing.T) {
+	dial_timeout := &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("i/o timeout: %w", context.DeadlineExceeded)}
+
+	for _, test := range []struct {
+		err       error
+		transient bool
+	}{
+		{fmt.Errorf("unable to open tcp connection with host 'sql:1433': %w", dial_timeout), true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{fmt.Errorf("failed to connect: timeout: %w", context.DeadlineExceeded), true}, // Login timeout
+		{mssql.Error{Number: 4060}, true},                                              // Database unavailable
+		{sqlStateError("57P03"), true},                                                 // Cannot connect now
+		{mssql.Error{Number: 18456}, false},                                            // Login failed
+		{sqlStateError("28P01"), false},                                                // Invalid password
+		{sqlStateError("3D000"), false},                                                // Unknown database
+		{errors.New("invalid connection string"), false},
+		{sqliteError(14), false}, // SQLITE_CANTOPEN
+		{context.Canceled, false},
+		{nil, false},
+	} {
+		assert.Equal(t, test.transient, IsTransientConnectError(test.err), "%v", test.err)
+	}
+}
+
+func TestRetryConfigBackoff(t *testing.T) {
+	policy := RetryConfig{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	var backoffs []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		backoffs = append(backoffs, policy.Backoff(attempt))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, backoffs)
+
+	assert.Equal(t, RetryConfig{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Minute}, RetryConfig{MaxAttempts: 3}.WithDefaults(5, time.Second, time.Minute))
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	failing := func(errs ...error) (func(ctx context.Context) error, *int) {
+		attempts := 0
+		return func(ctx context.Context) error {
+			attempts++
+			if attempts <= len(errs) {
+				return errs[attempts-1]
+			}
+			return nil
+		}, &attempts
+	}
+
+	// Succeeds once the transient errors are over
+	operation, attempts := failing(driver.ErrBadConn, driver.ErrBadConn)
+	var retried []int
+	err := Retry(context.Background(), policy, IsTransientError, func(attempt int, backoff time.Duration, err error) {
+		retried = append(retried, attempt)
+	}, operation)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *attempts)
+	assert.Equal(t, []int{1, 2}, retried)
+
+	// Gives up after MaxAttempts
+	operation, attempts = failing(driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn)
+	assert.ErrorIs(t, Retry(context.Background(), policy, IsTransientError, nil, operation), driver.ErrBadConn)
+	assert.Equal(t, 3, *attempts)
+
+	// Does not retry other errors
+	not_found := errors.New("record not found")
+	operation, attempts = failing(not_found)
+	assert.ErrorIs(t, Retry(context.Background(), policy, IsTransientError, nil, operation), not_found)
+	assert.Equal(t, 1, *attempts)
+
+	// Stops waiting once the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	operation, attempts = failing(driver.ErrBadConn)
+	err = Retry(ctx, RetryConfig{MaxAttempts: 3, InitialBackoff: time.Hour}, IsTransientError, nil, operation)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, *attempts)
+}
@@ -0,0 +1,289 @@
+// Package fixture defines a YAML/JSON format describing an org chart, its users and their profiles,
+// and loads it into the in-memory repositories.
+//
+// Example:
+//
+//	profiles:
+//	  - id: PRFEMPLOYEE000000001
+//	    type: EMPLOYEE
+//	    level: 0
+//	    access_level_read: 1
+//	  - id: PRFMANAGER0000000001
+//	    type: MANAGER
+//	    level: 1
+//	    access_level_read: 7
+//	employees:
+//	  - id: 1
+//	  - id: 2
+//	    manager: 1
+//	users:
+//	  - id: USR00000000000000001
+//	    employee: 1
+//	    profiles: [PRFEMPLOYEE000000001, PRFMANAGER0000000001]
+//	  - id: USR00000000000000002
+//	    employee: 2
+//	    profiles: [PRFEMPLOYEE000000001]
+//
+// The manager chains are derived from the employees' direct managers, unless they are listed
+// explicitly under manager_chains (to reproduce production data as is, cycles and all).
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	helpers "github.com/nuno-bastos/gin-gonic-wire-api/service/helpers"
+)
+
+// Fixture describes the calculation inputs: profiles, employees (the org chart) and users.
+type Fixture struct {
+	Profiles      []Profile      `yaml:"profiles" json:"profiles"`
+	Employees     []Employee     `yaml:"employees" json:"employees"`
+	Users         []User         `yaml:"users" json:"users"`
+	ManagerChains []ManagerChain `yaml:"manager_chains,omitempty" json:"manager_chains,omitempty"`
+}
+
+// Profile describes a Security.Profiles row.
+type Profile struct {
+	Id              string `yaml:"id" json:"id"`
+	Type            string `yaml:"type" json:"type"` // EMPLOYEE, MANAGER, ADMIN or a raw ProfileTypeId
+	Name            string `yaml:"name,omitempty" json:"name,omitempty"`
+	Level           uint   `yaml:"level" json:"level"`
+	Division        uint   `yaml:"division,omitempty" json:"division,omitempty"`
+	AccessLevelRead uint   `yaml:"access_level_read" json:"access_level_read"`
+}
+
+// Employee describes a Core.Employees row and its direct manager.
+type Employee struct {
+	Id        uint   `yaml:"id" json:"id"`
+	Number    string `yaml:"number,omitempty" json:"number,omitempty"`
+	Name      string `yaml:"name,omitempty" json:"name,omitempty"`
+	Manager   uint   `yaml:"manager,omitempty" json:"manager,omitempty"`       // Direct manager, 0 for none
+	IsManager *bool  `yaml:"is_manager,omitempty" json:"is_manager,omitempty"` // Defaults to having direct reports
+	Inactive  bool   `yaml:"inactive,omitempty" json:"inactive,omitempty"`
+}
+
+// User describes a Security.Users row and its associated profiles.
+type User struct {
+	Id       string   `yaml:"id" json:"id"`
+	Email    string   `yaml:"email,omitempty" json:"email,omitempty"`
+	Employee uint     `yaml:"employee,omitempty" json:"employee,omitempty"` // 0 for users who are not employees
+	Profiles []string `yaml:"profiles" json:"profiles"`
+}
+
+// ManagerChain describes a Core.ManagerChains row.
+type ManagerChain struct {
+	Manager  int  `yaml:"manager" json:"manager"`
+	Employee int  `yaml:"employee" json:"employee"`
+	Level    int  `yaml:"level" json:"level"`
+	Inactive bool `yaml:"inactive,omitempty" json:"inactive,omitempty"`
+}
+
+// Load reads a YAML or JSON fixture file.
+//
+// Parameters:
+// - path: The path of the fixture file.
+//
+// Returns:
+// - *Fixture: The fixture.
+// - error: An error if the file cannot be read or parsed.
+func Load(path string) (*Fixture, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture, err := Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return fixture, nil
+}
+
+// Parse parses a YAML or JSON fixture. Unknown fields are rejected.
+//
+// Parameters:
+// - content: The YAML or JSON content.
+//
+// Returns:
+// - *Fixture: The fixture.
+// - error: An error if the content cannot be parsed.
+func Parse(content []byte) (*Fixture, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var fixture Fixture
+	if err := decoder.Decode(&fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture: %w", err)
+	}
+
+	return &fixture, nil
+}
+
+// Save writes the fixture to a file, as JSON if its extension is .json and as YAML otherwise.
+//
+// Parameters:
+// - path: The path of the fixture file.
+// - fixture: The fixture.
+//
+// Returns:
+// - error: An error if the fixture cannot be encoded or written.
+func Save(path string, fixture *Fixture) error {
+	var content bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		encoder := json.NewEncoder(&content)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(fixture); err != nil {
+			return err
+		}
+	} else {
+		encoder := yaml.NewEncoder(&content)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(fixture); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, content.Bytes(), 0o644)
+}
+
+// Data converts the fixture into the rows of an in-memory store.
+//
+// Returns:
+// - memory.Data: The employees, direct managers, manager chains and users with their profiles.
+// - error: An error if the fixture references unknown profiles or employees, or if the org chart has a cycle.
+func (f *Fixture) Data() (memory.Data, error) {
+	var data memory.Data
+
+	profiles := make(map[string]*model.Profile, len(f.Profiles))
+	for _, profile := range f.Profiles {
+		if _, found := profiles[profile.Id]; found {
+			return data, fmt.Errorf("duplicate profile %s", profile.Id)
+		}
+		profiles[profile.Id] = &model.Profile{
+			Id:              profile.Id,
+			ProfileTypeId:   profileTypeId(profile.Type),
+			Name:            profile.Name,
+			Level:           profile.Level,
+			Division:        profile.Division,
+			AccessLevelRead: profile.AccessLevelRead,
+		}
+	}
+
+	employee_ids := make(map[uint]bool, len(f.Employees))
+	for _, employee := range f.Employees {
+		if employee_ids[employee.Id] || employee.Id == 0 {
+			return data, fmt.Errorf("invalid or duplicate employee id %d", employee.Id)
+		}
+		employee_ids[employee.Id] = true
+	}
+
+	has_reports := make(map[uint]bool)
+	for _, employee := range f.Employees {
+		if employee.Manager == 0 {
+			continue
+		}
+		if !employee_ids[employee.Manager] {
+			return data, fmt.Errorf("employee %d references unknown manager %d", employee.Id, employee.Manager)
+		}
+
+		has_reports[employee.Manager] = true
+		data.DirectManagers = append(data.DirectManagers, model.DirectManager{
+			ManagerId:      int(employee.Manager),
+			EmployeeId:     int(employee.Id),
+			EmployeeStatus: !employee.Inactive,
+		})
+	}
+
+	for _, employee := range f.Employees {
+		is_manager := has_reports[employee.Id]
+		if employee.IsManager != nil {
+			is_manager = *employee.IsManager
+		}
+
+		data.Employees = append(data.Employees, model.Employee{
+			Id:             employee.Id,
+			EmployeeNumber: employee.Number,
+			FullName:       employee.Name,
+			IsManager:      is_manager,
+		})
+	}
+	sort.Slice(data.Employees, func(i, j int) bool { return data.Employees[i].Id < data.Employees[j].Id })
+
+	if f.ManagerChains != nil {
+		for _, manager_chain := range f.ManagerChains {
+			data.ManagerChains = append(data.ManagerChains, model.ManagerChain{
+				ManagerId:      manager_chain.Manager,
+				EmployeeId:     manager_chain.Employee,
+				Level:          manager_chain.Level,
+				EmployeeStatus: !manager_chain.Inactive,
+			})
+		}
+	} else {
+		manager_chains, err := helpers.BuildManagerChainClosure(data.DirectManagers)
+		if err != nil {
+			return data, err
+		}
+		data.ManagerChains = manager_chains
+	}
+
+	// Users may reference employees missing from the org chart: this is a data quality issue, not a fixture error
+	for _, user := range f.Users {
+		model_user := &model.User{
+			Id:         user.Id,
+			Email:      user.Email,
+			EmployeeId: user.Employee,
+		}
+
+		for _, profile_id := range user.Profiles {
+			profile, found := profiles[profile_id]
+			if !found {
+				return data, fmt.Errorf("user %s references unknown profile %s", user.Id, profile_id)
+			}
+			model_user.Profiles = append(model_user.Profiles, profile)
+		}
+
+		data.Users = append(data.Users, model_user)
+	}
+
+	return data, nil
+}
+
+// Store creates an in-memory store holding the fixture data.
+//
+// Returns:
+// - *memory.Store: The store.
+// - error: An error if the fixture is invalid, see Data.
+func (f *Fixture) Store() (*memory.Store, error) {
+	data, err := f.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	return memory.NewStore(data), nil
+}
+
+// profileTypeId resolves a profile type name to its ProfileTypeId. Other values are used as is.
+func profileTypeId(profile_type string) string {
+	switch profile_type {
+	case "EMPLOYEE":
+		return service.ProfileTypeId.EMPLOYEE
+	case "MANAGER":
+		return service.ProfileTypeId.MANAGER
+	case "ADMIN":
+		return service.ProfileTypeId.ADMIN
+	default:
+		return profile_type
+	}
+}
@@ -0,0 +1,370 @@
+// Package generator generates synthetic organizations (employees, manager chains, profiles and users)
+// for benchmarks and demos. The same options and seed always generate the same organization.
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/fixture"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	helpers "github.com/nuno-bastos/gin-gonic-wire-api/service/helpers"
+)
+
+// Options defines the shape of the generated organization.
+type Options struct {
+	Employees int // Number of employees
+	MaxDepth  int // Maximum number of management levels below the root employee, 0 for unlimited
+	MinSpan   int // Minimum number of direct reports per manager
+	MaxSpan   int // Maximum number of direct reports per manager
+
+	ManagerLevels int // Number of MANAGER profile levels: the root holds the highest, the deepest managers level 1
+	Divisions     int // Number of divisions, each branch below the root belongs to one of them
+
+	InactiveRatio            float64 // Share of inactive employees
+	ExtraManagerProfileRatio float64 // Share of managers also holding the MANAGER profile one level below their own
+	NonEmployeeUserRatio     float64 // Users who are not employees, relative to the number of employees
+
+	ProfileTypes service.ProfileTypes // IDs of the EMPLOYEE and MANAGER profile types, service.ProfileTypeId when unset
+
+	Seed int64
+}
+
+// DefaultOptions returns the options of a mid-sized organization of 1000 employees.
+func DefaultOptions() Options {
+	return Options{
+		Employees:                1000,
+		MinSpan:                  2,
+		MaxSpan:                  8,
+		ManagerLevels:            5,
+		Divisions:                1,
+		InactiveRatio:            0.05,
+		ExtraManagerProfileRatio: 0.1,
+		NonEmployeeUserRatio:     0.01,
+		ProfileTypes:             service.ProfileTypeId,
+		Seed:                     1,
+	}
+}
+
+// Organization holds the rows of a generated organization.
+type Organization struct {
+	Employees      []model.Employee
+	DirectManagers []model.DirectManager
+	ManagerChains  []model.ManagerChain
+	Profiles       []model.Profile
+	Users          []model.User // Profiles are given by ProfileUsers
+	ProfileUsers   []model.ProfileUser
+
+	profileTypes service.ProfileTypes
+}
+
+// Generate generates an organization: a tree of employees placed breadth-first under the root employee,
+// each manager getting a random span of control, one user per employee holding the EMPLOYEE profile, and the
+// MANAGER profile of their division and level for every employee with reports.
+//
+// Parameters:
+// - options: The shape of the organization.
+//
+// Returns:
+// - *Organization: The generated organization.
+// - error: An error if the options are invalid.
+func Generate(options Options) (*Organization, error) {
+	if err := validate(options); err != nil {
+		return nil, err
+	}
+
+	random := rand.New(rand.NewSource(options.Seed))
+	organization := &Organization{profileTypes: options.ProfileTypes}
+	if organization.profileTypes == (service.ProfileTypes{}) {
+		organization.profileTypes = service.ProfileTypeId
+	}
+
+	// Org chart, indexed by employee id (1-based)
+	employees := options.Employees
+	depth := make([]int, employees+1)
+	division := make([]int, employees+1)
+	has_reports := make([]bool, employees+1)
+	division[1] = 1
+
+	place := func(manager_id, employee_id int) {
+		depth[employee_id] = depth[manager_id] + 1
+		division[employee_id] = division[manager_id]
+		if manager_id == 1 {
+			division[employee_id] = (employee_id-2)%options.Divisions + 1
+		}
+		has_reports[manager_id] = true
+
+		organization.DirectManagers = append(organization.DirectManagers, model.DirectManager{
+			ManagerId:      manager_id,
+			EmployeeId:     employee_id,
+			EmployeeStatus: random.Float64() >= options.InactiveRatio,
+		})
+	}
+	can_manage := func(employee_id int) bool {
+		return options.MaxDepth == 0 || depth[employee_id] < options.MaxDepth
+	}
+
+	managers := []int{1}
+	next_id := 2
+	for next_id <= employees {
+		if len(managers) == 0 {
+			// Every manager got their span but the maximum depth leaves employees to place:
+			// give them to random employees who can still manage
+			manager_id := 1 + random.Intn(next_id-1)
+			if can_manage(manager_id) {
+				place(manager_id, next_id)
+				next_id++
+			}
+			continue
+		}
+
+		manager_id := managers[0]
+		managers = managers[1:]
+
+		span := options.MinSpan + random.Intn(options.MaxSpan-options.MinSpan+1)
+		for i := 0; i < span && next_id <= employees; i++ {
+			place(manager_id, next_id)
+			if can_manage(next_id) {
+				managers = append(managers, next_id)
+			}
+			next_id++
+		}
+	}
+
+	manager_chains, err := helpers.BuildManagerChainClosure(organization.DirectManagers)
+	if err != nil {
+		return nil, err
+	}
+	organization.ManagerChains = manager_chains
+
+	// Profiles: the EMPLOYEE profile, then one MANAGER profile per division and level
+	employee_profile := model.Profile{
+		Id:              profileId(1),
+		ProfileTypeId:   organization.profileTypes.EMPLOYEE,
+		Name:            "Employee",
+		Description:     "Generated employee profile",
+		AccessLevelRead: 1,
+	}
+	organization.Profiles = append(organization.Profiles, employee_profile)
+
+	access_levels := []uint{1, 3, 5, 7}
+	manager_profile_ids := make(map[[2]int]string)
+	for division_number := 1; division_number <= options.Divisions; division_number++ {
+		for level := 1; level <= options.ManagerLevels; level++ {
+			profile := model.Profile{
+				Id:              profileId(len(organization.Profiles) + 1),
+				ProfileTypeId:   organization.profileTypes.MANAGER,
+				Name:            fmt.Sprintf("Manager D%d L%d", division_number, level),
+				Description:     "Generated manager profile",
+				Level:           uint(level),
+				Division:        uint(division_number),
+				AccessLevelRead: access_levels[random.Intn(len(access_levels))],
+			}
+			manager_profile_ids[[2]int{division_number, level}] = profile.Id
+			organization.Profiles = append(organization.Profiles, profile)
+		}
+	}
+
+	// Employees and their users
+	created_at := model.Timestamp{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	for employee_id := 1; employee_id <= employees; employee_id++ {
+		organization.Employees = append(organization.Employees, model.Employee{
+			Id:             uint(employee_id),
+			EmployeeNumber: fmt.Sprintf("E%07d", employee_id),
+			FullName:       fmt.Sprintf("Employee %d", employee_id),
+			IsManager:      has_reports[employee_id],
+		})
+
+		user := newUser(employee_id, uint(employee_id), created_at)
+		organization.Users = append(organization.Users, user)
+		organization.ProfileUsers = append(organization.ProfileUsers, model.ProfileUser{UserID: user.Id, ProfileID: employee_profile.Id})
+
+		if has_reports[employee_id] {
+			level := options.ManagerLevels - depth[employee_id]
+			if level < 1 {
+				level = 1
+			}
+			organization.ProfileUsers = append(organization.ProfileUsers, model.ProfileUser{
+				UserID:    user.Id,
+				ProfileID: manager_profile_ids[[2]int{division[employee_id], level}],
+			})
+
+			if level > 1 && random.Float64() < options.ExtraManagerProfileRatio {
+				organization.ProfileUsers = append(organization.ProfileUsers, model.ProfileUser{
+					UserID:    user.Id,
+					ProfileID: manager_profile_ids[[2]int{division[employee_id], level - 1}],
+				})
+			}
+		}
+	}
+
+	// Users who are not employees, e.g. service accounts, holding a random MANAGER profile
+	non_employee_users := int(options.NonEmployeeUserRatio * float64(employees))
+	for i := 1; i <= non_employee_users; i++ {
+		user := newUser(employees+i, 0, created_at)
+		organization.Users = append(organization.Users, user)
+		organization.ProfileUsers = append(organization.ProfileUsers, model.ProfileUser{
+			UserID:    user.Id,
+			ProfileID: organization.Profiles[1+random.Intn(len(organization.Profiles)-1)].Id,
+		})
+	}
+
+	return organization, nil
+}
+
+// UsersWithProfiles returns every user, including the users who are not employees, with their associated profiles.
+func (o *Organization) UsersWithProfiles() []*model.User {
+	profiles := make(map[string]*model.Profile, len(o.Profiles))
+	for i := range o.Profiles {
+		profiles[o.Profiles[i].Id] = &o.Profiles[i]
+	}
+
+	users := make([]*model.User, len(o.Users))
+	users_by_id := make(map[string]*model.User, len(o.Users))
+	for i := range o.Users {
+		user := o.Users[i]
+		users[i] = &user
+		users_by_id[user.Id] = &user
+	}
+	for _, profile_user := range o.ProfileUsers {
+		user := users_by_id[profile_user.UserID]
+		user.Profiles = append(user.Profiles, profiles[profile_user.ProfileID])
+	}
+
+	return users
+}
+
+// Data returns the organization as the rows of an in-memory store.
+func (o *Organization) Data() memory.Data {
+	return memory.Data{
+		Employees:      o.Employees,
+		ManagerChains:  o.ManagerChains,
+		DirectManagers: o.DirectManagers,
+		Users:          o.UsersWithProfiles(),
+	}
+}
+
+// Fixture returns the organization as a fixture, whose manager chains are derived from the direct managers.
+func (o *Organization) Fixture() *fixture.Fixture {
+	var organization_fixture fixture.Fixture
+
+	for _, profile := range o.Profiles {
+		profile_type := "EMPLOYEE"
+		if profile.ProfileTypeId == o.profileTypes.MANAGER {
+			profile_type = "MANAGER"
+		}
+		organization_fixture.Profiles = append(organization_fixture.Profiles, fixture.Profile{
+			Id:              profile.Id,
+			Type:            profile_type,
+			Name:            profile.Name,
+			Level:           profile.Level,
+			Division:        profile.Division,
+			AccessLevelRead: profile.AccessLevelRead,
+		})
+	}
+
+	direct_managers := make(map[int]model.DirectManager, len(o.DirectManagers))
+	for _, direct_manager := range o.DirectManagers {
+		direct_managers[direct_manager.EmployeeId] = direct_manager
+	}
+	for _, employee := range o.Employees {
+		direct_manager := direct_managers[int(employee.Id)]
+		organization_fixture.Employees = append(organization_fixture.Employees, fixture.Employee{
+			Id:       employee.Id,
+			Number:   employee.EmployeeNumber,
+			Name:     employee.FullName,
+			Manager:  uint(direct_manager.ManagerId),
+			Inactive: direct_manager.ManagerId != 0 && !direct_manager.EmployeeStatus,
+		})
+	}
+
+	for _, user := range o.UsersWithProfiles() {
+		fixture_user := fixture.User{Id: user.Id, Email: user.Email, Employee: user.EmployeeId, Profiles: []string{}}
+		for _, profile := range user.Profiles {
+			fixture_user.Profiles = append(fixture_user.Profiles, profile.Id)
+		}
+		organization_fixture.Users = append(organization_fixture.Users, fixture_user)
+	}
+
+	return &organization_fixture
+}
+
+// writeBatchSize keeps the widest table (Users, 8 columns) below the SQL Server limit of 2100 parameters per statement.
+const writeBatchSize = 200
+
+// Write replaces the content of the input tables (Employees, ManagerChains, DirectManagers, Profiles, Users and
+// ProfileUsers) of the database with the organization, creating the tables if needed, within a single transaction.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - gormDB: the database connection.
+//
+// Returns:
+// - error: an error object if the operation fails, nil otherwise.
+func (o *Organization) Write(ctx context.Context, gormDB *gorm.DB) error {
+	if err := db.MigrateInputTables(gormDB.WithContext(ctx)); err != nil {
+		return err
+	}
+
+	return gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Delete existing records, referencing tables first
+		for _, table := range []interface{}{&model.ProfileUser{}, &model.User{}, &model.Profile{}, &model.DirectManager{}, &model.ManagerChain{}, &model.Employee{}} {
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, rows := range []interface{}{o.Employees, o.ManagerChains, o.DirectManagers, o.Profiles, o.Users, o.ProfileUsers} {
+			if err := tx.Omit(clause.Associations).CreateInBatches(rows, writeBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func validate(options Options) error {
+	switch {
+	case options.Employees < 1:
+		return fmt.Errorf("employees must be at least 1, got %d", options.Employees)
+	case options.MaxDepth < 0:
+		return fmt.Errorf("max depth must not be negative, got %d", options.MaxDepth)
+	case options.MinSpan < 1 || options.MaxSpan < options.MinSpan:
+		return fmt.Errorf("span of control must satisfy 1 <= min (%d) <= max (%d)", options.MinSpan, options.MaxSpan)
+	case options.ManagerLevels < 1:
+		return fmt.Errorf("manager levels must be at least 1, got %d", options.ManagerLevels)
+	case options.Divisions < 1:
+		return fmt.Errorf("divisions must be at least 1, got %d", options.Divisions)
+	case options.InactiveRatio < 0 || options.InactiveRatio > 1,
+		options.ExtraManagerProfileRatio < 0 || options.ExtraManagerProfileRatio > 1,
+		options.NonEmployeeUserRatio < 0:
+		return fmt.Errorf("ratios must be between 0 and 1")
+	}
+
+	return nil
+}
+
+func newUser(number int, employee_id uint, created_at model.Timestamp) model.User {
+	return model.User{
+		Id:          fmt.Sprintf("USR%017d", number),
+		Email:       fmt.Sprintf("user%d@example.com", number),
+		FirstName:   "User",
+		LastName:    fmt.Sprint(number),
+		CreatedAt:   created_at,
+		LastLoginAt: created_at,
+		EmployeeId:  employee_id,
+	}
+}
+
+func profileId(number int) string {
+	return fmt.Sprintf("PRF%017d", number)
+}
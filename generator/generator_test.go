@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/fixture"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	options := DefaultOptions()
+	options.Employees = 500
+
+	first, err := Generate(options)
+	require.NoError(t, err)
+	second, err := Generate(options)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	options.Seed++
+	third, err := Generate(options)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.DirectManagers, third.DirectManagers)
+}
+
+func TestGenerateRespectsOptions(t *testing.T) {
+	options := DefaultOptions()
+	options.Employees = 2000
+	options.MaxDepth = 3
+	options.MinSpan, options.MaxSpan = 3, 5
+	options.Divisions = 4
+	options.InactiveRatio = 0.2
+	options.NonEmployeeUserRatio = 0.05
+
+	organization, err := Generate(options)
+	require.NoError(t, err)
+
+	assert.Len(t, organization.Employees, 2000)
+	assert.Len(t, organization.DirectManagers, 1999)
+	assert.Len(t, organization.Users, 2100)
+	assert.Len(t, organization.Profiles, 1+4*options.ManagerLevels)
+
+	inactive := 0
+	for _, direct_manager := range organization.DirectManagers {
+		if !direct_manager.EmployeeStatus {
+			inactive++
+		}
+	}
+	assert.InDelta(t, 0.2, float64(inactive)/1999, 0.05)
+
+	for _, manager_chain := range organization.ManagerChains {
+		assert.LessOrEqual(t, manager_chain.Level, options.MaxDepth)
+	}
+
+	divisions := make(map[uint]bool)
+	for _, profile := range organization.Profiles {
+		divisions[profile.Division] = true
+	}
+	assert.Len(t, divisions, 5) // The EMPLOYEE profile has no division
+
+	_, err = Generate(Options{Employees: 10, MinSpan: 3, MaxSpan: 2, ManagerLevels: 1, Divisions: 1})
+	assert.Error(t, err)
+}
+
+func TestFixtureRoundTrip(t *testing.T) {
+	options := DefaultOptions()
+	options.Employees = 300
+	options.Divisions = 2
+	organization, err := Generate(options)
+	require.NoError(t, err)
+
+	for _, name := range []string{"organization.yaml", "organization.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		require.NoError(t, fixture.Save(path, organization.Fixture()))
+
+		loaded, err := fixture.Load(path)
+		require.NoError(t, err)
+		data, err := loaded.Data()
+		require.NoError(t, err)
+
+		expected := organization.Data()
+		assert.Equal(t, expected.Employees, data.Employees, name)
+		assert.Equal(t, expected.DirectManagers, data.DirectManagers, name)
+		assert.ElementsMatch(t, expected.ManagerChains, data.ManagerChains, name)
+		require.Len(t, data.Users, len(expected.Users), name)
+		for i, user := range data.Users {
+			assert.Equal(t, expected.Users[i].EmployeeId, user.EmployeeId, name)
+			assert.Equal(t, profileIds(expected.Users[i]), profileIds(user), name)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dialector, err := db.OpenDialector(db.DriverSQLite, "file:generator_test?mode=memory&cache=shared")
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		NamingStrategy: db.NewNamingStrategy(dialector.Name()),
+	})
+	require.NoError(t, err)
+
+	options := DefaultOptions()
+	options.Employees = 200
+	organization, err := Generate(options)
+	require.NoError(t, err)
+
+	// Writing twice replaces the previous organization
+	require.NoError(t, organization.Write(context.Background(), gormDB))
+	require.NoError(t, organization.Write(context.Background(), gormDB))
+
+	for table, expected := range map[interface{}]int{
+		&model.Employee{}:      len(organization.Employees),
+		&model.ManagerChain{}:  len(organization.ManagerChains),
+		&model.DirectManager{}: len(organization.DirectManagers),
+		&model.Profile{}:       len(organization.Profiles),
+		&model.User{}:          len(organization.Users),
+		&model.ProfileUser{}:   len(organization.ProfileUsers),
+	} {
+		var count int64
+		require.NoError(t, gormDB.Model(table).Count(&count).Error)
+		assert.Equal(t, int64(expected), count, "%T", table)
+	}
+}
+
+func profileIds(user *model.User) []string {
+	var profile_ids []string
+	for _, profile := range user.Profiles {
+		profile_ids = append(profile_ids, profile.Id)
+	}
+
+	return profile_ids
+}
+
+func TestGenerateUsesTheProfileTypes(t *testing.T) {
+	options := DefaultOptions()
+	options.Employees = 100
+	options.ProfileTypes = service.ProfileTypes{MANAGER: "PFTMANAGER", EMPLOYEE: "PFTEMPLOYEE"}
+
+	organization, err := Generate(options)
+	require.NoError(t, err)
+
+	profile_types := make(map[string]string)
+	for _, profile := range organization.Profiles {
+		assert.Contains(t, []string{"PFTMANAGER", "PFTEMPLOYEE"}, profile.ProfileTypeId)
+		profile_types[profile.Id] = profile.ProfileTypeId
+	}
+	for _, profile := range organization.Fixture().Profiles {
+		assert.Equal(t, map[string]string{"PFTMANAGER": "MANAGER", "PFTEMPLOYEE": "EMPLOYEE"}[profile_types[profile.Id]], profile.Type)
+	}
+}
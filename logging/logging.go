@@ -0,0 +1,122 @@
+// Package logging sets up the structured logger of the application and carries the correlation IDs
+// (HTTP request, calculation run, trace) that every log line is annotated with.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// Formats accepted by the logging.format setting.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type contextKey int
+
+const (
+	requestIdKey contextKey = iota
+	runIdKey
+)
+
+// NewLogger creates the logger of the application, writing to stderr so that stdout stays free for the exports.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - *slog.Logger: The logger.
+// - error: An error if the level or the format is unknown.
+func NewLogger(config *db.Config) (*slog.Logger, error) {
+	return NewLoggerTo(os.Stderr, config)
+}
+
+// NewLoggerTo creates the logger of the application writing to the given output.
+// Every line logged with a context carries the request_id, run_id, trace_id and span_id found in it.
+//
+// Parameters:
+// - output: Where the log lines are written.
+// - config: The application configuration.
+//
+// Returns:
+// - *slog.Logger: The logger.
+// - error: An error if the level or the format is unknown.
+func NewLoggerTo(output io.Writer, config *db.Config) (*slog.Logger, error) {
+	var level slog.Level
+	if config.Logging.Level != "" {
+		if err := level.UnmarshalText([]byte(config.Logging.Level)); err != nil {
+			return nil, fmt.Errorf("unknown logging.level %q: expected debug, info, warn or error", config.Logging.Level)
+		}
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(config.Logging.Format) {
+	case "", FormatJSON:
+		handler = slog.NewJSONHandler(output, options)
+	case FormatText:
+		handler = slog.NewTextHandler(output, options)
+	default:
+		return nil, fmt.Errorf("unknown logging.format %q: expected %s or %s", config.Logging.Format, FormatJSON, FormatText)
+	}
+
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// WithRequestId returns a copy of the context carrying the ID of the HTTP request.
+func WithRequestId(ctx context.Context, request_id string) context.Context {
+	return context.WithValue(ctx, requestIdKey, request_id)
+}
+
+// RequestId returns the ID of the HTTP request carried by the context, "" if there is none.
+func RequestId(ctx context.Context) string {
+	request_id, _ := ctx.Value(requestIdKey).(string)
+	return request_id
+}
+
+// WithRunId returns a copy of the context carrying the ID of the calculation run.
+func WithRunId(ctx context.Context, run_id string) context.Context {
+	return context.WithValue(ctx, runIdKey, run_id)
+}
+
+// RunId returns the ID of the calculation run carried by the context, "" if there is none.
+func RunId(ctx context.Context) string {
+	run_id, _ := ctx.Value(runIdKey).(string)
+	return run_id
+}
+
+// contextHandler adds the correlation IDs of the context to the records of the wrapped handler.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (p *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if request_id := RequestId(ctx); request_id != "" {
+		record.AddAttrs(slog.String("request_id", request_id))
+	}
+	if run_id := RunId(ctx); run_id != "" {
+		record.AddAttrs(slog.String("run_id", run_id))
+	}
+	if span_context := trace.SpanContextFromContext(ctx); span_context.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span_context.TraceID().String()), slog.String("span_id", span_context.SpanID().String()))
+	}
+
+	return p.Handler.Handle(ctx, record)
+}
+
+func (p *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: p.Handler.WithAttrs(attrs)}
+}
+
+func (p *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: p.Handler.WithGroup(name)}
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+func TestLinesCarryTheCorrelationIdsOfTheContext(t *testing.T) {
+	var output bytes.Buffer
+	logger, err := NewLoggerTo(&output, &db.Config{})
+	require.NoError(t, err)
+
+	span_context := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9},
+		SpanID:     trace.SpanID{0x00, 0xf0},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), span_context)
+	ctx = WithRunId(WithRequestId(ctx, "request-1"), "run-1")
+	logger.With("component", "test").InfoContext(ctx, "Calculated", "rules", 3)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(output.Bytes(), &record))
+	assert.Equal(t, "Calculated", record["msg"])
+	assert.Equal(t, "test", record["component"])
+	assert.EqualValues(t, 3, record["rules"])
+	assert.Equal(t, "request-1", record["request_id"])
+	assert.Equal(t, "run-1", record["run_id"])
+	assert.Equal(t, span_context.TraceID().String(), record["trace_id"])
+	assert.Equal(t, span_context.SpanID().String(), record["span_id"])
+
+	// Without IDs in the context, none are logged
+	output.Reset()
+	logger.Info("Started")
+	assert.NotContains(t, output.String(), "_id")
+}
+
+func TestNewLoggerAppliesTheLevelAndFormat(t *testing.T) {
+	var output bytes.Buffer
+	logger, err := NewLoggerTo(&output, &db.Config{Logging: db.LoggingConfig{Level: "warn", Format: "text"}})
+	require.NoError(t, err)
+
+	logger.Info("Skipped")
+	assert.Empty(t, output.String())
+	logger.Warn("Logged")
+	assert.Contains(t, output.String(), "level=WARN msg=Logged")
+
+	_, err = NewLoggerTo(&output, &db.Config{Logging: db.LoggingConfig{Level: "verbose"}})
+	assert.ErrorContains(t, err, "verbose")
+	_, err = NewLoggerTo(&output, &db.Config{Logging: db.LoggingConfig{Format: "xml"}})
+	assert.ErrorContains(t, err, "xml")
+}
@@ -1,16 +1,11 @@
 package main
 
 import (
-	"log"
+	"os"
 
-	wire "github.com/nuno-bastos/gin-gonic-wire-api/wire_di"
+	"github.com/nuno-bastos/gin-gonic-wire-api/cli"
 )
 
 func main() {
-	server, diErr := wire.Inject()
-	if diErr != nil {
-		log.Fatal("Error - Cannot Start Server: ", diErr)
-	} else {
-		server.Start()
-	}
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
 }
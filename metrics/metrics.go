@@ -0,0 +1,157 @@
+// Package metrics defines the Prometheus metrics of the security matrix calculation and of the HTTP API.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Stages of the calculation timed by Metrics.StageDuration.
+const (
+	StageFindAll                              = "FindAll"                              // Loading the manager chains (or the direct managers they are derived from), and the employees when data quality is enforced
+	StageFetchEmployeesWithAssociatedProfiles = "FetchEmployeesWithAssociatedProfiles" // Loading the users with their profiles
+	StageCalculateRules                       = "CalculateRules"                       // Running the filters of one user's profiles
+	StageFlatten                              = "Flatten"                              // Condensing the rules of one user
+	StageRemoveRulesWithDefaultValues         = "RemoveRulesWithDefaultValues"         // Dropping the condensed rules without access of one user
+	StageWriteRules                           = "WriteRules"                           // Streaming the rules into the database and committing them
+)
+
+// Outcomes of a calculation counted by Metrics.Runs.
+const (
+	OutcomeSuccess     = "success"
+	OutcomeDataQuality = "data_quality" // Refused by the enforced data quality checks
+	OutcomeCancelled   = "cancelled"    // The context was cancelled or timed out
+	OutcomeError       = "error"
+)
+
+// Phases of the calculation retried after a transient database error, counted by Metrics.Retries.
+const (
+	PhaseRead  = "read"  // Loading the manager chains, the users and their profiles
+	PhaseWrite = "write" // Streaming the rules into the database and committing them
+)
+
+// Kinds of filter failures counted by Metrics.FilterFailures.
+const (
+	FailureError = "error" // The filter returned an error
+	FailurePanic = "panic" // The filter panicked
+)
+
+// Channels of the calculation pipeline reported by Metrics.ChannelDepth.
+const (
+	ChannelUsers = "users" // Users waiting for a worker
+	ChannelRules = "rules" // Condensed rules waiting to be written
+)
+
+// Metrics holds the collectors of the application, registered on their own registry.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	// Calculation
+	StageDuration  *prometheus.HistogramVec // by stage
+	RulesEmitted   *prometheus.CounterVec   // by origin_filter, before condensing
+	RulesWritten   prometheus.Counter
+	Runs           *prometheus.CounterVec // by outcome
+	Retries        *prometheus.CounterVec // by phase
+	FilterFailures *prometheus.CounterVec // by filter and kind
+	Workers        prometheus.Gauge
+	BusyWorkers    prometheus.Gauge
+	ChannelDepth   *prometheus.GaugeVec // by channel
+
+	// HTTP API
+	HTTPRequests        *prometheus.CounterVec   // by method, route and status
+	HTTPRequestDuration *prometheus.HistogramVec // by method, route and status
+	HTTPInFlight        prometheus.Gauge
+}
+
+// NewMetrics creates the metrics of the application on a new registry, along with the Go runtime and process collectors.
+//
+// Returns:
+// - *Metrics: The metrics.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+
+		StageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "security_matrix_stage_duration_seconds",
+			Help: "Duration of the stages of the security matrix calculation. The CalculateRules, Flatten and RemoveRulesWithDefaultValues stages are observed once per user.",
+			// From 10µs (one user's filters) to about 3 minutes (writing a large matrix)
+			Buckets: prometheus.ExponentialBuckets(0.00001, 4, 13),
+		}, []string{"stage"}),
+		RulesEmitted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "security_matrix_rules_emitted_total",
+			Help: "Allow or deny rules emitted by each filter, before they are condensed.",
+		}, []string{"origin_filter"}),
+		RulesWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "security_matrix_rules_written_total",
+			Help: "GoMatrixRule rows written by the successful calculations.",
+		}),
+		Runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "security_matrix_runs_total",
+			Help: "Security matrix calculations by outcome (success, data_quality, cancelled or error).",
+		}, []string{"outcome"}),
+		Retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "security_matrix_retries_total",
+			Help: "Phases of the security matrix calculation retried after a transient database error, by phase (read or write).",
+		}, []string{"phase"}),
+		FilterFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "security_matrix_filter_failures_total",
+			Help: "Filters that returned an error or panicked for a user, by filter and kind (error or panic). The filter is empty when the rules of the user failed outside of the filters.",
+		}, []string{"filter", "kind"}),
+		Workers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "security_matrix_workers",
+			Help: "Worker goroutines of the running calculation.",
+		}),
+		BusyWorkers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "security_matrix_busy_workers",
+			Help: "Worker goroutines currently processing a user, out of security_matrix_workers.",
+		}),
+		ChannelDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "security_matrix_channel_depth",
+			Help: "Items buffered in the channels of the calculation pipeline (users waiting for a worker, rules waiting to be written).",
+		}, []string{"channel"}),
+
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of the HTTP requests by method, route and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		HTTPInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests currently being served.",
+		}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.StageDuration,
+		m.RulesEmitted,
+		m.RulesWritten,
+		m.Runs,
+		m.Retries,
+		m.FilterFailures,
+		m.Workers,
+		m.BusyWorkers,
+		m.ChannelDepth,
+		m.HTTPRequests,
+		m.HTTPRequestDuration,
+		m.HTTPInFlight,
+	)
+
+	return m
+}
+
+// ObserveStage records the duration of a calculation stage started at the given time.
+//
+// Parameters:
+// - stage: The stage, one of the Stage constants.
+// - started_at: When the stage started.
+func (p *Metrics) ObserveStage(stage string, started_at time.Time) {
+	p.StageDuration.WithLabelValues(stage).Observe(time.Since(started_at).Seconds())
+}
@@ -0,0 +1,17 @@
+package model
+
+import "gorm.io/gorm/schema"
+
+// DirectManager represents a single direct manager -> employee edge of the org chart.
+// The full ManagerChains closure (every manager above an employee, with its level)
+// can be derived from these edges.
+type DirectManager struct {
+	Id             uint `gorm:"column:Id;primaryKey"`
+	ManagerId      int  `gorm:"column:ManagerId;not null"`
+	EmployeeId     int  `gorm:"column:EmployeeId;not null"`
+	EmployeeStatus bool `gorm:"column:EmployeeStatus;not null"`
+}
+
+func (DirectManager) TableName(namer schema.Namer) string {
+	return SchemaTableName(namer, "Core", "DirectManagers")
+}
@@ -1,5 +1,7 @@
 package model
 
+import "gorm.io/gorm/schema"
+
 type ManagerChain struct {
 	Id             uint `gorm:"column:Id;primaryKey"`
 	ManagerId      int  `gorm:"column:ManagerId;not null"`
@@ -8,6 +10,6 @@ type ManagerChain struct {
 	EmployeeStatus bool `gorm:"column:EmployeeStatus;not null"`
 }
 
-func (ManagerChain) TableName() string {
-	return "Core.ManagerChains"
+func (ManagerChain) TableName(namer schema.Namer) string {
+	return SchemaTableName(namer, "Core", "ManagerChains")
 }
@@ -1,10 +1,12 @@
 package model
 
+import "gorm.io/gorm/schema"
+
 type ProfileUser struct {
 	UserID    string `gorm:"column:UserId"`
 	ProfileID string `gorm:"column:ProfileId"`
 }
 
-func (ProfileUser) TableName() string {
-	return "Security.ProfileUsers"
+func (ProfileUser) TableName(namer schema.Namer) string {
+	return SchemaTableName(namer, "Security", "ProfileUsers")
 }
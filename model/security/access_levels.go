@@ -1,6 +1,9 @@
 package model_security
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
 type ReadAccessLevelsEnum uint32
 
@@ -14,3 +17,29 @@ const (
 	FULL_DENY              = uint32(math.MaxUint32) // uint32 var with all bits set to 1
 	DENY_ACCESS_LEVEL_READ = FULL_DENY - uint32(GenericFields)
 )
+
+// ReadAccessLevelBit is a single bit of All and the name of its decoded export column.
+type ReadAccessLevelBit struct {
+	Name  string
+	Level ReadAccessLevelsEnum
+}
+
+// ReadAccessLevelBits lists the bits of All, lowest first. Bits without a level of their own
+// are named after their position (e.g. "AccessBit0").
+func ReadAccessLevelBits() []ReadAccessLevelBit {
+	names := map[ReadAccessLevelsEnum]string{
+		GenericFields: "GenericFields",
+	}
+
+	var bits []ReadAccessLevelBit
+	for position := 0; ReadAccessLevelsEnum(1)<<position <= All; position++ {
+		level := ReadAccessLevelsEnum(1) << position
+		name, found := names[level]
+		if !found {
+			name = fmt.Sprintf("AccessBit%d", position)
+		}
+		bits = append(bits, ReadAccessLevelBit{Name: name, Level: level})
+	}
+
+	return bits
+}
@@ -0,0 +1,36 @@
+package model_security
+
+// DataQualityIssueType identifies the kind of inconsistency found between Users, Employees and Profiles.
+type DataQualityIssueType string
+
+const (
+	// A user references an EmployeeId that does not exist in Core.Employees
+	UnknownEmployee DataQualityIssueType = "UNKNOWN_EMPLOYEE"
+	// A user holds more than one EMPLOYEE-type profile
+	MultipleEmployeeProfiles DataQualityIssueType = "MULTIPLE_EMPLOYEE_PROFILES"
+	// An employee flagged IsManager has no user holding a MANAGER-type profile
+	ManagerFlagWithoutManagerProfile DataQualityIssueType = "MANAGER_FLAG_WITHOUT_MANAGER_PROFILE"
+	// A user holds a MANAGER-type profile but their employee is not flagged IsManager
+	ManagerProfileWithoutManagerFlag DataQualityIssueType = "MANAGER_PROFILE_WITHOUT_MANAGER_FLAG"
+	// An employee flagged IsManager has no reports in the manager chains
+	ManagerFlagWithoutReports DataQualityIssueType = "MANAGER_FLAG_WITHOUT_REPORTS"
+	// An employee has reports in the manager chains but is not flagged IsManager
+	ReportsWithoutManagerFlag DataQualityIssueType = "REPORTS_WITHOUT_MANAGER_FLAG"
+)
+
+type DataQualityIssue struct {
+	Type       DataQualityIssueType `json:"type"`
+	UserId     string               `json:"userId,omitempty"`
+	EmployeeId uint                 `json:"employeeId,omitempty"`
+	ProfileIds []string             `json:"profileIds,omitempty"`
+	Message    string               `json:"message"`
+}
+
+type DataQualityReport struct {
+	Issues []DataQualityIssue `json:"issues"`
+}
+
+// HasIssues reports whether any inconsistency was found.
+func (r DataQualityReport) HasIssues() bool {
+	return len(r.Issues) > 0
+}
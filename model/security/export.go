@@ -0,0 +1,47 @@
+package model_security
+
+import "fmt"
+
+// ExportFormat is the file format of a security matrix export.
+type ExportFormat string
+
+const (
+	ExportFormatCSV     ExportFormat = "csv"     // Comma-separated values with a header line
+	ExportFormatJSONL   ExportFormat = "jsonl"   // JSON Lines, one object per rule
+	ExportFormatParquet ExportFormat = "parquet" // Apache Parquet, columns typed like the GoMatrixRule table
+)
+
+// ExportFormats lists the supported export formats.
+var ExportFormats = []ExportFormat{ExportFormatCSV, ExportFormatJSONL, ExportFormatParquet}
+
+// ParseExportFormat returns the export format with the given name.
+func ParseExportFormat(name string) (ExportFormat, error) {
+	for _, format := range ExportFormats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown export format %q, expected one of %v", name, ExportFormats)
+}
+
+// ContentType returns the media type of the format.
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatCSV:
+		return "text/csv"
+	case ExportFormatJSONL:
+		return "application/jsonl"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// ExportOptions selects the format and the columns of a security matrix export.
+type ExportOptions struct {
+	Format ExportFormat
+	// Enrich adds the user email and the employee number of every rule.
+	Enrich bool
+	// DecodeAccess adds a boolean column per bit of AccessLevelRead, see ReadAccessLevelBits.
+	DecodeAccess bool
+}
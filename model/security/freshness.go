@@ -0,0 +1,83 @@
+package model_security
+
+import (
+	"time"
+
+	"github.com/nuno-bastos/gin-gonic-wire-api/model"
+)
+
+// Statuses of a GoMatrixCalculationRun.
+const (
+	RunStatusSucceeded   = "succeeded"   // The rules were committed
+	RunStatusInterrupted = "interrupted" // The calculation was cancelled, its rules rolled back
+)
+
+// GoMatrixCalculationRun records a calculation of the security matrix: written once its rules are committed,
+// or once it was interrupted (e.g. by a shutdown) and its rules rolled back.
+type GoMatrixCalculationRun struct {
+	Id         uint            `gorm:"column:Id;primaryKey;autoIncrement" json:"id"`
+	RunId      string          `gorm:"column:RunId;size:64" json:"runId,omitempty"` // The run_id of the log lines of the calculation
+	Status     string          `gorm:"column:Status;size:16;not null;default:succeeded" json:"status"`
+	StartedAt  model.Timestamp `gorm:"column:StartedAt;not null" json:"startedAt"`
+	FinishedAt model.Timestamp `gorm:"column:FinishedAt;not null" json:"finishedAt"`
+	Rules      int64           `gorm:"column:Rules;not null" json:"rules"` // GoMatrixRule rows written
+	// SkippedUsers counts the users whose filters failed under the skip_user policy, none of their rules being written
+	SkippedUsers int64 `gorm:"column:SkippedUsers;not null;default:0" json:"skippedUsers,omitempty"`
+	// SnapshotAt is when the snapshot the inputs were read from was taken, nil if the run was interrupted before
+	SnapshotAt *model.Timestamp `gorm:"column:SnapshotAt" json:"snapshotAt,omitempty"`
+}
+
+// FreshnessReport tells how old the stored security matrix is.
+type FreshnessReport struct {
+	LastCalculation    *GoMatrixCalculationRun `json:"lastCalculation"` // nil when the matrix was never calculated
+	AgeSeconds         float64                 `json:"ageSeconds,omitempty"`
+	StalenessThreshold string                  `json:"stalenessThreshold"`
+	Stale              bool                    `json:"stale"` // Older than the threshold, or never calculated
+}
+
+// ReadinessCheck is the outcome of one readiness check, Error is empty when it passed.
+type ReadinessCheck struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReadinessReport lists the outcome of every readiness check.
+type ReadinessReport struct {
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// Ready reports whether every check passed.
+func (r ReadinessReport) Ready() bool {
+	for _, check := range r.Checks {
+		if check.Error != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewFreshnessReport builds the FreshnessReport of the last calculation at the given time.
+//
+// Parameters:
+// - last_run: The last successful calculation, nil if there is none.
+// - threshold: The age above which the matrix is stale.
+// - now: The current time.
+//
+// Returns:
+// - FreshnessReport: The report.
+func NewFreshnessReport(last_run *GoMatrixCalculationRun, threshold time.Duration, now time.Time) FreshnessReport {
+	report := FreshnessReport{
+		LastCalculation:    last_run,
+		StalenessThreshold: threshold.String(),
+		Stale:              true,
+	}
+
+	if last_run != nil {
+		age := now.Sub(last_run.FinishedAt.Time)
+		report.AgeSeconds = age.Seconds()
+		report.Stale = age > threshold
+	}
+
+	return report
+}
@@ -0,0 +1,87 @@
+package model_security
+
+import "sort"
+
+// LevelIndex answers which employees hold a profile at or below a level. It is built once per calculation from
+// LevelToEmployees and shared by every filter, so it is never modified once built.
+//
+// Each employee is placed at the lowest level of its profiles, and the employees are ordered by that level and then
+// by id: the employees at or below a level are a prefix of that order, sharing its backing array.
+type LevelIndex struct {
+	levels    []uint // Distinct profile levels, ascending
+	employees []uint // Distinct employees, by lowest level and then by id
+	counts    []int  // counts[i]: employees at or below levels[i]
+}
+
+// NewLevelIndex builds the LevelIndex of a map of profile levels to employee IDs.
+//
+// Parameters:
+// - level_to_employees: The employee IDs holding a profile of each level, see helpers.GetLevelToEmployeesMap.
+//
+// Returns:
+// - *LevelIndex: The index.
+func NewLevelIndex(level_to_employees map[uint][]uint) *LevelIndex {
+	index := &LevelIndex{}
+
+	lowest_levels := make(map[uint]uint)
+	for level, employee_ids := range level_to_employees {
+		index.levels = append(index.levels, level)
+		for _, employee_id := range employee_ids {
+			if lowest, found := lowest_levels[employee_id]; !found || level < lowest {
+				lowest_levels[employee_id] = level
+			}
+		}
+	}
+	sort.Slice(index.levels, func(i, j int) bool {
+		return index.levels[i] < index.levels[j]
+	})
+
+	index.employees = make([]uint, 0, len(lowest_levels))
+	for employee_id := range lowest_levels {
+		index.employees = append(index.employees, employee_id)
+	}
+	sort.Slice(index.employees, func(i, j int) bool {
+		level_i, level_j := lowest_levels[index.employees[i]], lowest_levels[index.employees[j]]
+		if level_i != level_j {
+			return level_i < level_j
+		}
+		return index.employees[i] < index.employees[j]
+	})
+
+	index.counts = make([]int, len(index.levels))
+	count := 0
+	for i, level := range index.levels {
+		for count < len(index.employees) && lowest_levels[index.employees[count]] <= level {
+			count++
+		}
+		index.counts[i] = count
+	}
+
+	return index
+}
+
+// Levels returns the distinct profile levels in ascending order. The slice must not be modified.
+func (i *LevelIndex) Levels() []uint {
+	return i.levels
+}
+
+// EmployeesAtOrBelow returns the distinct employees holding a profile at the given level or below, in a slice shared by
+// every caller that must not be modified; appending to it allocates a new one.
+//
+// Parameters:
+// - level: The level.
+//
+// Returns:
+// - []uint: The employee IDs, by lowest level and then by id.
+func (i *LevelIndex) EmployeesAtOrBelow(level uint) []uint {
+	// The first level above the given one
+	above := sort.Search(len(i.levels), func(k int) bool {
+		return i.levels[k] > level
+	})
+	if above == 0 {
+		return nil
+	}
+
+	count := i.counts[above-1]
+	return i.employees[:count:count]
+}
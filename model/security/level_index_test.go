@@ -0,0 +1,47 @@
+package model_security_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+func TestLevelIndex(t *testing.T) {
+	index := security_model.NewLevelIndex(map[uint][]uint{
+		0: {4, 2, 3, 1},
+		2: {1, 5, 5},
+		5: {6, 2},
+	})
+
+	assert.Equal(t, []uint{0, 2, 5}, index.Levels())
+
+	tests := []struct {
+		level    uint
+		expected []uint
+	}{
+		{0, []uint{1, 2, 3, 4}},
+		{1, []uint{1, 2, 3, 4}},
+		{2, []uint{1, 2, 3, 4, 5}}, // Employee 1 once, at its lowest level
+		{4, []uint{1, 2, 3, 4, 5}},
+		{5, []uint{1, 2, 3, 4, 5, 6}},
+		{9, []uint{1, 2, 3, 4, 5, 6}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, index.EmployeesAtOrBelow(test.level), "level %d", test.level)
+	}
+}
+
+func TestLevelIndexIsNotModifiedByAppends(t *testing.T) {
+	index := security_model.NewLevelIndex(map[uint][]uint{1: {1}, 2: {2}})
+
+	_ = append(index.EmployeesAtOrBelow(1), 99)
+
+	assert.Equal(t, []uint{1, 2}, index.EmployeesAtOrBelow(2))
+}
+
+func TestLevelIndexBelowTheLowestLevel(t *testing.T) {
+	assert.Empty(t, security_model.NewLevelIndex(map[uint][]uint{3: {1}}).EmployeesAtOrBelow(2))
+	assert.Empty(t, security_model.NewLevelIndex(nil).EmployeesAtOrBelow(2))
+}
@@ -5,3 +5,11 @@ type GoMatrixRule struct {
 	EmployeeId      uint   `gorm:"column:EmployeeId;primaryKey"`
 	AccessLevelRead uint32 `gorm:"column:AccessLevelRead"`
 }
+
+// EnrichedMatrixRule is a GoMatrixRule with the email of its user (from Security.Users) and the number
+// of its employee (from Core.Employees), both empty when the user or the employee is unknown.
+type EnrichedMatrixRule struct {
+	GoMatrixRule
+	UserEmail      string
+	EmployeeNumber string
+}
@@ -0,0 +1,81 @@
+package model_security
+
+import "fmt"
+
+// ParitySource selects the matrix compared with the legacy one.
+type ParitySource string
+
+const (
+	ParitySourceStored     ParitySource = "stored"     // The GoMatrixRule table
+	ParitySourceCalculated ParitySource = "calculated" // A fresh in-memory calculation, nothing is written
+)
+
+// ParseParitySource returns the parity source with the given name.
+func ParseParitySource(name string) (ParitySource, error) {
+	switch source := ParitySource(name); source {
+	case ParitySourceStored, ParitySourceCalculated:
+		return source, nil
+	default:
+		return "", fmt.Errorf("unknown parity source %q, expected %q or %q", name, ParitySourceStored, ParitySourceCalculated)
+	}
+}
+
+// ParityMismatchType identifies how a rule of the legacy matrix differs from the compared one.
+// A rule missing from either matrix counts as an access level of 0.
+type ParityMismatchType string
+
+const (
+	// MissingRule is granted by the legacy matrix only.
+	MissingRule ParityMismatchType = "MISSING_RULE"
+	// UnexpectedRule is granted by the compared matrix only.
+	UnexpectedRule ParityMismatchType = "UNEXPECTED_RULE"
+	// AccessLevelMismatch is granted by both matrices with different access levels.
+	AccessLevelMismatch ParityMismatchType = "ACCESS_LEVEL_MISMATCH"
+)
+
+// NoContributingFilter groups the mismatches for which no filter produced a rule.
+const NoContributingFilter = "(none)"
+
+// ParityMismatch is a (user, employee) pair whose access level differs between the matrices.
+type ParityMismatch struct {
+	UserId                string             `json:"userId"`
+	EmployeeId            uint               `json:"employeeId"`
+	Type                  ParityMismatchType `json:"type"`
+	LegacyAccessLevelRead uint32             `json:"legacyAccessLevelRead"`
+	AccessLevelRead       uint32             `json:"accessLevelRead"`
+	// Filters are the filters producing a rule for the pair in a calculation from the current data.
+	Filters []string `json:"filters"`
+}
+
+// ParityUserMismatches counts the mismatches of a user and lists the first ones.
+type ParityUserMismatches struct {
+	UserId     string                     `json:"userId"`
+	Mismatches int                        `json:"mismatches"`
+	ByType     map[ParityMismatchType]int `json:"byType"`
+	Samples    []ParityMismatch           `json:"samples"`
+}
+
+// ParityFilterMismatches counts the mismatches a filter contributed to.
+type ParityFilterMismatches struct {
+	Filter     string                     `json:"filter"`
+	Mismatches int                        `json:"mismatches"`
+	ByType     map[ParityMismatchType]int `json:"byType"`
+}
+
+// ParityReport is the result of a comparison between the legacy matrix and GoMatrixRule.
+type ParityReport struct {
+	Source      ParitySource `json:"source"`
+	LegacyTable string       `json:"legacyTable"`
+	LegacyRules int          `json:"legacyRules"` // Rules of the legacy matrix
+	Rules       int          `json:"rules"`       // Rules of the compared matrix
+	Mismatches  int          `json:"mismatches"`
+	// ByUser lists the users with mismatches, most mismatches first.
+	ByUser []ParityUserMismatches `json:"byUser"`
+	// ByFilter lists the contributing filters, most mismatches first. A mismatch counts for each of its filters.
+	ByFilter []ParityFilterMismatches `json:"byFilter"`
+}
+
+// HasMismatches reports whether the matrices differ.
+func (r ParityReport) HasMismatches() bool {
+	return r.Mismatches > 0
+}
@@ -7,6 +7,13 @@ type TupleProfileUser struct {
 	User    *model.User    `json:"user"`
 }
 
+// UserProfiles groups the manager and employee profiles held by a single (viewer) user.
+type UserProfiles struct {
+	User             *model.User
+	ManagerProfiles  []*model.Profile
+	EmployeeProfiles []*model.Profile
+}
+
 type TupleProcessors1 struct {
 	EmployeeId uint
 	Level      int
@@ -17,10 +24,13 @@ type TupleProcessors2 struct {
 	Level     int
 }
 
+// SecurityMatrixCalculationFilterInput is the input shared by every filter, built once per calculation by
+// BuildCalculationInput. The filters must not modify it.
 type SecurityMatrixCalculationFilterInput struct {
 	EmployeeToManagers map[int][]TupleProcessors2
 	EmployeeToReports  map[int][]TupleProcessors1
 	LevelToEmployees   map[uint][]uint
+	LevelIndex         *LevelIndex // Built from LevelToEmployees, read by the DenyProfileLevelsFilter when set
 }
 
 type AllowOrDenyRule struct {
@@ -0,0 +1,26 @@
+package model
+
+import "gorm.io/gorm/schema"
+
+// schemaTableNamer is implemented by naming strategies that know whether the dialect supports schemas.
+type schemaTableNamer interface {
+	SchemaTableName(schemaName, table string) string
+}
+
+// SchemaTableName returns the name of a table living in the given schema. Dialects without schemas
+// (e.g. SQLite) get the schema as a prefix instead ("Core_Employees" rather than "Core.Employees").
+//
+// Parameters:
+// - namer: the naming strategy of the database connection.
+// - schemaName: the schema of the table (e.g. "Core").
+// - table: the name of the table within the schema (e.g. "Employees").
+//
+// Returns:
+// - string: the dialect-aware table name.
+func SchemaTableName(namer schema.Namer, schemaName, table string) string {
+	if schema_namer, ok := namer.(schemaTableNamer); ok {
+		return schema_namer.SchemaTableName(schemaName, table)
+	}
+
+	return schemaName + "." + table
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Timestamp is a time.Time column stored as datetime2 on SQL Server and as the native
+// timestamp type of the other dialects.
+type Timestamp struct {
+	time.Time
+}
+
+// GormDataType tells GORM to handle the column as a time.
+func (Timestamp) GormDataType() string {
+	return string(schema.Time)
+}
+
+// GormDBDataType returns the column type of the dialect in use.
+func (Timestamp) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "sqlserver":
+		return "datetime2"
+	case "postgres":
+		return "timestamp"
+	default:
+		return "datetime"
+	}
+}
+
+// Scan implements the sql.Scanner interface.
+func (t *Timestamp) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v
+	default:
+		return fmt.Errorf("cannot scan %T into Timestamp", value)
+	}
+
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (t Timestamp) Value() (driver.Value, error) {
+	return t.Time, nil
+}
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type calculationRunRepository struct {
+	DB *gorm.DB
+}
+
+// NewCalculationRunRepository creates the CalculationRunRepository and migrates the GoMatrixCalculationRun table.
+func NewCalculationRunRepository(DB *gorm.DB) (interfaces.CalculationRunRepository, error) {
+	repo := &calculationRunRepository{DB: DB}
+
+	// Perform the migration
+	if err := repo.Migrate(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Migrate uses GORM's AutoMigrate to handle the table creation for GoMatrixCalculationRun.
+//
+// Returns:
+// - error: an error object if the migration fails, nil otherwise.
+func (p *calculationRunRepository) Migrate() error {
+	return p.DB.AutoMigrate(&security_model.GoMatrixCalculationRun{})
+}
+
+// RecordRun inserts a GoMatrixCalculationRun entry, its Id is set by the database.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - run: the succeeded or interrupted calculation.
+//
+// Returns:
+// - error: an error object if the insert fails, nil otherwise.
+func (p *calculationRunRepository) RecordRun(ctx context.Context, run *security_model.GoMatrixCalculationRun) error {
+	return p.DB.WithContext(ctx).Create(run).Error
+}
+
+// LastRun reads the succeeded GoMatrixCalculationRun entry that finished last, the interrupted runs are ignored.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle.
+//
+// Returns:
+// - *security_model.GoMatrixCalculationRun: the last successful calculation, nil if there is none.
+// - error: an error object if the read fails, nil otherwise.
+func (p *calculationRunRepository) LastRun(ctx context.Context) (*security_model.GoMatrixCalculationRun, error) {
+	var runs []security_model.GoMatrixCalculationRun
+
+	result := connection(ctx, p.DB).
+		Where(clause.Eq{Column: clause.Column{Name: "Status"}, Value: security_model.RunStatusSucceeded}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "FinishedAt"}, Desc: true}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "Id"}, Desc: true}).
+		Limit(1).
+		Find(&runs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+
+	return &runs[0], nil
+}
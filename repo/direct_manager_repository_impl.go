@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"context"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+
+	"gorm.io/gorm"
+)
+
+// directManagerRepository provides methods for accessing the direct manager -> employee edges
+type directManagerRepository struct {
+	DB *gorm.DB
+}
+
+func NewDirectManagerRepository(DB *gorm.DB) interfaces.DirectManagerRepository {
+	return &directManagerRepository{DB}
+}
+
+// FindAll retrieves all DirectManager records from the database.
+// Parameters:
+// - ctx: context for managing request lifecycle.
+//
+// Returns:
+// - []model.DirectManager: a slice of DirectManager records.
+// - error: error object if the operation fails, nil otherwise.
+func (p *directManagerRepository) FindAll(ctx context.Context) ([]model.DirectManager, error) {
+	var directManagers []model.DirectManager
+	err := connection(ctx, p.DB).Find(&directManagers).Error
+
+	return directManagers, err
+}
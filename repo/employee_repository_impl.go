@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"context"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+
+	"gorm.io/gorm"
+)
+
+// employeeRepository provides methods for accessing Employees
+type employeeRepository struct {
+	DB *gorm.DB
+}
+
+func NewEmployeeRepository(DB *gorm.DB) interfaces.EmployeeRepository {
+	return &employeeRepository{DB}
+}
+
+// FindAll retrieves all Employee records from the database.
+// Parameters:
+// - ctx: context for managing request lifecycle.
+//
+// Returns:
+// - []model.Employee: a slice of Employee records.
+// - error: error object if the operation fails, nil otherwise.
+func (p *employeeRepository) FindAll(ctx context.Context) ([]model.Employee, error) {
+	var employees []model.Employee
+	err := connection(ctx, p.DB).Find(&employees).Error
+
+	return employees, err
+}
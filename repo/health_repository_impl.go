@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+
+	"gorm.io/gorm"
+)
+
+type healthRepository struct {
+	DB *gorm.DB
+}
+
+func NewHealthRepository(DB *gorm.DB) interfaces.HealthRepository {
+	return &healthRepository{DB}
+}
+
+// Ping checks the database answers, through a connection of the GORM pool.
+//
+// Parameters:
+// - ctx: context bounding the check.
+//
+// Returns:
+// - error: an error object if the database cannot be reached, nil otherwise.
+func (p *healthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := p.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckMigrations checks the tables written by the application exist with every column of their model.
+//
+// Parameters:
+// - ctx: context bounding the check.
+//
+// Returns:
+// - error: an error naming the first missing table or column, nil otherwise.
+func (p *healthRepository) CheckMigrations(ctx context.Context) error {
+	migrator := p.DB.WithContext(ctx).Migrator()
+
+	for _, value := range []interface{}{&security_model.GoMatrixRule{}, &security_model.GoMatrixCalculationRun{}} {
+		statement := &gorm.Statement{DB: p.DB}
+		if err := statement.Parse(value); err != nil {
+			return err
+		}
+
+		if !migrator.HasTable(value) {
+			return fmt.Errorf("table %s is missing", statement.Schema.Table)
+		}
+		for _, field := range statement.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(value, field.DBName) {
+				return fmt.Errorf("column %s of table %s is missing", field.DBName, statement.Schema.Table)
+			}
+		}
+	}
+
+	return nil
+}
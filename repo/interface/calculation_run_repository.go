@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+
+	model_security "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+type CalculationRunRepository interface {
+	Migrate() error
+	RecordRun(ctx context.Context, run *model_security.GoMatrixCalculationRun) error
+	LastRun(ctx context.Context) (*model_security.GoMatrixCalculationRun, error) // The last succeeded run
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/nuno-bastos/gin-gonic-wire-api/model"
+)
+
+type DirectManagerRepository interface {
+	FindAll(ctx context.Context) ([]model.DirectManager, error)
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/nuno-bastos/gin-gonic-wire-api/model"
+)
+
+type EmployeeRepository interface {
+	FindAll(ctx context.Context) ([]model.Employee, error)
+}
@@ -0,0 +1,10 @@
+package interfaces
+
+import (
+	"context"
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	CheckMigrations(ctx context.Context) error
+}
@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+
+	model_security "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+type LegacyMatrixRuleRepository interface {
+	Table() string
+	ReadRules(ctx context.Context, batchSize int, handle func(rules []model_security.GoMatrixRule) error) error
+}
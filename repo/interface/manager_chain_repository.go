@@ -8,4 +8,5 @@ import (
 
 type ManagerChainRepository interface {
 	FindAll(ctx context.Context) ([]model.ManagerChain, error)
+	ReplaceAll(ctx context.Context, managerChains []model.ManagerChain) error
 }
@@ -9,4 +9,8 @@ import (
 type SecurityMatrixRuleRepository interface {
 	Migrate() error
 	WriteRules(ctx context.Context, rules []model_security.GoMatrixRule) error
+	WriteRulesStream(ctx context.Context, rules <-chan []model_security.GoMatrixRule) error
+	ReadRules(ctx context.Context, batchSize int, handle func(rules []model_security.GoMatrixRule) error) error
+	ReadEnrichedRules(ctx context.Context, batchSize int, handle func(rules []model_security.EnrichedMatrixRule) error) error
+	ReadUserRules(ctx context.Context, userId string) ([]model_security.GoMatrixRule, error)
 }
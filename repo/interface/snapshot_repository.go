@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+type SnapshotRepository interface {
+	// ReadSnapshot runs read with a context whose repository reads all see the same snapshot of the database,
+	// and returns when that snapshot was taken.
+	ReadSnapshot(ctx context.Context, read func(ctx context.Context) error) (time.Time, error)
+}
@@ -8,4 +8,6 @@ import (
 
 type UserRepository interface {
 	FetchEmployeesWithAssociatedProfiles(ctx context.Context) ([]*model.User, error)
+	FindUserById(ctx context.Context, id string) (*model.User, error)
+	FindUserByEmail(ctx context.Context, email string) (*model.User, error)
 }
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// legacyMatrixRuleRepository provides methods for reading the matrix table of the legacy implementation
+type legacyMatrixRuleRepository struct {
+	DB     *gorm.DB
+	config db.ParityConfig
+}
+
+// NewLegacyMatrixRuleRepository creates the LegacyMatrixRuleRepository reading the table and columns of the parity
+// configuration, by default the UserId, EmployeeId and AccessLevelRead columns of Security.MatrixRules.
+// A schema-qualified table name is resolved like the application's tables (Security_MatrixRules on SQLite).
+func NewLegacyMatrixRuleRepository(DB *gorm.DB, config *db.Config) interfaces.LegacyMatrixRuleRepository {
+	parity_config := config.Parity
+	if parity_config.LegacyTable == "" {
+		parity_config.LegacyTable = "Security.MatrixRules"
+	}
+	if parity_config.UserColumn == "" {
+		parity_config.UserColumn = "UserId"
+	}
+	if parity_config.EmployeeColumn == "" {
+		parity_config.EmployeeColumn = "EmployeeId"
+	}
+	if parity_config.AccessLevelColumn == "" {
+		parity_config.AccessLevelColumn = "AccessLevelRead"
+	}
+
+	return &legacyMatrixRuleRepository{DB: DB, config: parity_config}
+}
+
+// Table returns the name of the legacy matrix table.
+func (p *legacyMatrixRuleRepository) Table() string {
+	return p.config.LegacyTable
+}
+
+// ReadRules reads every rule of the legacy matrix table, ordered by user and employee,
+// and hands them to handle in batches, so the matrix is never held in memory as a whole.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - batch_size: the maximum number of rules handed to handle at once.
+// - handle: called with each batch of rules; reading stops at the first error it returns.
+//
+// Returns:
+// - error: an error object if the read or handle fails, nil otherwise.
+func (p *legacyMatrixRuleRepository) ReadRules(ctx context.Context, batch_size int, handle func(rules []security_model.GoMatrixRule) error) error {
+	table := p.config.LegacyTable
+	if schema_name, table_name, found := strings.Cut(table, "."); found {
+		table = model.SchemaTableName(p.DB.NamingStrategy, schema_name, table_name)
+	}
+
+	user_column := clause.Column{Name: p.config.UserColumn}
+	employee_column := clause.Column{Name: p.config.EmployeeColumn}
+	rows, err := connection(ctx, p.DB).
+		Table("?", clause.Table{Name: table}).
+		Select("?, ?, ?", user_column, employee_column, clause.Column{Name: p.config.AccessLevelColumn}).
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{{Column: user_column}, {Column: employee_column}}}).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return readInBatches(rows, batch_size, func(rows *sql.Rows) (rule security_model.GoMatrixRule, err error) {
+		err = rows.Scan(&rule.UserId, &rule.EmployeeId, &rule.AccessLevelRead)
+		return rule, err
+	}, handle)
+}
@@ -27,7 +27,41 @@ func NewManagerChainRepository(DB *gorm.DB) interfaces.ManagerChainRepository {
 // - error: error object if the operation fails, nil otherwise.
 func (p *managerChainRepository) FindAll(ctx context.Context) ([]model.ManagerChain, error) {
 	var managerChains []model.ManagerChain
-	err := p.DB.Find(&managerChains).Error
+	err := connection(ctx, p.DB).Find(&managerChains).Error
 
 	return managerChains, err
 }
+
+// ReplaceAll replaces every ManagerChain record in the database with the given ones.
+// It deletes existing records and inserts the new ones within a single transaction,
+// so other consumers of the table never observe a partially written closure.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - managerChains: a slice of ManagerChain to be written to the database.
+//
+// Returns:
+// - error: an error object if the operation fails, nil otherwise.
+func (p *managerChainRepository) ReplaceAll(ctx context.Context, managerChains []model.ManagerChain) error {
+	tx := p.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	// Delete existing records
+	if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&model.ManagerChain{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 4 parameters per row, kept well below the SQL Server limit of 2100 parameters per statement
+	batchSize := 500
+	if len(managerChains) > 0 {
+		if err := tx.CreateInBatches(managerChains, batchSize).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// calculationRunRepository provides methods for recording the calculation runs of a Store
+type calculationRunRepository struct {
+	store *Store
+}
+
+func NewCalculationRunRepository(store *Store) interfaces.CalculationRunRepository {
+	return &calculationRunRepository{store}
+}
+
+// Migrate does nothing: the store needs no schema.
+func (p *calculationRunRepository) Migrate() error {
+	return nil
+}
+
+// RecordRun appends the run to the store and sets its Id. Like the database column, Status defaults to succeeded.
+func (p *calculationRunRepository) RecordRun(ctx context.Context, run *security_model.GoMatrixCalculationRun) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if run.Status == "" {
+		run.Status = security_model.RunStatusSucceeded
+	}
+
+	p.store.write(func(data *Data) {
+		run.Id = uint(len(data.Runs) + 1)
+		data.Runs = append(data.Runs, *run)
+	})
+
+	return nil
+}
+
+// LastRun returns the succeeded run that finished last, nil if there is none.
+func (p *calculationRunRepository) LastRun(ctx context.Context) (*security_model.GoMatrixCalculationRun, error) {
+	var last *security_model.GoMatrixCalculationRun
+	p.store.read(func(data Data) {
+		for i, run := range data.Runs {
+			if run.Status != security_model.RunStatusSucceeded {
+				continue
+			}
+			if last == nil || !run.FinishedAt.Before(last.FinishedAt.Time) {
+				last = &data.Runs[i]
+			}
+		}
+	})
+
+	return last, nil
+}
@@ -0,0 +1,24 @@
+package memory
+
+import (
+	"context"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// directManagerRepository provides methods for accessing the DirectManagers of a Store
+type directManagerRepository struct {
+	store *Store
+}
+
+func NewDirectManagerRepository(store *Store) interfaces.DirectManagerRepository {
+	return &directManagerRepository{store}
+}
+
+// FindAll retrieves all DirectManager records from the store.
+func (p *directManagerRepository) FindAll(ctx context.Context) (direct_managers []model.DirectManager, err error) {
+	p.store.read(func(data Data) { direct_managers = data.DirectManagers })
+
+	return direct_managers, nil
+}
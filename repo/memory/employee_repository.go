@@ -0,0 +1,24 @@
+package memory
+
+import (
+	"context"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// employeeRepository provides methods for accessing the Employees of a Store
+type employeeRepository struct {
+	store *Store
+}
+
+func NewEmployeeRepository(store *Store) interfaces.EmployeeRepository {
+	return &employeeRepository{store}
+}
+
+// FindAll retrieves all Employee records from the store.
+func (p *employeeRepository) FindAll(ctx context.Context) (employees []model.Employee, err error) {
+	p.store.read(func(data Data) { employees = data.Employees })
+
+	return employees, nil
+}
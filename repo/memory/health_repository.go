@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"context"
+
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// healthRepository checks the health of a Store, which is always reachable and needs no schema
+type healthRepository struct {
+	store *Store
+}
+
+func NewHealthRepository(store *Store) interfaces.HealthRepository {
+	return &healthRepository{store}
+}
+
+// Ping only fails when ctx is done.
+func (p *healthRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// CheckMigrations never fails: the store needs no schema.
+func (p *healthRepository) CheckMigrations(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// legacyMatrixRuleRepository provides methods for reading the legacy matrix rules of a Store
+type legacyMatrixRuleRepository struct {
+	store *Store
+}
+
+func NewLegacyMatrixRuleRepository(store *Store) interfaces.LegacyMatrixRuleRepository {
+	return &legacyMatrixRuleRepository{store}
+}
+
+// Table returns the name the legacy rules of a store are reported under.
+func (p *legacyMatrixRuleRepository) Table() string {
+	return "memory"
+}
+
+// ReadRules hands every legacy rule of the store, ordered by UserId and EmployeeId, to handle in batches.
+func (p *legacyMatrixRuleRepository) ReadRules(ctx context.Context, batch_size int, handle func(rules []security_model.GoMatrixRule) error) error {
+	var rules []security_model.GoMatrixRule
+	p.store.read(func(data Data) { rules = data.LegacyRules })
+
+	return readSortedRules(ctx, rules, batch_size, handle)
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// managerChainRepository provides methods for accessing the ManagerChains of a Store
+type managerChainRepository struct {
+	store *Store
+}
+
+func NewManagerChainRepository(store *Store) interfaces.ManagerChainRepository {
+	return &managerChainRepository{store}
+}
+
+// FindAll retrieves all ManagerChain records from the store.
+func (p *managerChainRepository) FindAll(ctx context.Context) (manager_chains []model.ManagerChain, err error) {
+	p.store.read(func(data Data) { manager_chains = data.ManagerChains })
+
+	return manager_chains, nil
+}
+
+// ReplaceAll replaces every ManagerChain record of the store with the given ones.
+func (p *managerChainRepository) ReplaceAll(ctx context.Context, manager_chains []model.ManagerChain) error {
+	manager_chains = append([]model.ManagerChain{}, manager_chains...)
+	p.store.write(func(data *Data) { data.ManagerChains = manager_chains })
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"testing"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/repotest"
+)
+
+// TestRepositoryContract runs the repository contract test suite on the in-memory repositories.
+func TestRepositoryContract(t *testing.T) {
+	repotest.RunContract(t, func(t *testing.T, dataset repotest.Dataset) repotest.Backend {
+		profiles := make(map[string]*model.Profile)
+		for i := range dataset.Profiles {
+			profiles[dataset.Profiles[i].Id] = &dataset.Profiles[i]
+		}
+
+		users := make([]*model.User, len(dataset.Users))
+		users_by_id := make(map[string]*model.User)
+		for i := range dataset.Users {
+			users[i] = &dataset.Users[i]
+			users_by_id[users[i].Id] = users[i]
+		}
+		for _, profile_user := range dataset.ProfileUsers {
+			user := users_by_id[profile_user.UserID]
+			user.Profiles = append(user.Profiles, profiles[profile_user.ProfileID])
+		}
+
+		store := NewStore(Data{
+			Employees:      dataset.Employees,
+			ManagerChains:  dataset.ManagerChains,
+			DirectManagers: dataset.DirectManagers,
+			Users:          users,
+			LegacyRules:    dataset.LegacyRules,
+		})
+
+		return repotest.Backend{
+			ManagerChains:  NewManagerChainRepository(store),
+			DirectManagers: NewDirectManagerRepository(store),
+			Employees:      NewEmployeeRepository(store),
+			Users:          NewUserRepository(store),
+			Rules:          NewSecurityMatrixRuleRepository(store),
+			Runs:           NewCalculationRunRepository(store),
+			Health:         NewHealthRepository(store),
+			Snapshots:      NewSnapshotRepository(store),
+			LegacyRules:    NewLegacyMatrixRuleRepository(store),
+			ReadRules:      func(t *testing.T) []security_model.GoMatrixRule { return store.Rules() },
+		}
+	})
+}
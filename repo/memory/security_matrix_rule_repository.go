@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// securityMatrixRuleRepository provides methods for writing the GoMatrixRules of a Store
+type securityMatrixRuleRepository struct {
+	store *Store
+}
+
+func NewSecurityMatrixRuleRepository(store *Store) interfaces.SecurityMatrixRuleRepository {
+	return &securityMatrixRuleRepository{store}
+}
+
+// Migrate does nothing: the store needs no schema.
+func (p *securityMatrixRuleRepository) Migrate() error {
+	return nil
+}
+
+// WriteRules replaces every GoMatrixRule record of the store with the given ones.
+func (p *securityMatrixRuleRepository) WriteRules(ctx context.Context, rules []security_model.GoMatrixRule) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rules = append([]security_model.GoMatrixRule{}, rules...)
+	p.store.write(func(data *Data) { data.Rules = rules })
+
+	return nil
+}
+
+// WriteRulesStream replaces every GoMatrixRule record of the store with the rules received until
+// the channel is closed. Nothing is replaced if ctx is cancelled before the stream completes.
+func (p *securityMatrixRuleRepository) WriteRulesStream(ctx context.Context, rules_channel <-chan []security_model.GoMatrixRule) error {
+	rules := []security_model.GoMatrixRule{}
+	for chunk := range rules_channel {
+		rules = append(rules, chunk...)
+	}
+
+	return p.WriteRules(ctx, rules)
+}
+
+// ReadRules hands every GoMatrixRule record of the store, ordered by UserId and EmployeeId, to handle in batches.
+func (p *securityMatrixRuleRepository) ReadRules(ctx context.Context, batch_size int, handle func(rules []security_model.GoMatrixRule) error) error {
+	return readSortedRules(ctx, p.store.Rules(), batch_size, handle)
+}
+
+// ReadUserRules returns the GoMatrixRule records of the viewer user, ordered by EmployeeId.
+func (p *securityMatrixRuleRepository) ReadUserRules(ctx context.Context, user_id string) ([]security_model.GoMatrixRule, error) {
+	rules := []security_model.GoMatrixRule{}
+	for _, rule := range p.store.Rules() {
+		if rule.UserId == user_id {
+			rules = append(rules, rule)
+		}
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].EmployeeId < rules[j].EmployeeId })
+
+	return rules, ctx.Err()
+}
+
+// readSortedRules sorts the rules by UserId and EmployeeId and hands them to handle in batches.
+func readSortedRules(ctx context.Context, rules []security_model.GoMatrixRule, batch_size int, handle func(rules []security_model.GoMatrixRule) error) error {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].UserId != rules[j].UserId {
+			return rules[i].UserId < rules[j].UserId
+		}
+		return rules[i].EmployeeId < rules[j].EmployeeId
+	})
+
+	if batch_size <= 0 {
+		batch_size = len(rules)
+	}
+	for start := 0; start < len(rules); start += batch_size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start + batch_size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		if err := handle(rules[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadEnrichedRules hands every GoMatrixRule record of the store, with the email of its user and the number
+// of its employee, ordered by UserId and EmployeeId, to handle in batches.
+func (p *securityMatrixRuleRepository) ReadEnrichedRules(ctx context.Context, batch_size int, handle func(rules []security_model.EnrichedMatrixRule) error) error {
+	emails := make(map[string]string)
+	employee_numbers := make(map[uint]string)
+	p.store.read(func(data Data) {
+		for _, user := range data.Users {
+			emails[user.Id] = user.Email
+		}
+		for _, employee := range data.Employees {
+			employee_numbers[employee.Id] = employee.EmployeeNumber
+		}
+	})
+
+	return p.ReadRules(ctx, batch_size, func(rules []security_model.GoMatrixRule) error {
+		enriched := make([]security_model.EnrichedMatrixRule, len(rules))
+		for i, rule := range rules {
+			enriched[i] = security_model.EnrichedMatrixRule{
+				GoMatrixRule:   rule,
+				UserEmail:      emails[rule.UserId],
+				EmployeeNumber: employee_numbers[rule.EmployeeId],
+			}
+		}
+		return handle(enriched)
+	})
+}
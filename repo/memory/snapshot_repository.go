@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// snapshotRepository reads a Store, whose repositories each return a copy of its rows
+type snapshotRepository struct {
+	store *Store
+}
+
+func NewSnapshotRepository(store *Store) interfaces.SnapshotRepository {
+	return &snapshotRepository{store}
+}
+
+// ReadSnapshot runs read and returns when it started. The reads are only consistent with each other while the store
+// is not written concurrently.
+func (p *snapshotRepository) ReadSnapshot(ctx context.Context, read func(ctx context.Context) error) (time.Time, error) {
+	snapshot_at := time.Now().UTC()
+	if err := read(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	return snapshot_at, nil
+}
@@ -0,0 +1,112 @@
+// Package memory provides in-memory implementations of the repositories, backed by a shared Store.
+// They need no database and are meant for tests and local experiments.
+package memory
+
+import (
+	"sync"
+
+	"github.com/google/wire"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+// RepositorySet provides every in-memory repository from a *Store.
+var RepositorySet = wire.NewSet(
+	NewManagerChainRepository,
+	NewDirectManagerRepository,
+	NewUserRepository,
+	NewEmployeeRepository,
+	NewSecurityMatrixRuleRepository,
+	NewLegacyMatrixRuleRepository,
+	NewCalculationRunRepository,
+	NewHealthRepository,
+	NewSnapshotRepository,
+)
+
+// Data holds the rows a Store is created with.
+type Data struct {
+	Employees      []model.Employee
+	ManagerChains  []model.ManagerChain
+	DirectManagers []model.DirectManager
+	Users          []*model.User // Users with their associated profiles
+	Rules          []security_model.GoMatrixRule
+	LegacyRules    []security_model.GoMatrixRule // Rules of the legacy matrix, compared by the parity checker
+	Runs           []security_model.GoMatrixCalculationRun
+}
+
+// Store holds the tables shared by the in-memory repositories.
+// Every read returns a copy, so callers can never alter the stored rows.
+type Store struct {
+	mutex sync.RWMutex
+	data  Data
+}
+
+// NewStore creates a Store holding a copy of the given data.
+//
+// Parameters:
+// - data: The initial rows of the store.
+//
+// Returns:
+// - *Store: The store.
+func NewStore(data Data) *Store {
+	return &Store{data: copyData(data)}
+}
+
+// Rules returns a copy of the GoMatrixRule rows currently stored.
+func (s *Store) Rules() []security_model.GoMatrixRule {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]security_model.GoMatrixRule{}, s.data.Rules...)
+}
+
+// Runs returns a copy of the GoMatrixCalculationRun rows currently stored.
+func (s *Store) Runs() []security_model.GoMatrixCalculationRun {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]security_model.GoMatrixCalculationRun{}, s.data.Runs...)
+}
+
+// read runs f with a copy of the stored data.
+func (s *Store) read(f func(data Data)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	f(copyData(s.data))
+}
+
+// write runs f with exclusive access to the stored data.
+func (s *Store) write(f func(data *Data)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f(&s.data)
+}
+
+// copyData copies every row of data, including the users' profiles.
+func copyData(data Data) Data {
+	copied := Data{
+		Employees:      append([]model.Employee{}, data.Employees...),
+		ManagerChains:  append([]model.ManagerChain{}, data.ManagerChains...),
+		DirectManagers: append([]model.DirectManager{}, data.DirectManagers...),
+		Users:          make([]*model.User, len(data.Users)),
+		Rules:          append([]security_model.GoMatrixRule{}, data.Rules...),
+		LegacyRules:    append([]security_model.GoMatrixRule{}, data.LegacyRules...),
+		Runs:           append([]security_model.GoMatrixCalculationRun{}, data.Runs...),
+	}
+
+	for i, user := range data.Users {
+		user_copy := *user
+		user_copy.Profiles = make([]*model.Profile, len(user.Profiles))
+		for j, profile := range user.Profiles {
+			profile_copy := *profile
+			profile_copy.Users = nil
+			user_copy.Profiles[j] = &profile_copy
+		}
+		copied.Users[i] = &user_copy
+	}
+
+	return copied
+}
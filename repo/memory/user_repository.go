@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// userRepository provides methods for accessing the Users of a Store
+type userRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) interfaces.UserRepository {
+	return &userRepository{store}
+}
+
+// FetchEmployeesWithAssociatedProfiles retrieves the users who are employees (EmployeeId > 0),
+// with their associated profiles.
+func (p *userRepository) FetchEmployeesWithAssociatedProfiles(ctx context.Context) ([]*model.User, error) {
+	var users []*model.User
+	p.store.read(func(data Data) {
+		for _, user := range data.Users {
+			if user.EmployeeId > 0 {
+				users = append(users, user)
+			}
+		}
+	})
+
+	return users, nil
+}
+
+// FindUserById retrieves the user with the given Id, with its associated profiles, nil if there is none.
+func (p *userRepository) FindUserById(ctx context.Context, id string) (*model.User, error) {
+	return p.findUser(func(user *model.User) bool { return user.Id == id })
+}
+
+// FindUserByEmail retrieves the user with the given Email, with its associated profiles, nil if there is none.
+func (p *userRepository) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return p.findUser(func(user *model.User) bool { return user.Email == email })
+}
+
+// findUser retrieves the only user matching, an error when several do.
+func (p *userRepository) findUser(matches func(user *model.User) bool) (*model.User, error) {
+	var found []*model.User
+	p.store.read(func(data Data) {
+		for _, user := range data.Users {
+			if matches(user) {
+				found = append(found, user)
+			}
+		}
+	})
+
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return found[0], nil
+	default:
+		return nil, fmt.Errorf("several users match (%s, %s)", found[0].Id, found[1].Id)
+	}
+}
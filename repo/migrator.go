@@ -0,0 +1,41 @@
+package repo
+
+import (
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+
+	"gorm.io/gorm"
+)
+
+// Migrator creates or updates the tables of the application.
+type Migrator struct {
+	DB       *gorm.DB
+	ruleRepo interfaces.SecurityMatrixRuleRepository
+	runRepo  interfaces.CalculationRunRepository
+}
+
+func NewMigrator(DB *gorm.DB, ruleRepo interfaces.SecurityMatrixRuleRepository, runRepo interfaces.CalculationRunRepository) *Migrator {
+	return &Migrator{DB: DB, ruleRepo: ruleRepo, runRepo: runRepo}
+}
+
+// Migrate creates or updates the GoMatrixRule and GoMatrixCalculationRun tables written by the calculation.
+//
+// Parameters:
+// - input_tables: also create or update the input tables (Employees, ManagerChains, DirectManagers, Profiles,
+// Users and ProfileUsers), which are owned by other systems in production: for development and tests only.
+//
+// Returns:
+// - error: an error object if a migration fails, nil otherwise.
+func (p *Migrator) Migrate(input_tables bool) error {
+	if input_tables {
+		if err := db.MigrateInputTables(p.DB); err != nil {
+			return err
+		}
+	}
+
+	if err := p.ruleRepo.Migrate(); err != nil {
+		return err
+	}
+
+	return p.runRepo.Migrate()
+}
@@ -0,0 +1,19 @@
+package repo
+
+import (
+	"github.com/google/wire"
+)
+
+// RepositorySet provides every database repository, and the Migrator, from a *gorm.DB and the application configuration.
+var RepositorySet = wire.NewSet(
+	NewManagerChainRepository,
+	NewDirectManagerRepository,
+	NewUserRepository,
+	NewEmployeeRepository,
+	NewSecurityMatrixRuleRepository,
+	NewLegacyMatrixRuleRepository,
+	NewCalculationRunRepository,
+	NewHealthRepository,
+	NewSnapshotRepository,
+	NewMigrator,
+)
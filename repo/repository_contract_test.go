@@ -0,0 +1,188 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/repotest"
+)
+
+// TestRepositoryContract runs the repository contract test suite on every supported dialect.
+// SQLite always runs in memory, PostgreSQL and SQL Server run when TEST_POSTGRES_DSN and
+// TEST_SQLSERVER_DSN point at disposable databases (their tables are dropped and recreated).
+func TestRepositoryContract(t *testing.T) {
+	dialects := []struct {
+		driver string
+		dsn    string
+	}{
+		{db.DriverSQLite, ""},
+		{db.DriverPostgres, os.Getenv("TEST_POSTGRES_DSN")},
+		{db.DriverSQLServer, os.Getenv("TEST_SQLSERVER_DSN")},
+	}
+
+	for _, dialect := range dialects {
+		t.Run(dialect.driver, func(t *testing.T) {
+			if dialect.driver != db.DriverSQLite && dialect.dsn == "" {
+				t.Skipf("set TEST_%s_DSN to run the contract on %s", strings.ToUpper(dialect.driver), dialect.driver)
+			}
+
+			repotest.RunContract(t, func(t *testing.T, dataset repotest.Dataset) repotest.Backend {
+				dsn := dialect.dsn
+				if dialect.driver == db.DriverSQLite {
+					// A named shared-cache database per test, kept alive by the connection pool
+					dsn = fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+				}
+
+				return newGormBackend(t, dialect.driver, dsn, dataset)
+			})
+		})
+	}
+}
+
+// legacyMatrixRule is a row of the legacy matrix table, named differently from GoMatrixRule
+// to exercise the parity configuration.
+type legacyMatrixRule struct {
+	ViewerId string `gorm:"column:ViewerId;size:20"`
+	TargetId uint   `gorm:"column:TargetId"`
+	Access   uint32 `gorm:"column:Access"`
+}
+
+func (legacyMatrixRule) TableName(namer schema.Namer) string {
+	return model.SchemaTableName(namer, "Security", "LegacyMatrix")
+}
+
+// newGormBackend (re)creates every table on the given database, seeds it and returns the GORM repositories.
+func newGormBackend(t *testing.T, driver string, dsn string, dataset repotest.Dataset) repotest.Backend {
+	dialector, err := db.OpenDialector(driver, dsn)
+	require.NoError(t, err)
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		NamingStrategy: db.NewNamingStrategy(dialector.Name()),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := gormDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	require.NoError(t, db.CreateSchemas(gormDB, "Core", "Security"))
+	require.NoError(t, gormDB.Migrator().DropTable(
+		&model.ProfileUser{}, &model.User{}, &model.Profile{},
+		&model.DirectManager{}, &model.ManagerChain{}, &model.Employee{},
+		&security_model.GoMatrixRule{}, &security_model.GoMatrixCalculationRun{}, &legacyMatrixRule{},
+	))
+	require.NoError(t, db.MigrateInputTables(gormDB))
+	require.NoError(t, gormDB.AutoMigrate(&legacyMatrixRule{}))
+
+	for _, rows := range []interface{}{dataset.Employees, dataset.ManagerChains, dataset.DirectManagers, dataset.Profiles, dataset.Users, dataset.ProfileUsers} {
+		require.NoError(t, gormDB.Omit(clause.Associations).Create(rows).Error)
+	}
+	for _, rule := range dataset.LegacyRules {
+		require.NoError(t, gormDB.Create(&legacyMatrixRule{ViewerId: rule.UserId, TargetId: rule.EmployeeId, Access: rule.AccessLevelRead}).Error)
+	}
+
+	rule_repo, err := NewSecurityMatrixRuleRepository(gormDB, &db.Config{})
+	require.NoError(t, err)
+	run_repo, err := NewCalculationRunRepository(gormDB)
+	require.NoError(t, err)
+	snapshot_repo, err := NewSnapshotRepository(gormDB, &db.Config{})
+	require.NoError(t, err)
+
+	return repotest.Backend{
+		ManagerChains:  NewManagerChainRepository(gormDB),
+		DirectManagers: NewDirectManagerRepository(gormDB),
+		Employees:      NewEmployeeRepository(gormDB),
+		Users:          NewUserRepository(gormDB),
+		Rules:          rule_repo,
+		Runs:           run_repo,
+		Health:         NewHealthRepository(gormDB),
+		Snapshots:      snapshot_repo,
+		LegacyRules: NewLegacyMatrixRuleRepository(gormDB, &db.Config{Parity: db.ParityConfig{
+			LegacyTable:       "Security.LegacyMatrix",
+			UserColumn:        "ViewerId",
+			EmployeeColumn:    "TargetId",
+			AccessLevelColumn: "Access",
+		}}),
+		ReadRules: func(t *testing.T) []security_model.GoMatrixRule {
+			var rules []security_model.GoMatrixRule
+			require.NoError(t, gormDB.Find(&rules).Error)
+			return rules
+		},
+	}
+}
+
+// TestHealthRepositoryDetectsMissingMigrations checks the readiness check fails while a table written by the calculation is missing.
+func TestHealthRepositoryDetectsMissingMigrations(t *testing.T) {
+	backend := newGormBackend(t, db.DriverSQLite, "file:health_missing_migrations?mode=memory&cache=shared", repotest.DefaultDataset())
+	require.NoError(t, backend.Health.CheckMigrations(context.Background()))
+
+	gormDB := backend.Health.(*healthRepository).DB
+	require.NoError(t, gormDB.Migrator().DropTable(&security_model.GoMatrixCalculationRun{}))
+	assert.ErrorContains(t, backend.Health.CheckMigrations(context.Background()), "GoMatrixCalculationRun")
+
+	require.NoError(t, backend.Runs.Migrate())
+	assert.NoError(t, backend.Health.CheckMigrations(context.Background()))
+}
+
+// TestReadSnapshotIsolation checks the reads of a snapshot ignore the rows committed by another connection meanwhile.
+// SQLite runs on a file database in WAL mode, whose readers keep the snapshot of their first read. SQL Server needs
+// ALLOW_SNAPSHOT_ISOLATION ON.
+func TestReadSnapshotIsolation(t *testing.T) {
+	dialects := []struct {
+		driver string
+		dsn    string
+	}{
+		{db.DriverSQLite, "file:" + filepath.Join(t.TempDir(), "snapshot.db") + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"},
+		{db.DriverPostgres, os.Getenv("TEST_POSTGRES_DSN")},
+		{db.DriverSQLServer, os.Getenv("TEST_SQLSERVER_DSN")},
+	}
+
+	for _, dialect := range dialects {
+		t.Run(dialect.driver, func(t *testing.T) {
+			if dialect.dsn == "" {
+				t.Skipf("set TEST_%s_DSN to run the test on %s", strings.ToUpper(dialect.driver), dialect.driver)
+			}
+
+			ctx := context.Background()
+			backend := newGormBackend(t, dialect.driver, dialect.dsn, repotest.DefaultDataset())
+
+			var before, after []model.ManagerChain
+			_, err := backend.Snapshots.ReadSnapshot(ctx, func(snapshot_ctx context.Context) (err error) {
+				if before, err = backend.ManagerChains.FindAll(snapshot_ctx); err != nil {
+					return err
+				}
+
+				// Committed outside of the snapshot
+				if err := backend.ManagerChains.ReplaceAll(ctx, before[:1]); err != nil {
+					return err
+				}
+
+				after, err = backend.ManagerChains.FindAll(snapshot_ctx)
+				return err
+			})
+			require.NoError(t, err)
+			assert.Len(t, before, len(repotest.DefaultDataset().ManagerChains))
+			assert.ElementsMatch(t, before, after)
+
+			current, err := backend.ManagerChains.FindAll(ctx)
+			require.NoError(t, err)
+			assert.Len(t, current, 1)
+		})
+	}
+}
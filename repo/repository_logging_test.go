@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	logging "github.com/nuno-bastos/gin-gonic-wire-api/logging"
+)
+
+// connectLoggedDatabase opens an in-memory SQLite database with the input tables, logging to the returned buffer.
+func connectLoggedDatabase(t *testing.T, name string, config db.LoggingConfig) (*gorm.DB, *bytes.Buffer) {
+	t.Helper()
+
+	var output bytes.Buffer
+	app_config := &db.Config{DatabaseDriver: db.DriverSQLite, DatabaseDSN: "file:" + name + "?mode=memory&cache=shared", Logging: config}
+	logger, err := logging.NewLoggerTo(&output, app_config)
+	require.NoError(t, err)
+
+	gormDB, err := db.ConnectDatabase(context.Background(), app_config, noop.NewTracerProvider(), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := gormDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.MigrateInputTables(gormDB))
+	output.Reset()
+
+	return gormDB, &output
+}
+
+// gormRecords parses the log lines of the queries.
+func gormRecords(t *testing.T, output *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+		if record["component"] == "gorm" {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// TestQueriesAreLoggedWithoutTheirValues checks that, at the info GORM level, every query is logged with the run ID
+// of its context and without the values of its statement.
+func TestQueriesAreLoggedWithoutTheirValues(t *testing.T) {
+	gormDB, output := connectLoggedDatabase(t, "logging_info_test", db.LoggingConfig{GormLevel: "info"})
+
+	ctx := logging.WithRunId(context.Background(), "run-1")
+	_, err := NewUserRepository(gormDB).FindUserByEmail(ctx, "secret@example.com")
+	require.NoError(t, err)
+
+	records := gormRecords(t, output)
+	require.NotEmpty(t, records)
+	for _, record := range records {
+		assert.Equal(t, "Query", record["msg"])
+		assert.Equal(t, "INFO", record["level"])
+		assert.Equal(t, "run-1", record["run_id"])
+		assert.NotContains(t, record["sql"], "secret@example.com")
+	}
+}
+
+func TestOnlySlowAndFailedQueriesAreLoggedByDefault(t *testing.T) {
+	gormDB, output := connectLoggedDatabase(t, "logging_warn_test", db.LoggingConfig{})
+	_, err := NewManagerChainRepository(gormDB).FindAll(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, gormRecords(t, output))
+
+	gormDB, output = connectLoggedDatabase(t, "logging_slow_test", db.LoggingConfig{SlowQueryThreshold: time.Nanosecond})
+	_, err = NewManagerChainRepository(gormDB).FindAll(context.Background())
+	require.NoError(t, err)
+	records := gormRecords(t, output)
+	require.NotEmpty(t, records)
+	assert.Equal(t, "Slow query", records[0]["msg"])
+	assert.Equal(t, "WARN", records[0]["level"])
+
+	output.Reset()
+	require.Error(t, gormDB.Exec("SELECT * FROM no_such_table").Error)
+	records = gormRecords(t, output)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Query failed", records[0]["msg"])
+	assert.Equal(t, "ERROR", records[0]["level"])
+}
+
+func TestConnectDatabaseRejectsUnknownGormLevels(t *testing.T) {
+	_, err := db.ConnectDatabase(context.Background(), &db.Config{DatabaseDriver: db.DriverSQLite, DatabaseDSN: "file::memory:", Logging: db.LoggingConfig{GormLevel: "verbose"}}, noop.NewTracerProvider(), slog.Default())
+	assert.ErrorContains(t, err, "verbose")
+}
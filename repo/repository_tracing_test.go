@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// TestQueriesAreTracedUnderTheCallerSpan checks that the queries of ConnectDatabase are children of the span in
+// the context given to the repositories, and that their values are left out of the spans.
+func TestQueriesAreTracedUnderTheCallerSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	gormDB, err := db.ConnectDatabase(context.Background(), &db.Config{DatabaseDriver: db.DriverSQLite, DatabaseDSN: "file:tracing_test?mode=memory&cache=shared"}, provider, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := gormDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.MigrateInputTables(gormDB))
+	exporter.Reset()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	_, err = NewUserRepository(gormDB).FindUserByEmail(ctx, "secret@example.com")
+	require.NoError(t, err)
+	_, err = NewManagerChainRepository(gormDB).FindAll(ctx)
+	require.NoError(t, err)
+	parent.End()
+
+	var queries []string
+	for _, span := range exporter.GetSpans() {
+		if span.Name != "gorm.Query" {
+			continue
+		}
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+
+		for _, attribute := range span.Attributes {
+			if attribute.Key == "db.statement" {
+				queries = append(queries, attribute.Value.AsString())
+			}
+		}
+	}
+
+	require.NotEmpty(t, queries)
+	assert.True(t, strings.Contains(strings.Join(queries, "\n"), "ManagerChains"), queries)
+	for _, query := range queries {
+		assert.NotContains(t, query, "secret@example.com")
+	}
+}
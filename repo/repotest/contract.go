@@ -0,0 +1,545 @@
+// Package repotest provides the contract test suite every repository implementation must pass,
+// whatever the database dialect (or storage) behind it.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+)
+
+// Dataset holds the rows a Backend is seeded with.
+type Dataset struct {
+	Employees      []model.Employee
+	ManagerChains  []model.ManagerChain
+	DirectManagers []model.DirectManager
+	Profiles       []model.Profile
+	Users          []model.User // Profiles are given by ProfileUsers
+	ProfileUsers   []model.ProfileUser
+	LegacyRules    []security_model.GoMatrixRule // Rows of the legacy matrix table
+}
+
+// Backend is a set of repositories sharing the same storage.
+type Backend struct {
+	ManagerChains  interfaces.ManagerChainRepository
+	DirectManagers interfaces.DirectManagerRepository
+	Employees      interfaces.EmployeeRepository
+	Users          interfaces.UserRepository
+	Rules          interfaces.SecurityMatrixRuleRepository
+	LegacyRules    interfaces.LegacyMatrixRuleRepository
+	Runs           interfaces.CalculationRunRepository
+	Health         interfaces.HealthRepository
+	Snapshots      interfaces.SnapshotRepository
+
+	// ReadRules returns every GoMatrixRule currently stored.
+	ReadRules func(t *testing.T) []security_model.GoMatrixRule
+}
+
+// BackendFactory creates a new, isolated Backend seeded with the given dataset.
+type BackendFactory func(t *testing.T, dataset Dataset) Backend
+
+// DefaultDataset returns a small org chart: employee 1 manages 2 and 3, employee 2 manages 4 and 5.
+// Every user holds the EMPLOYEE profile, users of employees 1 and 2 also hold the MANAGER profile,
+// and user USR00000000000000006 is not an employee.
+func DefaultDataset() Dataset {
+	created_at := model.Timestamp{Time: time.Date(2024, 7, 1, 8, 30, 0, 0, time.UTC)}
+
+	dataset := Dataset{
+		DirectManagers: []model.DirectManager{
+			{ManagerId: 1, EmployeeId: 2, EmployeeStatus: true},
+			{ManagerId: 1, EmployeeId: 3, EmployeeStatus: true},
+			{ManagerId: 2, EmployeeId: 4, EmployeeStatus: true},
+			{ManagerId: 2, EmployeeId: 5, EmployeeStatus: false},
+		},
+		ManagerChains: []model.ManagerChain{
+			{ManagerId: 1, EmployeeId: 2, Level: 1, EmployeeStatus: true},
+			{ManagerId: 1, EmployeeId: 3, Level: 1, EmployeeStatus: true},
+			{ManagerId: 2, EmployeeId: 4, Level: 1, EmployeeStatus: true},
+			{ManagerId: 1, EmployeeId: 4, Level: 2, EmployeeStatus: true},
+			{ManagerId: 2, EmployeeId: 5, Level: 1, EmployeeStatus: false},
+			{ManagerId: 1, EmployeeId: 5, Level: 2, EmployeeStatus: false},
+		},
+		Profiles: []model.Profile{
+			{Id: "PRF00000000000000001", ProfileTypeId: "PFT33333333333333333", Name: "Employee", Description: "Employee profile", Level: 1, AccessLevelRead: 1},
+			{Id: "PRF00000000000000002", ProfileTypeId: "PFT22222222222222222", Name: "Manager", Description: "Manager profile", Level: 2, AccessLevelRead: 7},
+		},
+	}
+
+	for employee_id := uint(1); employee_id <= 5; employee_id++ {
+		dataset.Employees = append(dataset.Employees, model.Employee{
+			Id:             employee_id,
+			EmployeeNumber: fmt.Sprintf("E%04d", employee_id),
+			FullName:       fmt.Sprintf("Employee %d", employee_id),
+			IsManager:      employee_id <= 2,
+		})
+	}
+
+	for user_number := uint(1); user_number <= 6; user_number++ {
+		user := model.User{
+			Id:          fmt.Sprintf("USR%017d", user_number),
+			Email:       fmt.Sprintf("user%d@example.com", user_number),
+			FirstName:   "User",
+			LastName:    fmt.Sprint(user_number),
+			CreatedAt:   created_at,
+			LastLoginAt: created_at,
+		}
+		if user_number <= 5 {
+			user.EmployeeId = user_number
+		}
+		dataset.Users = append(dataset.Users, user)
+
+		dataset.ProfileUsers = append(dataset.ProfileUsers, model.ProfileUser{UserID: user.Id, ProfileID: "PRF00000000000000001"})
+		if user_number <= 2 {
+			dataset.ProfileUsers = append(dataset.ProfileUsers, model.ProfileUser{UserID: user.Id, ProfileID: "PRF00000000000000002"})
+		}
+	}
+
+	dataset.LegacyRules = []security_model.GoMatrixRule{
+		{UserId: "USR00000000000000002", EmployeeId: 4, AccessLevelRead: 7},
+		{UserId: "USR00000000000000001", EmployeeId: 3, AccessLevelRead: 7},
+		{UserId: "USR00000000000000001", EmployeeId: 2, AccessLevelRead: 7},
+		{UserId: "USR00000000000000003", EmployeeId: 3, AccessLevelRead: 0},
+	}
+
+	return dataset
+}
+
+// RunContract runs the repository contract test suite against the backends created by newBackend.
+func RunContract(t *testing.T, newBackend BackendFactory) {
+	ctx := context.Background()
+
+	t.Run("ManagerChainRepository/FindAll", func(t *testing.T) {
+		dataset := DefaultDataset()
+		backend := newBackend(t, dataset)
+
+		manager_chains, err := backend.ManagerChains.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, sortedManagerChains(dataset.ManagerChains), sortedManagerChains(manager_chains))
+	})
+
+	t.Run("ManagerChainRepository/ReplaceAll", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+		replacement := []model.ManagerChain{
+			{ManagerId: 3, EmployeeId: 3, Level: 0, EmployeeStatus: true},
+			{ManagerId: 3, EmployeeId: 1, Level: 1, EmployeeStatus: true},
+		}
+
+		require.NoError(t, backend.ManagerChains.ReplaceAll(ctx, replacement))
+
+		manager_chains, err := backend.ManagerChains.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, sortedManagerChains(replacement), sortedManagerChains(manager_chains))
+
+		require.NoError(t, backend.ManagerChains.ReplaceAll(ctx, nil))
+		manager_chains, err = backend.ManagerChains.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, manager_chains)
+	})
+
+	t.Run("DirectManagerRepository/FindAll", func(t *testing.T) {
+		dataset := DefaultDataset()
+		backend := newBackend(t, dataset)
+
+		direct_managers, err := backend.DirectManagers.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, sortedDirectManagers(dataset.DirectManagers), sortedDirectManagers(direct_managers))
+	})
+
+	t.Run("EmployeeRepository/FindAll", func(t *testing.T) {
+		dataset := DefaultDataset()
+		backend := newBackend(t, dataset)
+
+		employees, err := backend.Employees.FindAll(ctx)
+		require.NoError(t, err)
+		sort.Slice(employees, func(i, j int) bool { return employees[i].Id < employees[j].Id })
+		assert.Equal(t, dataset.Employees, employees)
+	})
+
+	t.Run("UserRepository/FetchEmployeesWithAssociatedProfiles", func(t *testing.T) {
+		dataset := DefaultDataset()
+		backend := newBackend(t, dataset)
+
+		users, err := backend.Users.FetchEmployeesWithAssociatedProfiles(ctx)
+		require.NoError(t, err)
+
+		// Only users who are employees, with their profiles preloaded
+		got := make(map[string][]string)
+		for _, user := range users {
+			var profile_ids []string
+			for _, profile := range user.Profiles {
+				profile_ids = append(profile_ids, profile.Id)
+			}
+			sort.Strings(profile_ids)
+			got[user.Id] = profile_ids
+
+			assert.NotZero(t, user.EmployeeId, "user %s is not an employee", user.Id)
+			assert.True(t, user.CreatedAt.Equal(dataset.Users[0].CreatedAt.Time), "user %s CreatedAt: %v", user.Id, user.CreatedAt)
+		}
+
+		assert.Equal(t, map[string][]string{
+			"USR00000000000000001": {"PRF00000000000000001", "PRF00000000000000002"},
+			"USR00000000000000002": {"PRF00000000000000001", "PRF00000000000000002"},
+			"USR00000000000000003": {"PRF00000000000000001"},
+			"USR00000000000000004": {"PRF00000000000000001"},
+			"USR00000000000000005": {"PRF00000000000000001"},
+		}, got)
+
+		for _, user := range users {
+			for _, profile := range user.Profiles {
+				if profile.Id == "PRF00000000000000002" {
+					assert.Equal(t, dataset.Profiles[1].ProfileTypeId, profile.ProfileTypeId)
+					assert.Equal(t, dataset.Profiles[1].Level, profile.Level)
+					assert.Equal(t, dataset.Profiles[1].AccessLevelRead, profile.AccessLevelRead)
+				}
+			}
+		}
+	})
+
+	t.Run("UserRepository/FindUser", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+
+		user, err := backend.Users.FindUserById(ctx, "USR00000000000000002")
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		assert.Equal(t, "user2@example.com", user.Email)
+		assert.Len(t, user.Profiles, 2)
+
+		// Users who are not employees are found too
+		user, err = backend.Users.FindUserByEmail(ctx, "user6@example.com")
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		assert.Equal(t, "USR00000000000000006", user.Id)
+		require.Len(t, user.Profiles, 1)
+		assert.Equal(t, "PRF00000000000000001", user.Profiles[0].Id)
+
+		user, err = backend.Users.FindUserById(ctx, "USR99999999999999999")
+		require.NoError(t, err)
+		assert.Nil(t, user)
+
+		user, err = backend.Users.FindUserByEmail(ctx, "nobody@example.com")
+		require.NoError(t, err)
+		assert.Nil(t, user)
+	})
+
+	t.Run("SecurityMatrixRuleRepository/ReadUserRules", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+		rules := generateRules(1000, 0)
+		require.NoError(t, backend.Rules.WriteRules(ctx, rules))
+
+		var expected []security_model.GoMatrixRule
+		for _, rule := range rules {
+			if rule.UserId == rules[500].UserId {
+				expected = append(expected, rule)
+			}
+		}
+		read, err := backend.Rules.ReadUserRules(ctx, rules[500].UserId)
+		require.NoError(t, err)
+		assert.Equal(t, expected, read)
+
+		read, err = backend.Rules.ReadUserRules(ctx, "USR99999999999999999")
+		require.NoError(t, err)
+		assert.Empty(t, read)
+	})
+
+	t.Run("SecurityMatrixRuleRepository/WriteRules", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+		require.NoError(t, backend.Rules.Migrate())
+
+		first := generateRules(1000, 0)
+		require.NoError(t, backend.Rules.WriteRules(ctx, first))
+		assert.Equal(t, first, sortedRules(backend.ReadRules(t)))
+
+		// Writing replaces the whole matrix
+		second := generateRules(10, 1)
+		require.NoError(t, backend.Rules.WriteRules(ctx, second))
+		assert.Equal(t, second, sortedRules(backend.ReadRules(t)))
+
+		require.NoError(t, backend.Rules.WriteRules(ctx, nil))
+		assert.Empty(t, backend.ReadRules(t))
+	})
+
+	t.Run("SecurityMatrixRuleRepository/WriteRulesStream", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+		rules := generateRules(1000, 0)
+
+		require.NoError(t, backend.Rules.WriteRulesStream(ctx, streamRules(rules, 7)))
+		assert.Equal(t, rules, sortedRules(backend.ReadRules(t)))
+	})
+
+	t.Run("SecurityMatrixRuleRepository/WriteRulesStreamKeepsTheMatrixReadable", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+		previous := generateRules(10, 1)
+		require.NoError(t, backend.Rules.WriteRules(ctx, previous))
+
+		rules_channel := make(chan []security_model.GoMatrixRule)
+		close_stream := sync.OnceFunc(func() { close(rules_channel) })
+		defer close_stream()
+		done := make(chan error, 1)
+		go func() { done <- backend.Rules.WriteRulesStream(ctx, rules_channel) }()
+
+		// The previous matrix is served while the stream is being written
+		rules := generateRules(1000, 0)
+		rules_channel <- rules[:500]
+		read_done := make(chan []security_model.GoMatrixRule, 1)
+		go func() {
+			read, err := backend.Rules.ReadUserRules(ctx, previous[0].UserId)
+			assert.NoError(t, err)
+			read_done <- read
+		}()
+		select {
+		case read := <-read_done:
+			assert.Equal(t, previous, read)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the matrix is locked while the stream is written")
+		}
+
+		rules_channel <- rules[500:]
+		close_stream()
+		require.NoError(t, <-done)
+		assert.Equal(t, rules, sortedRules(backend.ReadRules(t)))
+	})
+
+	t.Run("SecurityMatrixRuleRepository/ReadRules", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+		rules := generateRules(1000, 0)
+		require.NoError(t, backend.Rules.WriteRules(ctx, rules))
+
+		// Batches are handed in order and never exceed the batch size
+		var read []security_model.GoMatrixRule
+		require.NoError(t, backend.Rules.ReadRules(ctx, 300, func(batch []security_model.GoMatrixRule) error {
+			assert.LessOrEqual(t, len(batch), 300)
+			read = append(read, batch...)
+			return nil
+		}))
+		assert.Equal(t, rules, read)
+
+		// Reading stops at the first error of handle
+		stop := fmt.Errorf("stop")
+		batches := 0
+		assert.ErrorIs(t, backend.Rules.ReadRules(ctx, 300, func(batch []security_model.GoMatrixRule) error {
+			batches++
+			return stop
+		}), stop)
+		assert.Equal(t, 1, batches)
+	})
+
+	t.Run("SecurityMatrixRuleRepository/ReadEnrichedRules", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+		rules := []security_model.GoMatrixRule{
+			{UserId: "USR00000000000000001", EmployeeId: 2, AccessLevelRead: 7},
+			{UserId: "USR00000000000000001", EmployeeId: 99, AccessLevelRead: 2},
+			{UserId: "USR00000000000000006", EmployeeId: 3, AccessLevelRead: 1},
+			{UserId: "USR99999999999999999", EmployeeId: 1, AccessLevelRead: 0},
+		}
+		require.NoError(t, backend.Rules.WriteRules(ctx, rules))
+
+		// Unknown users and employees are kept, without email or employee number
+		var read []security_model.EnrichedMatrixRule
+		require.NoError(t, backend.Rules.ReadEnrichedRules(ctx, 3, func(batch []security_model.EnrichedMatrixRule) error {
+			assert.LessOrEqual(t, len(batch), 3)
+			read = append(read, batch...)
+			return nil
+		}))
+		assert.Equal(t, []security_model.EnrichedMatrixRule{
+			{GoMatrixRule: rules[0], UserEmail: "user1@example.com", EmployeeNumber: "E0002"},
+			{GoMatrixRule: rules[1], UserEmail: "user1@example.com"},
+			{GoMatrixRule: rules[2], UserEmail: "user6@example.com", EmployeeNumber: "E0003"},
+			{GoMatrixRule: rules[3], EmployeeNumber: "E0001"},
+		}, read)
+	})
+
+	t.Run("LegacyMatrixRuleRepository/ReadRules", func(t *testing.T) {
+		dataset := DefaultDataset()
+		backend := newBackend(t, dataset)
+
+		var read []security_model.GoMatrixRule
+		require.NoError(t, backend.LegacyRules.ReadRules(ctx, 3, func(batch []security_model.GoMatrixRule) error {
+			assert.LessOrEqual(t, len(batch), 3)
+			read = append(read, batch...)
+			return nil
+		}))
+		assert.Equal(t, sortedRules(dataset.LegacyRules), read)
+		assert.NotEmpty(t, backend.LegacyRules.Table())
+	})
+
+	t.Run("CalculationRunRepository/LastRun", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+
+		last_run, err := backend.Runs.LastRun(ctx)
+		require.NoError(t, err)
+		assert.Nil(t, last_run, "never calculated")
+
+		started_at := time.Date(2024, 7, 2, 3, 0, 0, 0, time.UTC)
+		runs := []*security_model.GoMatrixCalculationRun{
+			{StartedAt: model.Timestamp{Time: started_at}, FinishedAt: model.Timestamp{Time: started_at.Add(time.Minute)}, Rules: 1000, SnapshotAt: &model.Timestamp{Time: started_at.Add(time.Second)}},
+			{StartedAt: model.Timestamp{Time: started_at.Add(-time.Hour)}, FinishedAt: model.Timestamp{Time: started_at.Add(-50 * time.Minute)}, Rules: 10},
+		}
+		for _, run := range runs {
+			require.NoError(t, backend.Runs.RecordRun(ctx, run))
+			assert.NotZero(t, run.Id)
+		}
+		assert.NotEqual(t, runs[0].Id, runs[1].Id)
+
+		// The run that finished last, whatever the order they were recorded in
+		last_run, err = backend.Runs.LastRun(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, last_run)
+		assert.Equal(t, runs[0].Id, last_run.Id)
+		assert.Equal(t, int64(1000), last_run.Rules)
+		assert.True(t, last_run.StartedAt.Equal(started_at), "StartedAt: %v", last_run.StartedAt)
+		assert.True(t, last_run.FinishedAt.Equal(started_at.Add(time.Minute)), "FinishedAt: %v", last_run.FinishedAt)
+		assert.Equal(t, security_model.RunStatusSucceeded, last_run.Status, "default status")
+		require.NotNil(t, last_run.SnapshotAt)
+		assert.True(t, last_run.SnapshotAt.Equal(started_at.Add(time.Second)), "SnapshotAt: %v", last_run.SnapshotAt)
+
+		// Interrupted runs are recorded, but the matrix they left is the one of the last succeeded run
+		interrupted := &security_model.GoMatrixCalculationRun{
+			RunId:      "interrupted-run",
+			Status:     security_model.RunStatusInterrupted,
+			StartedAt:  model.Timestamp{Time: started_at.Add(time.Hour)},
+			FinishedAt: model.Timestamp{Time: started_at.Add(2 * time.Hour)},
+		}
+		require.NoError(t, backend.Runs.RecordRun(ctx, interrupted))
+		assert.NotZero(t, interrupted.Id)
+
+		last_run, err = backend.Runs.LastRun(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, last_run)
+		assert.Equal(t, runs[0].Id, last_run.Id)
+	})
+
+	t.Run("SnapshotRepository/ReadSnapshot", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+
+		// Every read sees the seeded rows
+		var manager_chains []model.ManagerChain
+		var users []*model.User
+		// Read from the clock of the database, a second apart from the clock of the test at most
+		before := time.Now().UTC().Add(-time.Second)
+		snapshot_at, err := backend.Snapshots.ReadSnapshot(ctx, func(ctx context.Context) (err error) {
+			if manager_chains, err = backend.ManagerChains.FindAll(ctx); err != nil {
+				return err
+			}
+			users, err = backend.Users.FetchEmployeesWithAssociatedProfiles(ctx)
+			return err
+		})
+		require.NoError(t, err)
+		assert.Len(t, manager_chains, len(DefaultDataset().ManagerChains))
+		assert.Len(t, users, 5)
+		assert.False(t, snapshot_at.Before(before) || snapshot_at.After(time.Now().UTC().Add(time.Second)), "snapshot at %v", snapshot_at)
+
+		// The error of the reads is returned, and the repositories remain usable after it
+		read_err := fmt.Errorf("read failed")
+		_, err = backend.Snapshots.ReadSnapshot(ctx, func(ctx context.Context) error {
+			if _, err := backend.Employees.FindAll(ctx); err != nil {
+				return err
+			}
+			return read_err
+		})
+		assert.ErrorIs(t, err, read_err)
+
+		employees, err := backend.Employees.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, employees, 5)
+	})
+
+	t.Run("HealthRepository", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+
+		assert.NoError(t, backend.Health.Ping(ctx))
+		assert.NoError(t, backend.Health.CheckMigrations(ctx))
+	})
+
+	t.Run("SecurityMatrixRuleRepository/WriteRulesStreamCancelled", func(t *testing.T) {
+		backend := newBackend(t, DefaultDataset())
+		previous := generateRules(10, 1)
+		require.NoError(t, backend.Rules.WriteRules(ctx, previous))
+
+		// A cancelled stream must never leave a partial matrix behind
+		cancelled_ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.Error(t, backend.Rules.WriteRulesStream(cancelled_ctx, streamRules(generateRules(100, 0), 7)))
+		assert.Equal(t, previous, sortedRules(backend.ReadRules(t)))
+	})
+}
+
+// generateRules deterministically generates size distinct, sorted rules.
+func generateRules(size int, variant uint32) []security_model.GoMatrixRule {
+	rules := make([]security_model.GoMatrixRule, 0, size)
+	for i := 0; i < size; i++ {
+		rules = append(rules, security_model.GoMatrixRule{
+			UserId:          fmt.Sprintf("USR%017d", i/100),
+			EmployeeId:      uint(i%100) + 1,
+			AccessLevelRead: uint32(i%7) + 1 + variant,
+		})
+	}
+
+	return rules
+}
+
+// streamRules sends the rules in chunks of the given size to a closed channel.
+func streamRules(rules []security_model.GoMatrixRule, chunk_size int) <-chan []security_model.GoMatrixRule {
+	rules_channel := make(chan []security_model.GoMatrixRule, len(rules)/chunk_size+1)
+	for i := 0; i < len(rules); i += chunk_size {
+		end := i + chunk_size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		rules_channel <- rules[i:end]
+	}
+	close(rules_channel)
+
+	return rules_channel
+}
+
+func sortedRules(rules []security_model.GoMatrixRule) []security_model.GoMatrixRule {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].UserId != rules[j].UserId {
+			return rules[i].UserId < rules[j].UserId
+		}
+		return rules[i].EmployeeId < rules[j].EmployeeId
+	})
+
+	return rules
+}
+
+// sortedManagerChains sorts a copy of the manager chains, ignoring their generated Id.
+func sortedManagerChains(manager_chains []model.ManagerChain) []model.ManagerChain {
+	sorted := make([]model.ManagerChain, len(manager_chains))
+	for i, manager_chain := range manager_chains {
+		manager_chain.Id = 0
+		sorted[i] = manager_chain
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].EmployeeId != sorted[j].EmployeeId {
+			return sorted[i].EmployeeId < sorted[j].EmployeeId
+		}
+		return sorted[i].Level < sorted[j].Level
+	})
+
+	return sorted
+}
+
+// sortedDirectManagers sorts a copy of the direct managers, ignoring their generated Id.
+func sortedDirectManagers(direct_managers []model.DirectManager) []model.DirectManager {
+	sorted := make([]model.DirectManager, len(direct_managers))
+	for i, direct_manager := range direct_managers {
+		direct_manager.Id = 0
+		sorted[i] = direct_manager
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].EmployeeId < sorted[j].EmployeeId
+	})
+
+	return sorted
+}
@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"database/sql"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+
+	"gorm.io/gorm"
+)
+
+// bulkCopyRulesInserter inserts rules through a SQL Server bulk copy (TDS bulk insert).
+// Rows are streamed to the server by the driver, without the 2100 parameters per statement
+// limit that caps batched INSERT statements.
+type bulkCopyRulesInserter struct {
+	tx   *gorm.DB
+	stmt *sql.Stmt
+}
+
+func newBulkCopyRulesInserter(tx *gorm.DB) rulesInserter {
+	return &bulkCopyRulesInserter{tx: tx}
+}
+
+// Insert sends the rules to the bulk copy, opening it on the first call.
+//
+// Parameters:
+// - rules: a slice of GoMatrixRule to be written to the database.
+//
+// Returns:
+// - error: an error object if the operation fails, nil otherwise.
+func (p *bulkCopyRulesInserter) Insert(rules []security_model.GoMatrixRule) error {
+	ctx := p.tx.Statement.Context
+
+	if p.stmt == nil {
+		// Resolve the table name the same way GORM does for the batched insert path, the staging table when set
+		table_statement := &gorm.Statement{DB: p.tx, Table: p.tx.Statement.Table}
+		if err := table_statement.Parse(&security_model.GoMatrixRule{}); err != nil {
+			return err
+		}
+
+		query := mssql.CopyIn(table_statement.Table, mssql.BulkOptions{Tablock: true}, "UserId", "EmployeeId", "AccessLevelRead")
+		stmt, err := p.tx.Statement.ConnPool.PrepareContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		p.stmt = stmt
+	}
+
+	for _, rule := range rules {
+		if _, err := p.stmt.ExecContext(ctx, rule.UserId, int64(rule.EmployeeId), int64(rule.AccessLevelRead)); err != nil {
+			p.stmt.Close()
+			p.stmt = nil
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush completes the bulk copy, sending the remaining rows to the server.
+//
+// Returns:
+// - error: an error object if the operation fails, nil otherwise.
+func (p *bulkCopyRulesInserter) Flush() error {
+	if p.stmt == nil {
+		return nil
+	}
+	defer func() {
+		p.stmt.Close()
+		p.stmt = nil
+	}()
+
+	// Executing the statement without arguments ends the bulk copy
+	_, err := p.stmt.ExecContext(p.tx.Statement.Context)
+	return err
+}
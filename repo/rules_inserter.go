@@ -0,0 +1,104 @@
+package repo
+
+import (
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+
+	"gorm.io/gorm"
+)
+
+// rulesInserter inserts GoMatrixRule entries within a transaction.
+// Insert may buffer rules, Flush must be called once every rule has been passed to Insert.
+type rulesInserter interface {
+	Insert(rules []security_model.GoMatrixRule) error
+	Flush() error
+}
+
+const defaultBatchSize = 156 /*
+* The batch size determines the number of rule entries in each transaction batch creation iteration.
+* Performance varies with different values (e.g., sample values for my machine):
+*
+* - Low values (e.g., 1-50) result in slower processing speed
+*
+* - Moderate values (e.g., 100-200) achieve optimal performance, processing
+*   at approximately twice the speed or half the time compared to lower values.
+*
+* - Higher values (e.g., 400+, max 700 due to local SQL server limit) result
+*   in longer processing times, around the same as higher low values.
+*
+*
+*   Adjust according to performance :)
+*	In this case, 156 was determined the optimal batch size from a Bayesian Optimization analysis.
+*	It can be overridden with calculation.batch_size, cmd/autotune searches the best value for a machine.
+*
+ */
+
+// batchedRulesInserter inserts rules with multi-row INSERT statements of batchSize rows.
+// Rules are buffered across Insert calls so that small slices (e.g. one user's rules) still
+// produce full batches.
+type batchedRulesInserter struct {
+	tx        *gorm.DB
+	batchSize int
+	pending   []security_model.GoMatrixRule
+}
+
+func newBatchedRulesInserter(tx *gorm.DB, batchSize int) rulesInserter {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return &batchedRulesInserter{tx: tx, batchSize: batchSize}
+}
+
+// Insert inserts every full batch available and buffers the remaining rules.
+//
+// Parameters:
+// - rules: a slice of GoMatrixRule to be written to the database.
+//
+// Returns:
+// - error: an error object if the operation fails, nil otherwise.
+func (p *batchedRulesInserter) Insert(rules []security_model.GoMatrixRule) error {
+	// Complete the pending batch first
+	if len(p.pending) > 0 {
+		missing := p.batchSize - len(p.pending)
+		if missing > len(rules) {
+			missing = len(rules)
+		}
+		p.pending = append(p.pending, rules[:missing]...)
+		rules = rules[missing:]
+
+		if len(p.pending) < p.batchSize {
+			return nil
+		}
+		if err := p.tx.CreateInBatches(p.pending, p.batchSize).Error; err != nil {
+			return err
+		}
+		p.pending = p.pending[:0]
+	}
+
+	// Process the rules in batches to optimize performance and reduce memory usage.
+	for len(rules) >= p.batchSize {
+		batch := rules[:p.batchSize] // Extract the current batch from the rules slice.
+
+		// Insert the current batch into the database using CreateInBatches.
+		if err := p.tx.CreateInBatches(batch, len(batch)).Error; err != nil {
+			return err
+		}
+		rules = rules[p.batchSize:]
+	}
+
+	p.pending = append(p.pending, rules...)
+	return nil
+}
+
+// Flush inserts the buffered rules.
+//
+// Returns:
+// - error: an error object if the operation fails, nil otherwise.
+func (p *batchedRulesInserter) Flush() error {
+	if len(p.pending) == 0 {
+		return nil
+	}
+
+	err := p.tx.CreateInBatches(p.pending, len(p.pending)).Error
+	p.pending = p.pending[:0]
+	return err
+}
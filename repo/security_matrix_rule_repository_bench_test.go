@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"testing"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/generator"
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	filters "github.com/nuno-bastos/gin-gonic-wire-api/service/filters"
+
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// BenchmarkWriteRules compares the bulk copy and batched insert write paths on a generated dataset. It runs on
+// an in-memory SQLite database, or on the database of BENCHMARK_DATABASE_DRIVER and BENCHMARK_DATABASE_DSN when
+// set; the bulk copy needs SQL Server and is skipped elsewhere:
+//
+//	BENCHMARK_DATABASE_DSN="sqlserver://..." go test ./repo -run '^$' -bench 'WriteRules$' -benchtime 3x
+func BenchmarkWriteRules(b *testing.B) {
+	gormDB := openBenchmarkDatabase(b)
+
+	for _, size := range []int{10_000, 100_000, 1_000_000} {
+		rules := generateRules(size)
+
+		for _, mode := range []string{RuleWriteModeBatchedInsert, RuleWriteModeBulkCopy} {
+			b.Run(fmt.Sprintf("%s/%d", mode, size), func(b *testing.B) {
+				repo, err := NewSecurityMatrixRuleRepository(gormDB, &db.Config{RuleWriteMode: mode})
+				if err != nil {
+					b.Skip(err)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := repo.WriteRules(context.Background(), rules); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(size*b.N)/b.Elapsed().Seconds(), "rules/s")
+			})
+		}
+	}
+}
+
+// openBenchmarkDatabase opens the database of BENCHMARK_DATABASE_DRIVER and BENCHMARK_DATABASE_DSN (SQL Server
+// by default), an in-memory SQLite database when BENCHMARK_DATABASE_DSN is not set.
+func openBenchmarkDatabase(b *testing.B) *gorm.DB {
+	driver, dsn := os.Getenv("BENCHMARK_DATABASE_DRIVER"), os.Getenv("BENCHMARK_DATABASE_DSN")
+	if dsn == "" {
+		driver, dsn = db.DriverSQLite, "file:benchmark?mode=memory&cache=shared"
+	}
+
+	dialector, err := db.OpenDialector(driver, dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		NamingStrategy: db.NewNamingStrategy(dialector.Name()),
+	})
+	if err != nil {
+		b.Fatalf("failed to connect database: %v", err)
+	}
+
+	return gormDB
+}
+
+// generateRules deterministically generates size distinct GoMatrixRule entries, spread over
+// viewer users the way a real matrix is (a few hundred employees per user).
+func generateRules(size int) []security_model.GoMatrixRule {
+	random := rand.New(rand.NewSource(42))
+	access_levels := []uint32{uint32(security_model.GenericFields), uint32(security_model.All), security_model.DENY_ACCESS_LEVEL_READ}
+
+	rules := make([]security_model.GoMatrixRule, 0, size)
+	for i := 0; i < size; i++ {
+		rules = append(rules, security_model.GoMatrixRule{
+			UserId:          fmt.Sprintf("USR%017d", i/500),
+			EmployeeId:      uint(i%500) + 1,
+			AccessLevelRead: access_levels[random.Intn(len(access_levels))],
+		})
+	}
+
+	return rules
+}
+
+// BenchmarkWriteRulesGeneratedOrg writes the matrix calculated for a generated organization with the batched
+// insert path, for several batch sizes. It runs on an in-memory SQLite database, or on the database of
+// BENCHMARK_DATABASE_DRIVER and BENCHMARK_DATABASE_DSN when set.
+func BenchmarkWriteRulesGeneratedOrg(b *testing.B) {
+	gormDB := openBenchmarkDatabase(b)
+
+	options := generator.DefaultOptions()
+	options.Employees = 1_000
+	organization, err := generator.Generate(options)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rules := calculateGeneratedRules(b, organization)
+
+	for _, batch_size := range []int{50, 156, 500} {
+		b.Run(fmt.Sprintf("batch_size=%d", batch_size), func(b *testing.B) {
+			config := &db.Config{RuleWriteMode: RuleWriteModeBatchedInsert}
+			config.Calculation.BatchSize = batch_size
+			repo, err := NewSecurityMatrixRuleRepository(gormDB, config)
+			if err != nil {
+				b.Skip(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := repo.WriteRules(context.Background(), rules); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(rules)*b.N)/b.Elapsed().Seconds(), "rules/s")
+		})
+	}
+}
+
+// calculateGeneratedRules calculates the matrix of the organization with the production filters.
+func calculateGeneratedRules(b *testing.B, organization *generator.Organization) []security_model.GoMatrixRule {
+	store := memory.NewStore(organization.Data())
+	calculator := service.NewSecurityMatrixCalculatorService(
+		memory.NewManagerChainRepository(store),
+		memory.NewDirectManagerRepository(store),
+		memory.NewUserRepository(store),
+		memory.NewEmployeeRepository(store),
+		memory.NewSecurityMatrixRuleRepository(store),
+		memory.NewCalculationRunRepository(store),
+		memory.NewSnapshotRepository(store),
+		filters.ProvideManagerFilters(filters.NewAllowReportsFilter(), filters.NewDenyManagersFilter(), filters.NewDenyProfileLevelsFilter()),
+		filters.ProvideEmployeeFilters(filters.NewAllowSelfFilter(), filters.NewDenySelfFilter()),
+		service.ManagerChainOptions{},
+		service.DataQualityOptions{},
+		service.NewPipelineOptions(&db.Config{}),
+		service.ProfileTypeId,
+		service.RetryOptions{},
+		service.TimeoutOptions{},
+		service.FilterFailureOptions{},
+		metrics.NewMetrics(),
+		noop.NewTracerProvider(),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	if err := calculator.CalculateGoSecurityMatrix(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+
+	return store.Rules()
+}
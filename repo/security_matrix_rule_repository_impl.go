@@ -2,19 +2,66 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"strings"
 
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
 	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
 	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Supported values of the rule_write_mode configuration.
+const (
+	RuleWriteModeAuto          = "auto"           // bulk copy on SQL Server, batched inserts otherwise
+	RuleWriteModeBulkCopy      = "bulk_copy"      // SQL Server bulk copy (TDS bulk insert)
+	RuleWriteModeBatchedInsert = "batched_insert" // multi-row INSERT statements
+)
+
+// Prefixes of the tables swapped by replaceRules, suffixed by an id unique to each write.
+const (
+	ruleStagingTable = "GoMatrixRuleStaging" // the new rules, renamed to GoMatrixRule once complete
+	ruleRetiredTable = "GoMatrixRuleRetired" // the previous rules, dropped by the swap
 )
 
 type securityMatrixRuleRepository struct {
-	DB *gorm.DB
+	DB          *gorm.DB
+	newInserter func(tx *gorm.DB) rulesInserter
 }
 
-func NewSecurityMatrixRuleRepository(DB *gorm.DB) (interfaces.SecurityMatrixRuleRepository, error) {
+// NewSecurityMatrixRuleRepository creates the SecurityMatrixRuleRepository and migrates the GoMatrixRule table.
+// The way rules are inserted is selected by the rule_write_mode configuration: SQL Server bulk copy or batched
+// INSERT statements of calculation.batch_size rows, the latter being the fallback for other dialects.
+func NewSecurityMatrixRuleRepository(DB *gorm.DB, config *db.Config) (interfaces.SecurityMatrixRuleRepository, error) {
+	// SQL Server caps a statement at 2100 parameters, GoMatrixRule has 3 columns
+	if DB.Dialector.Name() == "sqlserver" && config.Calculation.BatchSize*3 > 2100 {
+		return nil, fmt.Errorf("calculation.batch_size %d exceeds the SQL Server limit of 700 rows per statement", config.Calculation.BatchSize)
+	}
+
 	repo := &securityMatrixRuleRepository{DB: DB}
+	repo.newInserter = func(tx *gorm.DB) rulesInserter {
+		return newBatchedRulesInserter(tx, config.Calculation.BatchSize)
+	}
+
+	switch config.RuleWriteMode {
+	case "", RuleWriteModeAuto:
+		if DB.Dialector.Name() == "sqlserver" {
+			repo.newInserter = newBulkCopyRulesInserter
+		}
+	case RuleWriteModeBulkCopy:
+		if DB.Dialector.Name() != "sqlserver" {
+			return nil, fmt.Errorf("rule_write_mode %q is only supported on sqlserver, not %s", config.RuleWriteMode, DB.Dialector.Name())
+		}
+		repo.newInserter = newBulkCopyRulesInserter
+	case RuleWriteModeBatchedInsert:
+	default:
+		return nil, fmt.Errorf("unknown rule_write_mode %q", config.RuleWriteMode)
+	}
 
 	// Perform the migration
 	if err := repo.Migrate(); err != nil {
@@ -32,8 +79,9 @@ func (p *securityMatrixRuleRepository) Migrate() error {
 	return p.DB.AutoMigrate(&security_model.GoMatrixRule{})
 }
 
-// WriteRules writes a batch of GoMatrixRule entries to the database. It deletes
-// existing records before inserting new ones.
+// WriteRules writes a batch of GoMatrixRule entries to the database, replacing the existing records.
+// The rules are first inserted into a staging table, the matrix is only locked by the transaction swapping
+// the staging table in (see replaceRules).
 //
 // Parameters:
 // - ctx: context for managing request lifecycle.
@@ -42,50 +90,238 @@ func (p *securityMatrixRuleRepository) Migrate() error {
 // Returns:
 // - error: an error object if the operation fails, nil otherwise.
 func (p *securityMatrixRuleRepository) WriteRules(ctx context.Context, rules []security_model.GoMatrixRule) error {
-	tx := p.DB.WithContext(ctx).Begin()
-	if tx.Error != nil {
-		return tx.Error
+	return p.replaceRules(ctx, func(inserter rulesInserter) error {
+		return inserter.Insert(rules)
+	})
+}
+
+// WriteRulesStream writes the GoMatrixRule entries received from a channel to the database, replacing the
+// existing records. The rules are inserted into a staging table as they are received, so at most one received
+// slice of rules is held by the repository at any time, and the matrix stays readable until the channel is
+// closed: only then is the staging table swapped in (see replaceRules).
+//
+// Parameters:
+// - ctx: context for managing request lifecycle. When it is cancelled the matrix is left untouched,
+// even if the channel was closed normally, since the producer may have stopped early.
+// - rules_channel: a channel of GoMatrixRule slices, closed by the producer once every rule has been sent.
+//
+// Returns:
+// - error: an error object if the operation fails, nil otherwise.
+func (p *securityMatrixRuleRepository) WriteRulesStream(ctx context.Context, rules_channel <-chan []security_model.GoMatrixRule) error {
+	return p.replaceRules(ctx, func(inserter rulesInserter) error {
+		for rules := range rules_channel {
+			if err := inserter.Insert(rules); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// replaceRules replaces the GoMatrixRule records with the rules inserted by fill. They are inserted into a staging
+// table created for this write with the layout of GoMatrixRule, which is then swapped in by renaming both tables
+// and dropping the previous one in a single transaction. The swap only changes metadata, so its locks do not grow
+// with the size of the matrix, and readers are neither blocked nor shown an empty matrix while the rules are
+// produced. Every write has its own staging table: concurrent writes do not interfere, the last one to swap wins.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle. When it is cancelled the matrix is left untouched.
+// - fill: inserts the new rules, it must not flush the inserter.
+//
+// Returns:
+// - error: an error object if the operation fails, nil otherwise.
+func (p *securityMatrixRuleRepository) replaceRules(ctx context.Context, fill func(inserter rulesInserter) error) error {
+	statement := &gorm.Statement{DB: p.DB}
+	if err := statement.Parse(&security_model.GoMatrixRule{}); err != nil {
+		return err
+	}
+	suffix := strings.ReplaceAll(uuid.NewString(), "-", "")
+	table, staging, retired := statement.Schema.Table, ruleStagingTable+suffix, ruleRetiredTable+suffix
+
+	conn := p.DB.WithContext(ctx)
+	if err := conn.Table(staging).Migrator().CreateTable(&security_model.GoMatrixRule{}); err != nil {
+		return err
 	}
+	defer func() {
+		// Left over when the write failed or was cancelled, gone once swapped in
+		p.DB.WithContext(context.WithoutCancel(ctx)).Migrator().DropTable(staging)
+	}()
+
+	// Stage the rules, the matrix stays readable meanwhile
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		inserter := p.newInserter(tx.Table(staging).Session(&gorm.Session{}))
+		if err := fill(inserter); err != nil {
+			return err
+		}
+		return inserter.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	// The producer closes the channel early when cancelled, never commit a partial matrix
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Swap the staged rules in, sp_rename on SQL Server, ALTER TABLE ... RENAME TO otherwise
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().RenameTable(table, retired); err != nil {
+			return err
+		}
+		if err := tx.Migrator().RenameTable(staging, table); err != nil {
+			return err
+		}
+
+		return tx.Migrator().DropTable(retired)
+	})
+}
 
-	// Delete existing records
-	if err := tx.Exec("DELETE FROM dbo.GoMatrixRule").Error; err != nil {
-		tx.Rollback()
+// ReadRules reads every GoMatrixRule entry from the database, ordered by UserId and EmployeeId,
+// and hands them to handle in batches, so the matrix is never held in memory as a whole.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - batch_size: the maximum number of rules handed to handle at once.
+// - handle: called with each batch of rules; reading stops at the first error it returns.
+//
+// Returns:
+// - error: an error object if the read or handle fails, nil otherwise.
+func (p *securityMatrixRuleRepository) ReadRules(ctx context.Context, batch_size int, handle func(rules []security_model.GoMatrixRule) error) error {
+	rows, err := orderedRules(connection(ctx, p.DB)).Rows()
+	if err != nil {
 		return err
 	}
+	defer rows.Close()
+
+	return readInBatches(rows, batch_size, func(rows *sql.Rows) (rule security_model.GoMatrixRule, err error) {
+		err = rows.Scan(&rule.UserId, &rule.EmployeeId, &rule.AccessLevelRead)
+		return rule, err
+	}, handle)
+}
+
+// orderedRules selects the columns of the GoMatrixRule entries, ordered by UserId and EmployeeId. The columns are
+// quoted, as PostgreSQL folds the unquoted names to lower case.
+//
+// Parameters:
+// - conn: the connection the query runs on.
+//
+// Returns:
+// - *gorm.DB: the query.
+func orderedRules(conn *gorm.DB) *gorm.DB {
+	return conn.
+		Model(&security_model.GoMatrixRule{}).
+		Select("UserId", "EmployeeId", "AccessLevelRead").
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Name: "UserId"}},
+			{Column: clause.Column{Name: "EmployeeId"}},
+		}})
+}
+
+// ReadUserRules reads the GoMatrixRule entries of one viewer user, ordered by EmployeeId.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - user_id: the UserId of the viewer user.
+//
+// Returns:
+// - []security_model.GoMatrixRule: the rules of the user, empty when there is none.
+// - error: an error object if the read fails, nil otherwise.
+func (p *securityMatrixRuleRepository) ReadUserRules(ctx context.Context, user_id string) ([]security_model.GoMatrixRule, error) {
+	rows, err := orderedRules(connection(ctx, p.DB)).
+		Where(clause.Eq{Column: clause.Column{Name: "UserId"}, Value: user_id}).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []security_model.GoMatrixRule{}
+	for rows.Next() {
+		var rule security_model.GoMatrixRule
+		if err := rows.Scan(&rule.UserId, &rule.EmployeeId, &rule.AccessLevelRead); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
 
-	batchSize := 156 /*
-	* The batchSize variable determines the number of rule entries in each transaction batch creation iteration.
-	* Performance varies with different values (e.g., sample values for my machine):
-	*
-	* - Low values (e.g., 1-50) result in slower processing speed
-	*
-	* - Moderate values (e.g., 100-200) achieve optimal performance, processing
-	*   at approximately twice the speed or half the time compared to lower values.
-	*
-	* - Higher values (e.g., 400+, max 700 due to local SQL server limit) result
-	*   in longer processing times, around the same as higher low values.
-	*
-	*
-	*   Adjust according to performance :)
-	*	In this case, 156 was determined the optimal batch size from a Bayesian Optimization analysis.
-	*
-	 */
-
-	// Process the rules in batches to optimize performance and reduce memory usage.
-	// The batch size is determined by the batchSize variable.
-	for i := 0; i < len(rules); i += batchSize {
-		end := i + batchSize // Calculate the end index for the current batch.
-		if end > len(rules) {
-			end = len(rules) // Ensure the end index does not exceed the total number of rules.
+// ReadEnrichedRules reads every GoMatrixRule entry like ReadRules, joined with the email of its user
+// from Security.Users and the number of its employee from Core.Employees.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - batch_size: the maximum number of rules handed to handle at once.
+// - handle: called with each batch of rules; reading stops at the first error it returns.
+//
+// Returns:
+// - error: an error object if the read or handle fails, nil otherwise.
+func (p *securityMatrixRuleRepository) ReadEnrichedRules(ctx context.Context, batch_size int, handle func(rules []security_model.EnrichedMatrixRule) error) error {
+	tables := make([]string, 3)
+	for i, value := range []interface{}{&security_model.GoMatrixRule{}, &model.User{}, &model.Employee{}} {
+		statement := &gorm.Statement{DB: p.DB}
+		if err := statement.Parse(value); err != nil {
+			return err
 		}
-		batch := rules[i:end] // Extract the current batch from the rules slice.
+		tables[i] = statement.Schema.Table
+	}
 
-		// Insert the current batch into the database using CreateInBatches.
-		if err := tx.CreateInBatches(batch, len(batch)).Error; err != nil {
-			tx.Rollback() // Rollback the transaction if an error occurs.
+	rows, err := connection(ctx, p.DB).
+		Table("?", clause.Table{Name: tables[0], Alias: "r"}).
+		Select("?, ?, ?, ?, ?",
+			clause.Column{Table: "r", Name: "UserId"}, clause.Column{Table: "r", Name: "EmployeeId"}, clause.Column{Table: "r", Name: "AccessLevelRead"},
+			clause.Column{Table: "u", Name: "Email"}, clause.Column{Table: "e", Name: "EmployeeNumber"}).
+		Joins("LEFT JOIN ? ON ? = ?", clause.Table{Name: tables[1], Alias: "u"}, clause.Column{Table: "u", Name: "Id"}, clause.Column{Table: "r", Name: "UserId"}).
+		Joins("LEFT JOIN ? ON ? = ?", clause.Table{Name: tables[2], Alias: "e"}, clause.Column{Table: "e", Name: "Id"}, clause.Column{Table: "r", Name: "EmployeeId"}).
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Table: "r", Name: "UserId"}},
+			{Column: clause.Column{Table: "r", Name: "EmployeeId"}},
+		}}).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return readInBatches(rows, batch_size, func(rows *sql.Rows) (rule security_model.EnrichedMatrixRule, err error) {
+		var email, employee_number sql.NullString
+		err = rows.Scan(&rule.UserId, &rule.EmployeeId, &rule.AccessLevelRead, &email, &employee_number)
+		rule.UserEmail, rule.EmployeeNumber = email.String, employee_number.String
+		return rule, err
+	}, handle)
+}
+
+// readInBatches scans the rows and hands them to handle in batches of batch_size (defaultBatchSize when not positive).
+func readInBatches[T any](rows *sql.Rows, batch_size int, scan func(rows *sql.Rows) (T, error), handle func(batch []T) error) error {
+	if batch_size <= 0 {
+		batch_size = defaultBatchSize
+	}
+
+	batch := make([]T, 0, batch_size)
+	for rows.Next() {
+		value, err := scan(rows)
+		if err != nil {
 			return err
 		}
+
+		batch = append(batch, value)
+		if len(batch) == batch_size {
+			if err := handle(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(batch) > 0 {
+		return handle(batch)
 	}
 
-	return tx.Commit().Error
+	return nil
 }
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/repotest"
+)
+
+// TestOrderedRulesQuotesTheColumns renders the query of ReadRules and ReadUserRules for PostgreSQL, which folds the
+// unquoted column names to lower case. The contract suite only runs there when TEST_POSTGRES_DSN is set.
+func TestOrderedRulesQuotesTheColumns(t *testing.T) {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+
+	sql := gormDB.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var rules []security_model.GoMatrixRule
+		return orderedRules(tx).Where(clause.Eq{Column: clause.Column{Name: "UserId"}, Value: "USR1"}).Find(&rules)
+	})
+
+	assert.Contains(t, sql, `SELECT "UserId","EmployeeId","AccessLevelRead" FROM`)
+	assert.Contains(t, sql, `WHERE "UserId" = 'USR1'`)
+	assert.Contains(t, sql, `ORDER BY "UserId","EmployeeId"`)
+}
+
+// TestReplaceRulesLeavesNoStagingTable checks the staging table is swapped in by a successful write and dropped by a
+// failed or cancelled one, the matrix being left untouched.
+func TestReplaceRulesLeavesNoStagingTable(t *testing.T) {
+	backend := newGormBackend(t, db.DriverSQLite, "file:replace_rules_staging?mode=memory&cache=shared", repotest.DefaultDataset())
+	gormDB := backend.Rules.(*securityMatrixRuleRepository).DB
+	rules := []security_model.GoMatrixRule{{UserId: "USR1", EmployeeId: 1, AccessLevelRead: 1}}
+	ctx := context.Background()
+
+	require.NoError(t, backend.Rules.WriteRules(ctx, rules))
+
+	// A duplicated primary key fails the insert
+	assert.Error(t, backend.Rules.WriteRules(ctx, []security_model.GoMatrixRule{rules[0], rules[0]}))
+
+	cancelled_ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, backend.Rules.WriteRules(cancelled_ctx, nil))
+
+	assert.Equal(t, rules, backend.ReadRules(t))
+	tables, err := gormDB.Migrator().GetTables()
+	require.NoError(t, err)
+	for _, table := range tables {
+		assert.NotContains(t, table, ruleStagingTable)
+		assert.NotContains(t, table, ruleRetiredTable)
+	}
+	assert.Contains(t, tables, "GoMatrixRule")
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+
+	"gorm.io/gorm"
+)
+
+// sqlServerSnapshotIsolationDisabled is the SQL Server error raised when a snapshot transaction reads a database
+// where ALLOW_SNAPSHOT_ISOLATION is OFF.
+const sqlServerSnapshotIsolationDisabled = 3952
+
+// snapshotKey is the key of the snapshot transaction carried by a context.
+type snapshotKey struct{}
+
+// connection returns the connection the queries of a repository run on: the snapshot transaction carried by ctx
+// (see ReadSnapshot), or DB outside of a snapshot. Either way bound to ctx.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle, possibly carrying a snapshot transaction.
+// - DB: the database connection of the repository.
+//
+// Returns:
+// - *gorm.DB: the connection to query.
+func connection(ctx context.Context, DB *gorm.DB) *gorm.DB {
+	if tx, found := ctx.Value(snapshotKey{}).(*gorm.DB); found {
+		return tx.WithContext(ctx)
+	}
+
+	return DB.WithContext(ctx)
+}
+
+// Supported values of the calculation.snapshot_isolation configuration.
+const (
+	SnapshotIsolationSnapshot      = "snapshot"       // SNAPSHOT on SQL Server, REPEATABLE READ on PostgreSQL
+	SnapshotIsolationSerializable  = "serializable"   // SERIALIZABLE, holding locks on the rows read until the end
+	SnapshotIsolationReadCommitted = "read_committed" // READ COMMITTED, each read seeing the rows committed before it
+)
+
+type snapshotRepository struct {
+	DB        *gorm.DB
+	txOptions *sql.TxOptions
+}
+
+// NewSnapshotRepository creates the SnapshotRepository reading at the isolation set by calculation.snapshot_isolation.
+// SQLite ignores it: its transactions are serializable.
+func NewSnapshotRepository(DB *gorm.DB, config *db.Config) (interfaces.SnapshotRepository, error) {
+	tx_options, err := snapshotTxOptions(DB.Dialector.Name(), config.Calculation.SnapshotIsolation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshotRepository{DB: DB, txOptions: tx_options}, nil
+}
+
+// ReadSnapshot runs read within a transaction at the configured isolation, read-only where the dialect supports it
+// (SQL Server has no read-only transactions). At the default snapshot isolation, the transaction runs at SNAPSHOT
+// on SQL Server (which needs ALLOW_SNAPSHOT_ISOLATION ON), REPEATABLE READ on PostgreSQL, and as a deferred
+// transaction on SQLite. Every repository read made with the context given to read runs in that transaction,
+// so they all see the data as of the same moment, unless the isolation is read_committed.
+// The transaction is always rolled back: nothing is written through it.
+//
+// Parameters:
+// - ctx: context for managing request lifecycle, the transaction is rolled back when it is cancelled.
+// - read: the reads, given the context carrying the transaction.
+//
+// Returns:
+// - time.Time: when the transaction began, in UTC, by the clock of the database. The snapshot is taken at its first
+// read, right after.
+// - error: an error object if the transaction cannot begin or read fails, nil otherwise.
+func (p *snapshotRepository) ReadSnapshot(ctx context.Context, read func(ctx context.Context) error) (time.Time, error) {
+	tx := p.DB.WithContext(ctx).Begin(p.txOptions)
+	if tx.Error != nil {
+		return time.Time{}, tx.Error
+	}
+	defer tx.Rollback()
+
+	snapshot_at, err := transactionTime(tx)
+	if err == nil {
+		err = read(context.WithValue(ctx, snapshotKey{}, tx))
+	}
+	if err != nil {
+		var sql_server_err interface{ SQLErrorNumber() int32 }
+		if errors.As(err, &sql_server_err) && sql_server_err.SQLErrorNumber() == sqlServerSnapshotIsolationDisabled {
+			return time.Time{}, fmt.Errorf("snapshot isolation is not allowed on the database, set its ALLOW_SNAPSHOT_ISOLATION option ON "+
+				"or calculation.snapshot_isolation to serializable: %w", err)
+		}
+		return time.Time{}, err
+	}
+
+	return snapshot_at, nil
+}
+
+// transactionTime reads the current time of the database within the transaction, so that the time of the snapshot
+// does not depend on the clock of the application: SYSUTCDATETIME() on SQL Server, now() (the start of the
+// transaction) on PostgreSQL. SQLite runs in the process of the application, whose clock is used.
+func transactionTime(tx *gorm.DB) (time.Time, error) {
+	var query string
+	switch tx.Dialector.Name() {
+	case db.DriverSQLServer:
+		query = "SELECT SYSUTCDATETIME()"
+	case db.DriverPostgres:
+		query = "SELECT now()"
+	default:
+		return time.Now().UTC(), nil
+	}
+
+	var transaction_time time.Time
+	if err := tx.Raw(query).Row().Scan(&transaction_time); err != nil {
+		return time.Time{}, fmt.Errorf("error reading the time of the database: %w", err)
+	}
+
+	return transaction_time.UTC(), nil
+}
+
+// snapshotTxOptions returns the options of the transaction reading a snapshot on the given dialect at the given
+// isolation, the default one when it is empty.
+func snapshotTxOptions(dialect string, isolation string) (*sql.TxOptions, error) {
+	var level sql.IsolationLevel
+	switch isolation {
+	case "", SnapshotIsolationSnapshot:
+		level = sql.LevelSnapshot
+	case SnapshotIsolationSerializable:
+		level = sql.LevelSerializable
+	case SnapshotIsolationReadCommitted:
+		level = sql.LevelReadCommitted
+	default:
+		return nil, fmt.Errorf("unknown calculation.snapshot_isolation %q", isolation)
+	}
+
+	switch dialect {
+	case db.DriverSQLServer:
+		// SQL Server has no read-only transactions
+		return &sql.TxOptions{Isolation: level}, nil
+	case db.DriverPostgres:
+		// The REPEATABLE READ transactions of PostgreSQL read a snapshot
+		if level == sql.LevelSnapshot {
+			level = sql.LevelRepeatableRead
+		}
+		return &sql.TxOptions{Isolation: level, ReadOnly: true}, nil
+	default:
+		return &sql.TxOptions{ReadOnly: true}, nil
+	}
+}
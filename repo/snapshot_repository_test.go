@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+func TestSnapshotTxOptions(t *testing.T) {
+	for _, test := range []struct {
+		dialect   string
+		isolation string
+		expected  sql.TxOptions
+	}{
+		{db.DriverSQLServer, "", sql.TxOptions{Isolation: sql.LevelSnapshot}},
+		{db.DriverSQLServer, SnapshotIsolationSerializable, sql.TxOptions{Isolation: sql.LevelSerializable}},
+		{db.DriverSQLServer, SnapshotIsolationReadCommitted, sql.TxOptions{Isolation: sql.LevelReadCommitted}},
+		{db.DriverPostgres, SnapshotIsolationSnapshot, sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}},
+		{db.DriverPostgres, SnapshotIsolationSerializable, sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}},
+		{db.DriverSQLite, SnapshotIsolationSerializable, sql.TxOptions{ReadOnly: true}},
+	} {
+		tx_options, err := snapshotTxOptions(test.dialect, test.isolation)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, *tx_options, "%s at %q", test.dialect, test.isolation)
+	}
+
+	_, err := snapshotTxOptions(db.DriverSQLite, "repeatable_read")
+	assert.ErrorContains(t, err, `unknown calculation.snapshot_isolation "repeatable_read"`)
+}
@@ -2,11 +2,13 @@ package repo
 
 import (
 	"context"
+	"fmt"
 
 	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
 	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -31,10 +33,57 @@ func (p *userRepository) FetchEmployeesWithAssociatedProfiles(ctx context.Contex
 	p.DB.SetupJoinTable(&model.User{}, "Profiles", &model.ProfileUser{})
 
 	// Retrieve users with associated profiles where EmployeeId is not null and greater than 0
-	result := p.DB.Preload("Profiles").Where("EmployeeId IS NOT NULL AND EmployeeId > 0").Find(&users)
+	// (NULL never compares greater than 0, the column is quoted for case-sensitive dialects)
+	result := connection(ctx, p.DB).Preload("Profiles").Where(clause.Gt{Column: "EmployeeId", Value: 0}).Find(&users)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
 	return users, nil
 }
+
+// FindUserById retrieves the User with the given Id, with its associated Profiles.
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - id: the Id of the User.
+//
+// Returns:
+// - *model.User: the User, nil if there is none with this Id.
+// - error: error object if the operation fails, nil otherwise.
+func (p *userRepository) FindUserById(ctx context.Context, id string) (*model.User, error) {
+	return p.findUser(ctx, clause.Eq{Column: clause.Column{Name: "Id"}, Value: id})
+}
+
+// FindUserByEmail retrieves the User with the given Email, with its associated Profiles.
+// Parameters:
+// - ctx: context for managing request lifecycle.
+// - email: the Email of the User.
+//
+// Returns:
+// - *model.User: the User, nil if there is none with this Email.
+// - error: error object if the operation fails or several Users share the Email, nil otherwise.
+func (p *userRepository) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return p.findUser(ctx, clause.Eq{Column: clause.Column{Name: "Email"}, Value: email})
+}
+
+// findUser retrieves the only User matching the condition, with its associated Profiles.
+func (p *userRepository) findUser(ctx context.Context, condition clause.Expression) (*model.User, error) {
+	var users []*model.User
+
+	p.DB.SetupJoinTable(&model.User{}, "Profiles", &model.ProfileUser{})
+
+	// Two rows are enough to tell an ambiguous match
+	result := connection(ctx, p.DB).Preload("Profiles").Where(condition).Limit(2).Find(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	switch len(users) {
+	case 0:
+		return nil, nil
+	case 1:
+		return users[0], nil
+	default:
+		return nil, fmt.Errorf("several users match %v", condition)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+// DataQualityError is returned by the calculation when data quality enforcement is enabled
+// and the inputs contain referential-integrity issues.
+type DataQualityError struct {
+	Report security_model.DataQualityReport
+}
+
+func (e *DataQualityError) Error() string {
+	counts := make(map[security_model.DataQualityIssueType]int)
+	for _, issue := range e.Report.Issues {
+		counts[issue.Type]++
+	}
+
+	var parts []string
+	for issue_type, count := range counts {
+		parts = append(parts, fmt.Sprintf("%s: %d", issue_type, count))
+	}
+	sort.Strings(parts)
+
+	return "data quality check failed (" + strings.Join(parts, ", ") + ")"
+}
+
+// CheckDataQuality cross-checks Users, Employees, Profiles and ManagerChains and returns
+// every referential-integrity issue found.
+//
+// Parameters:
+// - users: the users with their associated profiles.
+// - employees: every employee in Core.Employees.
+// - manager_chains: the manager chains used by the calculation.
+// - profile_types: the IDs of the profile types.
+//
+// Returns:
+// - security_model.DataQualityReport: the issues found, sorted by type, employee and user.
+//
+// Function Logic:
+// - Users whose EmployeeId does not exist in Core.Employees are flagged.
+// - Users holding more than one EMPLOYEE-type profile are flagged.
+// - Employee.IsManager is compared with holding a MANAGER-type profile and with having reports
+// (level > 0) in the manager chains; every disagreement is flagged.
+func CheckDataQuality(users []*model.User, employees []model.Employee, manager_chains []model.ManagerChain, profile_types ProfileTypes) security_model.DataQualityReport {
+	var issues []security_model.DataQualityIssue
+
+	employees_by_id := make(map[uint]model.Employee, len(employees))
+	for _, employee := range employees {
+		employees_by_id[employee.Id] = employee
+	}
+
+	employees_with_reports := make(map[uint]bool)
+	for _, manager_chain := range manager_chains {
+		if manager_chain.Level > 0 {
+			employees_with_reports[uint(manager_chain.ManagerId)] = true
+		}
+	}
+
+	employees_with_manager_profile := make(map[uint]bool)
+	for _, user := range users {
+		var employee_profile_ids []string
+		var manager_profile_ids []string
+		for _, profile := range user.Profiles {
+			switch profile.ProfileTypeId {
+			case profile_types.EMPLOYEE:
+				employee_profile_ids = append(employee_profile_ids, profile.Id)
+			case profile_types.MANAGER:
+				manager_profile_ids = append(manager_profile_ids, profile.Id)
+			}
+		}
+
+		if len(employee_profile_ids) > 1 {
+			issues = append(issues, security_model.DataQualityIssue{
+				Type:       security_model.MultipleEmployeeProfiles,
+				UserId:     user.Id,
+				EmployeeId: user.EmployeeId,
+				ProfileIds: employee_profile_ids,
+				Message:    fmt.Sprintf("user %s holds %d EMPLOYEE profiles, expected at most one", user.Id, len(employee_profile_ids)),
+			})
+		}
+
+		employee, found := employees_by_id[user.EmployeeId]
+		if !found {
+			issues = append(issues, security_model.DataQualityIssue{
+				Type:       security_model.UnknownEmployee,
+				UserId:     user.Id,
+				EmployeeId: user.EmployeeId,
+				Message:    fmt.Sprintf("user %s references employee %d which does not exist", user.Id, user.EmployeeId),
+			})
+			continue
+		}
+
+		if len(manager_profile_ids) > 0 {
+			employees_with_manager_profile[employee.Id] = true
+
+			if !employee.IsManager {
+				issues = append(issues, security_model.DataQualityIssue{
+					Type:       security_model.ManagerProfileWithoutManagerFlag,
+					UserId:     user.Id,
+					EmployeeId: employee.Id,
+					ProfileIds: manager_profile_ids,
+					Message:    fmt.Sprintf("user %s holds a MANAGER profile but employee %d is not flagged as manager", user.Id, employee.Id),
+				})
+			}
+		}
+	}
+
+	for _, employee := range employees {
+		if employee.IsManager && !employees_with_manager_profile[employee.Id] {
+			issues = append(issues, security_model.DataQualityIssue{
+				Type:       security_model.ManagerFlagWithoutManagerProfile,
+				EmployeeId: employee.Id,
+				Message:    fmt.Sprintf("employee %d is flagged as manager but no user holds a MANAGER profile for them", employee.Id),
+			})
+		}
+
+		if employee.IsManager && !employees_with_reports[employee.Id] {
+			issues = append(issues, security_model.DataQualityIssue{
+				Type:       security_model.ManagerFlagWithoutReports,
+				EmployeeId: employee.Id,
+				Message:    fmt.Sprintf("employee %d is flagged as manager but has no reports", employee.Id),
+			})
+		}
+
+		if !employee.IsManager && employees_with_reports[employee.Id] {
+			issues = append(issues, security_model.DataQualityIssue{
+				Type:       security_model.ReportsWithoutManagerFlag,
+				EmployeeId: employee.Id,
+				Message:    fmt.Sprintf("employee %d has reports but is not flagged as manager", employee.Id),
+			})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Type != issues[j].Type {
+			return issues[i].Type < issues[j].Type
+		}
+		if issues[i].EmployeeId != issues[j].EmployeeId {
+			return issues[i].EmployeeId < issues[j].EmployeeId
+		}
+		return issues[i].UserId < issues[j].UserId
+	})
+
+	if issues == nil {
+		issues = []security_model.DataQualityIssue{}
+	}
+
+	return security_model.DataQualityReport{Issues: issues}
+}
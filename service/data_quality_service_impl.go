@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+type dataQualityService struct {
+	managerChainRepo    interfaces.ManagerChainRepository
+	directManagerRepo   interfaces.DirectManagerRepository
+	userRepo            interfaces.UserRepository
+	employeeRepo        interfaces.EmployeeRepository
+	managerChainOptions ManagerChainOptions
+	profileTypes        ProfileTypes
+}
+
+// NewDataQualityService creates a new instance of DataQualityService.
+//
+// Parameters:
+// - _managerChainRepo: The ManagerChainRepository.
+// - _directManagerRepo: The DirectManagerRepository.
+// - _userRepo: The UserRepository.
+// - _employeeRepo: The EmployeeRepository.
+// - _managerChainOptions: Where the manager chains come from.
+// - _profileTypes: The IDs of the profile types.
+//
+// Returns:
+// - services.DataQualityService: The new instance of DataQualityService.
+func NewDataQualityService(
+	_managerChainRepo interfaces.ManagerChainRepository,
+	_directManagerRepo interfaces.DirectManagerRepository,
+	_userRepo interfaces.UserRepository,
+	_employeeRepo interfaces.EmployeeRepository,
+	_managerChainOptions ManagerChainOptions,
+	_profileTypes ProfileTypes,
+) services.DataQualityService {
+	return &dataQualityService{
+		managerChainRepo:    _managerChainRepo,
+		directManagerRepo:   _directManagerRepo,
+		userRepo:            _userRepo,
+		employeeRepo:        _employeeRepo,
+		managerChainOptions: _managerChainOptions,
+		profileTypes:        _profileTypes,
+	}
+}
+
+// GenerateDataQualityReport loads the same inputs as the security matrix calculation and
+// returns every referential-integrity issue found between them.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - security_model.DataQualityReport: The issues found.
+// - error: An error if loading any of the inputs fails.
+func (p *dataQualityService) GenerateDataQualityReport(ctx context.Context) (security_model.DataQualityReport, error) {
+	manager_chains, err := FetchManagerChains(ctx, p.managerChainRepo, p.directManagerRepo, p.managerChainOptions)
+	if err != nil {
+		return security_model.DataQualityReport{}, fmt.Errorf("error fetching manager chains: %w", err)
+	}
+
+	users, err := p.userRepo.FetchEmployeesWithAssociatedProfiles(ctx)
+	if err != nil {
+		return security_model.DataQualityReport{}, fmt.Errorf("error fetching users with associated profiles: %w", err)
+	}
+
+	employees, err := p.employeeRepo.FindAll(ctx)
+	if err != nil {
+		return security_model.DataQualityReport{}, fmt.Errorf("error fetching employees: %w", err)
+	}
+
+	return CheckDataQuality(users, employees, manager_chains, p.profileTypes), nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+// parquetRowGroupSize is the maximum number of rows buffered in memory before a Parquet row group is written.
+const parquetRowGroupSize = 100000
+
+// exportColumn is a column of an export: its name, its Parquet type and how its value is read from a rule.
+// Values are strings, uint64, uint32 or bool.
+type exportColumn struct {
+	name  string
+	node  parquet.Node
+	value func(rule *security_model.EnrichedMatrixRule) interface{}
+}
+
+// exportColumns returns the columns of an export with the given options.
+func exportColumns(options security_model.ExportOptions) []exportColumn {
+	columns := []exportColumn{
+		{"UserId", parquet.String(), func(rule *security_model.EnrichedMatrixRule) interface{} { return rule.UserId }},
+		{"EmployeeId", parquet.Uint(64), func(rule *security_model.EnrichedMatrixRule) interface{} { return uint64(rule.EmployeeId) }},
+		{"AccessLevelRead", parquet.Uint(32), func(rule *security_model.EnrichedMatrixRule) interface{} { return rule.AccessLevelRead }},
+	}
+
+	if options.Enrich {
+		columns = append(columns,
+			exportColumn{"UserEmail", parquet.String(), func(rule *security_model.EnrichedMatrixRule) interface{} { return rule.UserEmail }},
+			exportColumn{"EmployeeNumber", parquet.String(), func(rule *security_model.EnrichedMatrixRule) interface{} { return rule.EmployeeNumber }},
+		)
+	}
+
+	if options.DecodeAccess {
+		for _, bit := range security_model.ReadAccessLevelBits() {
+			level := uint32(bit.Level)
+			columns = append(columns, exportColumn{bit.Name, parquet.Leaf(parquet.BooleanType), func(rule *security_model.EnrichedMatrixRule) interface{} {
+				return rule.AccessLevelRead&level != 0
+			}})
+		}
+	}
+
+	return columns
+}
+
+// ruleEncoder writes batches of rules in an export format. Close flushes what is buffered and writes the
+// format's trailer, it does not close the underlying writer.
+type ruleEncoder interface {
+	Encode(rules []security_model.EnrichedMatrixRule) error
+	Close() error
+}
+
+// newRuleEncoder creates the encoder of the given format and writes its header.
+func newRuleEncoder(writer io.Writer, format security_model.ExportFormat, columns []exportColumn) (ruleEncoder, error) {
+	switch format {
+	case security_model.ExportFormatCSV:
+		return newCSVEncoder(writer, columns)
+	case security_model.ExportFormatJSONL:
+		return &jsonlEncoder{writer: bufio.NewWriter(writer), columns: columns}, nil
+	case security_model.ExportFormatParquet:
+		return newParquetEncoder(writer, columns), nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// csvEncoder writes a header line with the column names, then a line per rule.
+type csvEncoder struct {
+	writer  *csv.Writer
+	columns []exportColumn
+	record  []string
+}
+
+func newCSVEncoder(writer io.Writer, columns []exportColumn) (*csvEncoder, error) {
+	encoder := &csvEncoder{writer: csv.NewWriter(writer), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		encoder.record[i] = column.name
+	}
+
+	return encoder, encoder.writer.Write(encoder.record)
+}
+
+func (e *csvEncoder) Encode(rules []security_model.EnrichedMatrixRule) error {
+	for i := range rules {
+		for j, column := range e.columns {
+			switch value := column.value(&rules[i]).(type) {
+			case string:
+				e.record[j] = value
+			case uint64:
+				e.record[j] = strconv.FormatUint(value, 10)
+			case uint32:
+				e.record[j] = strconv.FormatUint(uint64(value), 10)
+			case bool:
+				e.record[j] = strconv.FormatBool(value)
+			}
+		}
+		if err := e.writer.Write(e.record); err != nil {
+			return err
+		}
+	}
+
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonlEncoder writes a JSON object per line, with the keys in column order.
+type jsonlEncoder struct {
+	writer  *bufio.Writer
+	columns []exportColumn
+	line    []byte
+}
+
+func (e *jsonlEncoder) Encode(rules []security_model.EnrichedMatrixRule) error {
+	for i := range rules {
+		e.line = append(e.line[:0], '{')
+		for j, column := range e.columns {
+			if j > 0 {
+				e.line = append(e.line, ',')
+			}
+			e.line = strconv.AppendQuote(e.line, column.name)
+			e.line = append(e.line, ':')
+
+			switch value := column.value(&rules[i]).(type) {
+			case string:
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return err
+				}
+				e.line = append(e.line, encoded...)
+			case uint64:
+				e.line = strconv.AppendUint(e.line, value, 10)
+			case uint32:
+				e.line = strconv.AppendUint(e.line, uint64(value), 10)
+			case bool:
+				e.line = strconv.AppendBool(e.line, value)
+			}
+		}
+		e.line = append(e.line, '}', '\n')
+
+		if _, err := e.writer.Write(e.line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *jsonlEncoder) Close() error {
+	return e.writer.Flush()
+}
+
+// parquetEncoder writes the rules as a Parquet file, in row groups of at most parquetRowGroupSize rows.
+type parquetEncoder struct {
+	writer  *parquet.Writer
+	columns []exportColumn
+	indexes []int // Index of the Parquet column of each export column
+	rows    []parquet.Row
+}
+
+func newParquetEncoder(writer io.Writer, columns []exportColumn) *parquetEncoder {
+	group := parquet.Group{}
+	for _, column := range columns {
+		group[column.name] = column.node
+	}
+	schema := parquet.NewSchema("GoMatrixRule", group)
+
+	// A group orders its columns by name
+	column_indexes := make(map[string]int)
+	for i, path := range schema.Columns() {
+		column_indexes[path[0]] = i
+	}
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		indexes[i] = column_indexes[column.name]
+	}
+
+	return &parquetEncoder{
+		writer:  parquet.NewWriter(writer, schema, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+		columns: columns,
+		indexes: indexes,
+	}
+}
+
+func (e *parquetEncoder) Encode(rules []security_model.EnrichedMatrixRule) error {
+	e.rows = e.rows[:0]
+	for i := range rules {
+		row := make(parquet.Row, len(e.columns))
+		for j, column := range e.columns {
+			row[e.indexes[j]] = parquet.ValueOf(column.value(&rules[i])).Level(0, 0, e.indexes[j])
+		}
+		e.rows = append(e.rows, row)
+	}
+
+	_, err := e.writer.WriteRows(e.rows)
+	return err
+}
+
+func (e *parquetEncoder) Close() error {
+	return e.writer.Close()
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+)
+
+// Supported values of the calculation.filter_failure_policy configuration.
+const (
+	FilterFailurePolicyAbort    = "abort"
+	FilterFailurePolicySkipUser = "skip_user"
+)
+
+// FilterFailureOptions decides what a filter failure, an error or a panic, does to the calculation.
+type FilterFailureOptions struct {
+	// SkipUser calculates no rule for the user whose filter failed and carries on with the other users,
+	// instead of aborting the calculation.
+	SkipUser bool
+}
+
+// NewFilterFailureOptions creates the FilterFailureOptions from the application configuration.
+// A filter failure aborts the calculation by default.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - FilterFailureOptions: The filter failure options.
+func NewFilterFailureOptions(config *db.Config) FilterFailureOptions {
+	return FilterFailureOptions{
+		SkipUser: config.Calculation.FilterFailurePolicy == FilterFailurePolicySkipUser,
+	}
+}
+
+// FilterError reports the failure of a filter run for a profile of a user: the filter returned an error or panicked.
+type FilterError struct {
+	Filter    string // Name of the filter, empty when the rules of the user failed outside of the filters
+	UserId    string
+	ProfileId string
+	Panic     bool   // The filter panicked, Err holds the recovered value
+	Stack     string // Stack of the goroutine that panicked
+	Err       error
+}
+
+func (e *FilterError) Error() string {
+	failure := "failed"
+	if e.Panic {
+		failure = "panicked"
+	}
+	if e.Filter == "" {
+		return fmt.Sprintf("calculating the rules of user %s %s: %v", e.UserId, failure, e.Err)
+	}
+
+	return fmt.Sprintf("filter %s %s for profile %s of user %s: %v", e.Filter, failure, e.ProfileId, e.UserId, e.Err)
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// newPanicError creates the FilterError of a recovered panic. The profile and the user may be nil.
+//
+// Parameters:
+// - filter: The name of the filter that panicked, empty outside of the filters.
+// - profile: The profile the filter ran for.
+// - user: The user holding the profile.
+// - recovered: The value recovered from the panic.
+// - stack: The stack of the goroutine that panicked.
+//
+// Returns:
+// - *FilterError: The failure.
+func newPanicError(filter string, profile *model.Profile, user *model.User, recovered interface{}, stack []byte) *FilterError {
+	filter_err := &FilterError{Filter: filter, Panic: true, Stack: string(stack), Err: fmt.Errorf("%v", recovered)}
+	if profile != nil {
+		filter_err.ProfileId = profile.Id
+	}
+	if user != nil {
+		filter_err.UserId = user.Id
+	}
+
+	return filter_err
+}
+
+// filterFailures collects the filter failures of a calculation, shared by its workers. Under the abort policy,
+// the first failure cancels the calculation, otherwise every failure is kept as a skipped user.
+type filterFailures struct {
+	mutex     sync.Mutex
+	skip_user bool
+	abort     context.CancelCauseFunc
+	skipped   []*FilterError
+	err       error
+}
+
+// add records a failure, aborting the calculation unless the users are skipped.
+func (f *filterFailures) add(filter_err *FilterError) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.skip_user {
+		f.skipped = append(f.skipped, filter_err)
+		return
+	}
+	if f.err == nil {
+		f.err = filter_err
+		f.abort(filter_err)
+	}
+}
+
+// result returns the ids of the skipped users, in the order they failed, and the failure that aborted the calculation.
+func (f *filterFailures) result() ([]string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var user_ids []string
+	seen := make(map[string]bool, len(f.skipped))
+	for _, filter_err := range f.skipped {
+		if !seen[filter_err.UserId] {
+			seen[filter_err.UserId] = true
+			user_ids = append(user_ids, filter_err.UserId)
+		}
+	}
+
+	return user_ids, f.err
+}
+
+// skippedUsers returns the ids of the users whose rules were skipped.
+func (f *filterFailures) skippedUsers() map[string]bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	user_ids := make(map[string]bool, len(f.skipped))
+	for _, filter_err := range f.skipped {
+		user_ids[filter_err.UserId] = true
+	}
+
+	return user_ids
+}
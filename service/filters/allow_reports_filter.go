@@ -1,6 +1,8 @@
 package filters
 
 import (
+	"context"
+
 	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
 	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
 	filters_interface "github.com/nuno-bastos/gin-gonic-wire-api/service/filters/interface"
@@ -12,11 +14,17 @@ func NewAllowReportsFilter() *AllowReportsFilter {
 	return &AllowReportsFilter{}
 }
 
+// Name returns "AllowReportsFilter".
+func (f *AllowReportsFilter) Name() string {
+	return "AllowReportsFilter"
+}
+
 // ExecuteFilter generates allow rules for the given profile and user based on the reports
 // associated with the user in the input data. The rules allow the manager to access the
 // reports or profiles of their subordinate users.
 //
 // Parameters:
+// - ctx: the context of the calculation, cancelled when it is interrupted.
 // - profile: a pointer to the Profile model instance.
 // - user: a pointer to the User model instance representing the manager.
 // - input: an instance of SecurityMatrixCalculationFilterInput containing filter criteria.
@@ -24,7 +32,8 @@ func NewAllowReportsFilter() *AllowReportsFilter {
 // Returns:
 //   - ProfileUserAllowOrDenyRules: the result of the filter operation, containing the generated
 //     allow rules for the manager to access reports or profiles of their subordinate users.
-func (f *AllowReportsFilter) ExecuteFilter(profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) security_model.ProfileUserAllowOrDenyRules {
+//   - error: always nil.
+func (f *AllowReportsFilter) ExecuteFilter(ctx context.Context, profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) (security_model.ProfileUserAllowOrDenyRules, error) {
 	var rules []security_model.AllowOrDenyRule // Initialize a list to store the generated rules
 	employeeId := user.EmployeeId              // Retrieve the employee ID from the user
 
@@ -44,9 +53,9 @@ func (f *AllowReportsFilter) ExecuteFilter(profile *model.Profile, user *model.U
 	return security_model.ProfileUserAllowOrDenyRules{
 		ProfileId:        profile.Id,
 		UserId:           user.Id,
-		OriginFilter:     "AllowReportsFilter",
+		OriginFilter:     f.Name(),
 		AllowOrDenyRules: rules,
-	}
+	}, nil
 }
 
 // Ensure AllowReportsFilter implements the IManagerFilter interface.
@@ -1,6 +1,9 @@
 package filters
 
 import (
+	"context"
+	"fmt"
+
 	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
 	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
 	filters_interface "github.com/nuno-bastos/gin-gonic-wire-api/service/filters/interface"
@@ -12,10 +15,16 @@ func NewAllowSelfFilter() *AllowSelfFilter {
 	return &AllowSelfFilter{}
 }
 
+// Name returns "AllowSelfFilter".
+func (f *AllowSelfFilter) Name() string {
+	return "AllowSelfFilter"
+}
+
 // ExecuteFilter generates allow rules for the given profile and user to access
 // the user's own profile. The rule allows the user full access to their own profile.
 //
 // Parameters:
+// - ctx: the context of the calculation, cancelled when it is interrupted.
 // - profile: a pointer to the Profile model instance.
 // - user: a pointer to the User model instance representing the employee.
 // - input: an instance of SecurityMatrixCalculationFilterInput containing filter criteria.
@@ -23,7 +32,12 @@ func NewAllowSelfFilter() *AllowSelfFilter {
 // Returns:
 //   - ProfileUserAllowOrDenyRules: the result of the filter operation, containing the generated
 //     allow rule for the employee to access their own profile.
-func (f *AllowSelfFilter) ExecuteFilter(profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) security_model.ProfileUserAllowOrDenyRules {
+//   - error: an error if the user is not an employee, nil otherwise.
+func (f *AllowSelfFilter) ExecuteFilter(ctx context.Context, profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) (security_model.ProfileUserAllowOrDenyRules, error) {
+	if user.EmployeeId == 0 {
+		return security_model.ProfileUserAllowOrDenyRules{}, fmt.Errorf("user %s is not an employee", user.Id)
+	}
+
 	rules := []security_model.AllowOrDenyRule{
 		{
 			UserManagerId:   user.Id,
@@ -35,9 +49,9 @@ func (f *AllowSelfFilter) ExecuteFilter(profile *model.Profile, user *model.User
 	return security_model.ProfileUserAllowOrDenyRules{
 		ProfileId:        profile.Id,
 		UserId:           user.Id,
-		OriginFilter:     "AllowSelfFilter",
+		OriginFilter:     f.Name(),
 		AllowOrDenyRules: rules,
-	}
+	}, nil
 }
 
 // Ensure AllowSelfFilter implements the IEmployeeFilter interface.
@@ -1,6 +1,8 @@
 package filters
 
 import (
+	"context"
+
 	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
 	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
 	filters_interface "github.com/nuno-bastos/gin-gonic-wire-api/service/filters/interface"
@@ -12,11 +14,17 @@ func NewDenyManagersFilter() *DenyManagersFilter {
 	return &DenyManagersFilter{}
 }
 
+// Name returns "DenyManagersFilter".
+func (f *DenyManagersFilter) Name() string {
+	return "DenyManagersFilter"
+}
+
 // ExecuteFilter generates deny rules for the given profile and user to deny access
 // to other managers who manage the same employee. The rules prevent the manager
 // from accessing other managers who manage the same employee.
 //
 // Parameters:
+// - ctx: the context of the calculation, cancelled when it is interrupted.
 // - profile: a pointer to the Profile model instance.
 // - user: a pointer to the User model instance representing the manager.
 // - input: an instance of SecurityMatrixCalculationFilterInput containing filter criteria.
@@ -24,7 +32,8 @@ func NewDenyManagersFilter() *DenyManagersFilter {
 // Returns:
 //   - ProfileUserAllowOrDenyRules: the result of the filter operation, containing the generated
 //     deny rules for the manager to deny access to other managers who manage the same employee.
-func (f *DenyManagersFilter) ExecuteFilter(profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) security_model.ProfileUserAllowOrDenyRules {
+//   - error: always nil.
+func (f *DenyManagersFilter) ExecuteFilter(ctx context.Context, profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) (security_model.ProfileUserAllowOrDenyRules, error) {
 	var rules []security_model.AllowOrDenyRule // Initialize a list to store the generated rules
 	employeeId := int(user.EmployeeId)         // Retrieve the employee ID associated with the userEmployee
 
@@ -43,9 +52,9 @@ func (f *DenyManagersFilter) ExecuteFilter(profile *model.Profile, user *model.U
 	return security_model.ProfileUserAllowOrDenyRules{
 		ProfileId:        profile.Id,
 		UserId:           user.Id,
-		OriginFilter:     "DenyManagersFilter",
+		OriginFilter:     f.Name(),
 		AllowOrDenyRules: rules,
-	}
+	}, nil
 }
 
 // Ensure DenyManagersFilter implements the IManagerFilter interface.
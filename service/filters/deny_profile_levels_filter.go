@@ -1,6 +1,7 @@
 package filters
 
 import (
+	"context"
 	"sort"
 
 	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
@@ -14,50 +15,90 @@ func NewDenyProfileLevelsFilter() *DenyProfileLevelsFilter {
 	return &DenyProfileLevelsFilter{}
 }
 
+// Name returns "DenyProfileLevelsFilter".
+func (f *DenyProfileLevelsFilter) Name() string {
+	return "DenyProfileLevelsFilter"
+}
+
 // ExecuteFilter generates deny rules for the given profile and user to deny access
 // to all other employees who have a lower or equal access level. The rules prevent
 // the manager from accessing profiles of other employees who are at the same or lower
 // access level.
 //
+// The employees at or below the level of the profile are read from the LevelIndex of the input, built once per
+// calculation by BuildCalculationInput, instead of sorting the levels of LevelToEmployees on each call. Inputs built
+// without it fall back to LevelToEmployees. Either way, an employee holding profiles of several of those levels is
+// denied once.
+//
 // Parameters:
+// - ctx: the context of the calculation, cancelled when it is interrupted.
 // - profile: a pointer to the Profile model instance.
 // - user: a pointer to the User model instance representing the manager.
 // - input: an instance of SecurityMatrixCalculationFilterInput containing filter criteria.
 //
 // Returns:
 //   - ProfileUserAllowOrDenyRules: the result of the filter operation, containing the generated
-//     deny rules for the manager to deny access to other employees' profiles.
-func (f *DenyProfileLevelsFilter) ExecuteFilter(profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) security_model.ProfileUserAllowOrDenyRules {
-	var rules []security_model.AllowOrDenyRule
+//     deny rules for the manager to deny access to other employees' profiles, one per employee.
+//   - error: the error of ctx when it is cancelled, nil otherwise.
+func (f *DenyProfileLevelsFilter) ExecuteFilter(ctx context.Context, profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) (security_model.ProfileUserAllowOrDenyRules, error) {
+	if err := ctx.Err(); err != nil {
+		return security_model.ProfileUserAllowOrDenyRules{}, err
+	}
+
+	// Create a deny rule for each employee at or below the level of the profile
+	var employee_ids []uint
+	if input.LevelIndex != nil {
+		employee_ids = input.LevelIndex.EmployeesAtOrBelow(profile.Level)
+	} else {
+		employee_ids = employeesAtOrBelow(input.LevelToEmployees, profile.Level)
+	}
+	rules := make([]security_model.AllowOrDenyRule, len(employee_ids))
+	for i, employeeId := range employee_ids {
+		rules[i] = security_model.AllowOrDenyRule{
+			UserManagerId:   user.Id,
+			EmployeeId:      employeeId,
+			AccessLevelRead: security_model.DENY_ACCESS_LEVEL_READ,
+		}
+	}
+
+	return security_model.ProfileUserAllowOrDenyRules{
+		ProfileId:        profile.Id,
+		UserId:           user.Id,
+		OriginFilter:     f.Name(),
+		AllowOrDenyRules: rules,
+	}, nil
+}
 
+// employeesAtOrBelow returns the employees of the levels at or below the given one, in the order of a LevelIndex:
+// by ascending level, each employee once, at its lowest level.
+func employeesAtOrBelow(level_to_employees map[uint][]uint, level uint) []uint {
 	// Sort the levels for iteration in ascending order
 	var levels []uint
-	for level := range input.LevelToEmployees {
-		levels = append(levels, level)
+	for employee_level := range level_to_employees {
+		if employee_level <= level {
+			levels = append(levels, employee_level)
+		}
 	}
 	sort.Slice(levels, func(i, j int) bool {
 		return levels[i] < levels[j]
 	})
 
-	// Iterate over the sorted levels and create deny rules
-	for _, level := range levels {
-		if level <= uint(profile.Level) {
-			for _, employeeId := range input.LevelToEmployees[level] {
-				rules = append(rules, security_model.AllowOrDenyRule{
-					UserManagerId:   user.Id,
-					EmployeeId:      employeeId,
-					AccessLevelRead: security_model.DENY_ACCESS_LEVEL_READ,
-				})
+	var employee_ids []uint
+	seen := make(map[uint]bool)
+	for _, employee_level := range levels {
+		start := len(employee_ids)
+		for _, employeeId := range level_to_employees[employee_level] {
+			if !seen[employeeId] {
+				seen[employeeId] = true
+				employee_ids = append(employee_ids, employeeId)
 			}
 		}
+		sort.Slice(employee_ids[start:], func(i, j int) bool {
+			return employee_ids[start+i] < employee_ids[start+j]
+		})
 	}
 
-	return security_model.ProfileUserAllowOrDenyRules{
-		ProfileId:        profile.Id,
-		UserId:           user.Id,
-		OriginFilter:     "DenyProfileLevelsFilter",
-		AllowOrDenyRules: rules,
-	}
+	return employee_ids
 }
 
 // Ensure DenyProfileLevelsFilter implements the IManagerFilter interface.
@@ -1,6 +1,9 @@
 package filters
 
 import (
+	"context"
+	"fmt"
+
 	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
 	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
 	filters_interface "github.com/nuno-bastos/gin-gonic-wire-api/service/filters/interface"
@@ -12,10 +15,16 @@ func NewDenySelfFilter() *DenySelfFilter {
 	return &DenySelfFilter{}
 }
 
+// Name returns "DenySelfFilter".
+func (f *DenySelfFilter) Name() string {
+	return "DenySelfFilter"
+}
+
 // ExecuteFilter generates deny rules for the given profile and user to deny access
 // to the user's own profile. The rule prevents the employee from accessing their own profile.
 //
 // Parameters:
+// - ctx: the context of the calculation, cancelled when it is interrupted.
 // - profile: a pointer to the Profile model instance.
 // - user: a pointer to the User model instance representing the employee.
 // - input: an instance of SecurityMatrixCalculationFilterInput containing filter criteria.
@@ -23,7 +32,12 @@ func NewDenySelfFilter() *DenySelfFilter {
 // Returns:
 //   - ProfileUserAllowOrDenyRules: the result of the filter operation, containing the generated
 //     deny rule for the employee to deny access to their own profile.
-func (f *DenySelfFilter) ExecuteFilter(profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) security_model.ProfileUserAllowOrDenyRules {
+//   - error: an error if the user is not an employee, nil otherwise.
+func (f *DenySelfFilter) ExecuteFilter(ctx context.Context, profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) (security_model.ProfileUserAllowOrDenyRules, error) {
+	if user.EmployeeId == 0 {
+		return security_model.ProfileUserAllowOrDenyRules{}, fmt.Errorf("user %s is not an employee", user.Id)
+	}
+
 	rules := []security_model.AllowOrDenyRule{
 		{
 			UserManagerId:   user.Id,
@@ -35,9 +49,9 @@ func (f *DenySelfFilter) ExecuteFilter(profile *model.Profile, user *model.User,
 	return security_model.ProfileUserAllowOrDenyRules{
 		ProfileId:        profile.Id,
 		UserId:           user.Id,
-		OriginFilter:     "DenySelfFilter",
+		OriginFilter:     f.Name(),
 		AllowOrDenyRules: rules,
-	}
+	}, nil
 }
 
 // Ensure DenySelfFilter implements the IEmployeeFilter interface.
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+// readinessTimeout bounds each readiness check, so a hung database fails the probe instead of blocking it.
+const readinessTimeout = 5 * time.Second
+
+// FreshnessOptions defines when the stored security matrix is considered stale.
+type FreshnessOptions struct {
+	StalenessThreshold time.Duration // Age of the last successful calculation above which the matrix is stale
+}
+
+// NewFreshnessOptions creates the FreshnessOptions from the application configuration.
+// Unless configured, the matrix is stale once its last calculation is older than 24 hours.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - FreshnessOptions: The freshness options.
+func NewFreshnessOptions(config *db.Config) FreshnessOptions {
+	options := FreshnessOptions{
+		StalenessThreshold: config.StalenessThreshold,
+	}
+
+	if options.StalenessThreshold <= 0 {
+		options.StalenessThreshold = 24 * time.Hour
+	}
+
+	return options
+}
+
+type healthService struct {
+	healthRepo       interfaces.HealthRepository
+	runRepo          interfaces.CalculationRunRepository
+	freshnessOptions FreshnessOptions
+	now              func() time.Time
+}
+
+// NewHealthService creates a new instance of HealthService.
+//
+// Parameters:
+// - _healthRepo: The HealthRepository checking the database.
+// - _runRepo: The CalculationRunRepository the last successful calculation is read from.
+// - _freshnessOptions: When the matrix is stale.
+//
+// Returns:
+// - services.HealthService: The new instance of HealthService.
+func NewHealthService(
+	_healthRepo interfaces.HealthRepository,
+	_runRepo interfaces.CalculationRunRepository,
+	_freshnessOptions FreshnessOptions,
+) services.HealthService {
+	return &healthService{
+		healthRepo:       _healthRepo,
+		runRepo:          _runRepo,
+		freshnessOptions: _freshnessOptions,
+		now:              time.Now,
+	}
+}
+
+// CheckReadiness checks the database answers a ping and the tables written by the application are migrated.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - security_model.ReadinessReport: The outcome of each check.
+func (p *healthService) CheckReadiness(ctx context.Context) security_model.ReadinessReport {
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{"database", p.healthRepo.Ping},
+		{"migrations", p.healthRepo.CheckMigrations},
+	}
+
+	var report security_model.ReadinessReport
+	for _, check := range checks {
+		check_ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+		err := check.check(check_ctx)
+		cancel()
+
+		result := security_model.ReadinessCheck{Name: check.name}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report
+}
+
+// CheckFreshness reports when the last successful calculation finished, how many rules it wrote,
+// and whether it is older than the staleness threshold. A matrix never calculated is stale.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - security_model.FreshnessReport: The report.
+// - error: An error if the last calculation cannot be read.
+func (p *healthService) CheckFreshness(ctx context.Context) (security_model.FreshnessReport, error) {
+	last_run, err := p.runRepo.LastRun(ctx)
+	if err != nil {
+		return security_model.FreshnessReport{}, fmt.Errorf("error reading the last calculation: %w", err)
+	}
+
+	return security_model.NewFreshnessReport(last_run, p.freshnessOptions.StalenessThreshold, p.now()), nil
+}
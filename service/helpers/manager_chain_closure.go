@@ -0,0 +1,164 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+)
+
+// ManagerCycleError is returned when the direct manager relationships contain a cycle,
+// which would make the manager chain closure infinite.
+type ManagerCycleError struct {
+	Cycle []int // EmployeeIds forming the cycle, the first id is repeated at the end
+}
+
+func (e *ManagerCycleError) Error() string {
+	ids := make([]string, len(e.Cycle))
+	for i, id := range e.Cycle {
+		ids[i] = fmt.Sprint(id)
+	}
+	return "manager cycle detected: " + strings.Join(ids, " -> ")
+}
+
+// BuildManagerChainClosure derives the fully expanded list of ManagerChains from the
+// direct manager -> employee relationships.
+//
+// For every employee this function walks up the org chart and emits one ManagerChain per
+// manager above them, where Level is the distance between both (1 for the direct manager,
+// 2 for the manager's manager, ...). A self-relation with level 0 is emitted for every
+// employee, matching the layout of the Core.ManagerChains table.
+// When an employee can be reached through more than one path, the shortest one defines the level.
+// A self-managed employee (ManagerId == EmployeeId), as HR exports commonly store the top of the
+// hierarchy, is a root: the edge is skipped and only the level 0 self-relation is kept.
+//
+// Parameters:
+// - direct_managers: a slice of DirectManager instances representing the direct edges.
+//
+// Returns:
+// - []model.ManagerChain: the transitive closure, ordered by EmployeeId and Level.
+// - error: a *ManagerCycleError if the relationships contain a cycle, nil otherwise.
+func BuildManagerChainClosure(direct_managers []model.DirectManager) ([]model.ManagerChain, error) {
+	employee_to_managers := make(map[int][]int)
+	employee_status := make(map[int]bool)
+
+	for _, direct_manager := range direct_managers {
+		employee_status[direct_manager.EmployeeId] = direct_manager.EmployeeStatus
+		if direct_manager.ManagerId == direct_manager.EmployeeId {
+			continue
+		}
+
+		employee_to_managers[direct_manager.EmployeeId] = append(employee_to_managers[direct_manager.EmployeeId], direct_manager.ManagerId)
+
+		// Top level managers only show up as ManagerId, they are considered active
+		if _, found := employee_status[direct_manager.ManagerId]; !found {
+			employee_status[direct_manager.ManagerId] = true
+		}
+	}
+
+	if cycle := findManagerCycle(employee_to_managers); cycle != nil {
+		return nil, &ManagerCycleError{Cycle: cycle}
+	}
+
+	employees := make([]int, 0, len(employee_status))
+	for employee_id := range employee_status {
+		employees = append(employees, employee_id)
+	}
+	sort.Ints(employees)
+
+	var manager_chains []model.ManagerChain
+	for _, employee_id := range employees {
+		status := employee_status[employee_id]
+
+		manager_chains = append(manager_chains, model.ManagerChain{
+			ManagerId:      employee_id,
+			EmployeeId:     employee_id,
+			Level:          0,
+			EmployeeStatus: status,
+		})
+
+		// Breadth-first walk up the chart so that the first visit is the shortest path
+		visited := map[int]bool{employee_id: true}
+		current := []int{employee_id}
+		for level := 1; len(current) > 0; level++ {
+			var next []int
+			for _, id := range current {
+				for _, manager_id := range employee_to_managers[id] {
+					if visited[manager_id] {
+						continue
+					}
+					visited[manager_id] = true
+					next = append(next, manager_id)
+
+					manager_chains = append(manager_chains, model.ManagerChain{
+						ManagerId:      manager_id,
+						EmployeeId:     employee_id,
+						Level:          level,
+						EmployeeStatus: status,
+					})
+				}
+			}
+			current = next
+		}
+	}
+
+	return manager_chains, nil
+}
+
+// findManagerCycle runs a depth-first search over the employee -> managers graph and returns
+// the first cycle found, or nil when the graph is acyclic.
+func findManagerCycle(employee_to_managers map[int][]int) []int {
+	const (
+		unvisited = iota
+		in_progress
+		done
+	)
+
+	state := make(map[int]int)
+	var path []int
+
+	var visit func(id int) []int
+	visit = func(id int) []int {
+		state[id] = in_progress
+		path = append(path, id)
+
+		for _, manager_id := range employee_to_managers[id] {
+			switch state[manager_id] {
+			case in_progress:
+				// Slice the current path from the first occurrence of manager_id
+				for i, path_id := range path {
+					if path_id == manager_id {
+						cycle := append([]int{}, path[i:]...)
+						return append(cycle, manager_id)
+					}
+				}
+			case unvisited:
+				if cycle := visit(manager_id); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+
+	// Visit in a stable order so that the reported cycle is deterministic
+	employees := make([]int, 0, len(employee_to_managers))
+	for employee_id := range employee_to_managers {
+		employees = append(employees, employee_id)
+	}
+	sort.Ints(employees)
+
+	for _, employee_id := range employees {
+		if state[employee_id] == unvisited {
+			if cycle := visit(employee_id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
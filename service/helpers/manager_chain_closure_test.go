@@ -0,0 +1,147 @@
+package helpers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	helpers "github.com/nuno-bastos/gin-gonic-wire-api/service/helpers"
+)
+
+// edge is an active employee reporting to a manager.
+func edge(manager_id, employee_id int) model.DirectManager {
+	return model.DirectManager{ManagerId: manager_id, EmployeeId: employee_id, EmployeeStatus: true}
+}
+
+// chain is an active employee below a manager at the given level.
+func chain(manager_id, employee_id, level int) model.ManagerChain {
+	return model.ManagerChain{ManagerId: manager_id, EmployeeId: employee_id, Level: level, EmployeeStatus: true}
+}
+
+func TestBuildManagerChainClosure(t *testing.T) {
+	tests := []struct {
+		name            string
+		direct_managers []model.DirectManager
+		expected        []model.ManagerChain
+	}{
+		{
+			name:            "empty",
+			direct_managers: nil,
+			expected:        nil,
+		},
+		{
+			name:            "chain",
+			direct_managers: []model.DirectManager{edge(1, 2), edge(2, 3)},
+			expected: []model.ManagerChain{
+				chain(1, 1, 0),
+				chain(2, 2, 0), chain(1, 2, 1),
+				chain(3, 3, 0), chain(2, 3, 1), chain(1, 3, 2),
+			},
+		},
+		{
+			// 1 manages 2 and 3, who both manage 4: 1 is reached through both, once
+			name:            "diamond",
+			direct_managers: []model.DirectManager{edge(1, 2), edge(1, 3), edge(2, 4), edge(3, 4)},
+			expected: []model.ManagerChain{
+				chain(1, 1, 0),
+				chain(2, 2, 0), chain(1, 2, 1),
+				chain(3, 3, 0), chain(1, 3, 1),
+				chain(4, 4, 0), chain(2, 4, 1), chain(3, 4, 1), chain(1, 4, 2),
+			},
+		},
+		{
+			// 4 reports to 3 (1 -> 2 -> 3 -> 4) and directly to 1: the shortest path defines the level of 1
+			name:            "shortest path",
+			direct_managers: []model.DirectManager{edge(1, 2), edge(2, 3), edge(3, 4), edge(1, 4)},
+			expected: []model.ManagerChain{
+				chain(1, 1, 0),
+				chain(2, 2, 0), chain(1, 2, 1),
+				chain(3, 3, 0), chain(2, 3, 1), chain(1, 3, 2),
+				chain(4, 4, 0), chain(3, 4, 1), chain(1, 4, 1), chain(2, 4, 2),
+			},
+		},
+		{
+			name:            "inactive employee",
+			direct_managers: []model.DirectManager{edge(1, 2), {ManagerId: 2, EmployeeId: 3, EmployeeStatus: false}},
+			expected: []model.ManagerChain{
+				chain(1, 1, 0),
+				chain(2, 2, 0), chain(1, 2, 1),
+				{ManagerId: 3, EmployeeId: 3, Level: 0}, {ManagerId: 2, EmployeeId: 3, Level: 1}, {ManagerId: 1, EmployeeId: 3, Level: 2},
+			},
+		},
+		{
+			// HR exports store the top of the hierarchy as self-managed: 1 is a root
+			name:            "self-managed root",
+			direct_managers: []model.DirectManager{edge(1, 1), edge(1, 2), edge(2, 3)},
+			expected: []model.ManagerChain{
+				chain(1, 1, 0),
+				chain(2, 2, 0), chain(1, 2, 1),
+				chain(3, 3, 0), chain(2, 3, 1), chain(1, 3, 2),
+			},
+		},
+		{
+			// The status of a self-managed employee comes from their own row
+			name:            "inactive self-managed root",
+			direct_managers: []model.DirectManager{{ManagerId: 1, EmployeeId: 1, EmployeeStatus: false}, edge(1, 2)},
+			expected: []model.ManagerChain{
+				{ManagerId: 1, EmployeeId: 1, Level: 0},
+				chain(2, 2, 0), chain(1, 2, 1),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager_chains, err := helpers.BuildManagerChainClosure(test.direct_managers)
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, manager_chains)
+		})
+	}
+}
+
+func TestBuildManagerChainClosureDetectsCycles(t *testing.T) {
+	tests := []struct {
+		name            string
+		direct_managers []model.DirectManager
+		cycle           []int
+	}{
+		{
+			name:            "two employees",
+			direct_managers: []model.DirectManager{edge(1, 2), edge(2, 1)},
+			cycle:           []int{1, 2, 1},
+		},
+		{
+			// 1 -> 2 -> 3 -> 4 -> 1, employee 1 reports to 2 and so on
+			name:            "four employees",
+			direct_managers: []model.DirectManager{edge(2, 1), edge(3, 2), edge(4, 3), edge(1, 4)},
+			cycle:           []int{1, 2, 3, 4, 1},
+		},
+		{
+			// 1 reports into the cycle 2 -> 3 -> 2 without being part of it
+			name:            "below a cycle",
+			direct_managers: []model.DirectManager{edge(2, 1), edge(3, 2), edge(2, 3)},
+			cycle:           []int{2, 3, 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager_chains, err := helpers.BuildManagerChainClosure(test.direct_managers)
+
+			assert.Nil(t, manager_chains)
+			var cycle_err *helpers.ManagerCycleError
+			require.True(t, errors.As(err, &cycle_err), "%v", err)
+			assert.Equal(t, test.cycle, cycle_err.Cycle)
+		})
+	}
+}
+
+func TestManagerCycleErrorMessage(t *testing.T) {
+	err := &helpers.ManagerCycleError{Cycle: []int{1, 2, 3, 1}}
+
+	assert.Equal(t, "manager cycle detected: 1 -> 2 -> 3 -> 1", err.Error())
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+type DataQualityService interface {
+	GenerateDataQualityReport(ctx context.Context) (security_model.DataQualityReport, error)
+}
@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+type HealthService interface {
+	CheckReadiness(ctx context.Context) security_model.ReadinessReport
+	CheckFreshness(ctx context.Context) (security_model.FreshnessReport, error)
+}
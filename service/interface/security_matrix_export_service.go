@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+	"io"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+type SecurityMatrixExportService interface {
+	Export(ctx context.Context, writer io.Writer, options security_model.ExportOptions) error
+	UserRules(ctx context.Context, userId string) ([]security_model.GoMatrixRule, error)
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+type SecurityMatrixParityService interface {
+	CheckParity(ctx context.Context, source security_model.ParitySource) (security_model.ParityReport, error)
+}
@@ -7,6 +7,8 @@ import (
 )
 
 type SecurityMatrixService interface {
-	CalculateGoSecurityMatrix(ctx context.Context)
-	CalculateRules(managersProfilesUsers, employeesProfilesUsers []*security_model.TupleProfileUser, input security_model.SecurityMatrixCalculationFilterInput) []*security_model.ProfileUserAllowOrDenyRules
+	CalculateGoSecurityMatrix(ctx context.Context) error
+	StreamRules(ctx context.Context, usersProfiles []*security_model.UserProfiles, input security_model.SecurityMatrixCalculationFilterInput) (<-chan []security_model.GoMatrixRule, func() ([]string, error))
+	CalculateUserRules(ctx context.Context, userProfiles *security_model.UserProfiles, input security_model.SecurityMatrixCalculationFilterInput) ([]*security_model.ProfileUserAllowOrDenyRules, error)
+	CalculateRules(ctx context.Context, managersProfilesUsers, employeesProfilesUsers []*security_model.TupleProfileUser, input security_model.SecurityMatrixCalculationFilterInput) ([]*security_model.ProfileUserAllowOrDenyRules, error)
 }
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+	helpers "github.com/nuno-bastos/gin-gonic-wire-api/service/helpers"
+)
+
+// ManagerChainOptions defines where the manager chains used by the calculation come from.
+type ManagerChainOptions struct {
+	// DeriveFromDirectManagers computes the manager chain closure from the direct manager
+	// relationships instead of reading the expanded Core.ManagerChains table.
+	DeriveFromDirectManagers bool
+	// Materialize writes the derived closure back to Core.ManagerChains.
+	Materialize bool
+}
+
+// NewManagerChainOptions creates the ManagerChainOptions from the application configuration.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - ManagerChainOptions: The manager chain options.
+func NewManagerChainOptions(config *db.Config) ManagerChainOptions {
+	return ManagerChainOptions{
+		DeriveFromDirectManagers: config.ManagerChainSource == db.ManagerChainSourceDirectManagers,
+		Materialize:              config.MaterializeManagerChains,
+	}
+}
+
+// FetchManagerChains returns the manager chains used by the calculation.
+// Depending on the ManagerChainOptions, they are either read from the expanded Core.ManagerChains
+// table or derived from the direct manager relationships (and optionally written back).
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - managerChainRepo: The ManagerChainRepository.
+// - directManagerRepo: The DirectManagerRepository.
+// - options: Where the manager chains come from.
+//
+// Returns:
+// - []model.ManagerChain: The manager chains.
+// - error: An error if fetching, deriving or materializing the chains fails.
+func FetchManagerChains(
+	ctx context.Context,
+	managerChainRepo interfaces.ManagerChainRepository,
+	directManagerRepo interfaces.DirectManagerRepository,
+	options ManagerChainOptions,
+) ([]model.ManagerChain, error) {
+	if !options.DeriveFromDirectManagers {
+		return managerChainRepo.FindAll(ctx)
+	}
+
+	direct_managers, err := directManagerRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manager_chains, err := helpers.BuildManagerChainClosure(direct_managers)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Materialize {
+		if err := managerChainRepo.ReplaceAll(ctx, manager_chains); err != nil {
+			return nil, err
+		}
+	}
+
+	return manager_chains, nil
+}
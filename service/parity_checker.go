@@ -0,0 +1,159 @@
+package service
+
+import (
+	"sort"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+// ParityOptions defines how much detail the parity report holds.
+type ParityOptions struct {
+	// MaxSamplesPerUser is the number of mismatches listed per user, every mismatch is counted.
+	MaxSamplesPerUser int
+}
+
+// parityComparison accumulates the mismatches between the legacy matrix and the compared one.
+// Mismatches are added in UserId order: once a user is complete, its mismatches are attributed to
+// the filters contributing to them and only their counts and samples are kept.
+type parityComparison struct {
+	options ParityOptions
+
+	// filterRules returns, for a user, the filters producing a rule for each employee.
+	filterRules func(user_id string) map[uint][]string
+
+	report     security_model.ParityReport
+	by_filter  map[string]*security_model.ParityFilterMismatches
+	user_id    string
+	mismatches []security_model.ParityMismatch // Mismatches of the current user
+}
+
+func newParityComparison(options ParityOptions, filterRules func(user_id string) map[uint][]string) *parityComparison {
+	return &parityComparison{
+		options:     options,
+		filterRules: filterRules,
+		by_filter:   make(map[string]*security_model.ParityFilterMismatches),
+	}
+}
+
+// compare compares the access levels the two matrices grant for a (user, employee) pair, 0 when it has no rule.
+func (c *parityComparison) compare(user_id string, employee_id uint, legacy_access_level, access_level uint32) {
+	if legacy_access_level == access_level {
+		return
+	}
+
+	mismatch := security_model.ParityMismatch{
+		UserId:                user_id,
+		EmployeeId:            employee_id,
+		Type:                  security_model.AccessLevelMismatch,
+		LegacyAccessLevelRead: legacy_access_level,
+		AccessLevelRead:       access_level,
+	}
+	if access_level == 0 {
+		mismatch.Type = security_model.MissingRule
+	} else if legacy_access_level == 0 {
+		mismatch.Type = security_model.UnexpectedRule
+	}
+
+	if user_id != c.user_id {
+		c.completeUser()
+		c.user_id = user_id
+	}
+	c.mismatches = append(c.mismatches, mismatch)
+}
+
+// completeUser attributes the mismatches of the current user to their contributing filters.
+func (c *parityComparison) completeUser() {
+	if len(c.mismatches) == 0 {
+		return
+	}
+
+	filter_rules := c.filterRules(c.user_id)
+	user := security_model.ParityUserMismatches{
+		UserId:     c.user_id,
+		Mismatches: len(c.mismatches),
+		ByType:     make(map[security_model.ParityMismatchType]int),
+	}
+
+	for i := range c.mismatches {
+		mismatch := &c.mismatches[i]
+		mismatch.Filters = filter_rules[mismatch.EmployeeId]
+		if len(mismatch.Filters) == 0 {
+			mismatch.Filters = []string{security_model.NoContributingFilter}
+		}
+
+		user.ByType[mismatch.Type]++
+		for _, filter := range mismatch.Filters {
+			filter_mismatches, found := c.by_filter[filter]
+			if !found {
+				filter_mismatches = &security_model.ParityFilterMismatches{Filter: filter, ByType: make(map[security_model.ParityMismatchType]int)}
+				c.by_filter[filter] = filter_mismatches
+			}
+			filter_mismatches.Mismatches++
+			filter_mismatches.ByType[mismatch.Type]++
+		}
+	}
+
+	samples := len(c.mismatches)
+	if samples > c.options.MaxSamplesPerUser {
+		samples = c.options.MaxSamplesPerUser
+	}
+	user.Samples = append([]security_model.ParityMismatch{}, c.mismatches[:samples]...)
+
+	c.report.Mismatches += len(c.mismatches)
+	c.report.ByUser = append(c.report.ByUser, user)
+	c.mismatches = c.mismatches[:0]
+}
+
+// complete returns the report, users and filters with the most mismatches first.
+func (c *parityComparison) complete() security_model.ParityReport {
+	c.completeUser()
+
+	sort.SliceStable(c.report.ByUser, func(i, j int) bool {
+		return c.report.ByUser[i].Mismatches > c.report.ByUser[j].Mismatches
+	})
+
+	c.report.ByFilter = make([]security_model.ParityFilterMismatches, 0, len(c.by_filter))
+	for _, filter_mismatches := range c.by_filter {
+		c.report.ByFilter = append(c.report.ByFilter, *filter_mismatches)
+	}
+	sort.Slice(c.report.ByFilter, func(i, j int) bool {
+		if c.report.ByFilter[i].Mismatches != c.report.ByFilter[j].Mismatches {
+			return c.report.ByFilter[i].Mismatches > c.report.ByFilter[j].Mismatches
+		}
+		return c.report.ByFilter[i].Filter < c.report.ByFilter[j].Filter
+	})
+
+	if c.report.ByUser == nil {
+		c.report.ByUser = []security_model.ParityUserMismatches{}
+	}
+
+	return c.report
+}
+
+// contributingFilters indexes, by employee, the filters producing a rule in the results of a user's filters.
+func contributingFilters(results []*security_model.ProfileUserAllowOrDenyRules) map[uint][]string {
+	filters := make(map[uint][]string)
+	for _, result := range results {
+		for _, rule := range result.AllowOrDenyRules {
+			if !containsString(filters[rule.EmployeeId], result.OriginFilter) {
+				filters[rule.EmployeeId] = append(filters[rule.EmployeeId], result.OriginFilter)
+			}
+		}
+	}
+
+	for _, employee_filters := range filters {
+		sort.Strings(employee_filters)
+	}
+
+	return filters
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,176 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nuno-bastos/gin-gonic-wire-api/generator"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	filters "github.com/nuno-bastos/gin-gonic-wire-api/service/filters"
+	helpers "github.com/nuno-bastos/gin-gonic-wire-api/service/helpers"
+)
+
+// benchmarkSizes are the number of employees of the generated organizations.
+var benchmarkSizes = []int{1_000, 5_000}
+
+// generatedStore generates an organization of the given size with the default options and loads it into a store.
+func generatedStore(b *testing.B, employees int) *memory.Store {
+	options := generator.DefaultOptions()
+	options.Employees = employees
+
+	organization, err := generator.Generate(options)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return memory.NewStore(organization.Data())
+}
+
+// BenchmarkCalculateRules measures the batch calculation (every filter of every profile user) on generated organizations.
+//
+//	go test ./service -run '^$' -bench CalculateRules -benchmem
+func BenchmarkCalculateRules(b *testing.B) {
+	for _, employees := range benchmarkSizes {
+		b.Run(fmt.Sprintf("employees=%d", employees), func(b *testing.B) {
+			store := generatedStore(b, employees)
+			calculator := newCalculator(store)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				calculateRules(b, calculator, store)
+			}
+		})
+	}
+}
+
+// BenchmarkFlatten measures the condensation of the filters' rules into GoMatrixRules on generated organizations.
+func BenchmarkFlatten(b *testing.B) {
+	for _, employees := range benchmarkSizes {
+		b.Run(fmt.Sprintf("employees=%d", employees), func(b *testing.B) {
+			store := generatedStore(b, employees)
+			calculator := newCalculator(store)
+			managers_profiles_users, employees_profiles_users, input := calculationInputs(b, store)
+			rules, err := calculator.CalculateRules(context.Background(), managers_profiles_users, employees_profiles_users, input)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				helpers.Flatten(rules)
+			}
+		})
+	}
+}
+
+// BenchmarkDenyProfileLevelsFilter measures the DenyProfileLevelsFilter run for every manager profile of generated
+// organizations, as in one calculation, reading the LevelIndex built once by BuildCalculationInput, or falling back
+// to LevelToEmployees without it. The rules emitted per calculation are reported. See
+// docs/benchmarks/calculation_context.md.
+//
+//	go test ./service -run '^$' -bench DenyProfileLevelsFilter -benchmem
+func BenchmarkDenyProfileLevelsFilter(b *testing.B) {
+	filter := filters.NewDenyProfileLevelsFilter()
+
+	for _, employees := range append(benchmarkSizes, 20_000) {
+		managers_profiles_users, _, input := calculationInputs(b, generatedStore(b, employees))
+
+		for _, level_index := range []bool{true, false} {
+			b.Run(fmt.Sprintf("employees=%d/level_index=%t", employees, level_index), func(b *testing.B) {
+				input := input
+				if !level_index {
+					input.LevelIndex = nil
+				}
+
+				b.ResetTimer()
+				rules := 0
+				for i := 0; i < b.N; i++ {
+					rules = 0
+					for _, profile_user := range managers_profiles_users {
+						result, err := filter.ExecuteFilter(context.Background(), profile_user.Profile, profile_user.User, input)
+						if err != nil {
+							b.Fatal(err)
+						}
+						rules += len(result.AllowOrDenyRules)
+					}
+				}
+				b.ReportMetric(float64(rules), "rules/op")
+			})
+		}
+	}
+}
+
+// BenchmarkBuildCalculationInput measures the preparation of the calculation input, managers and reports indexes and
+// LevelIndex included, once per calculation, on generated organizations.
+func BenchmarkBuildCalculationInput(b *testing.B) {
+	for _, employees := range append(benchmarkSizes, 20_000) {
+		b.Run(fmt.Sprintf("employees=%d", employees), func(b *testing.B) {
+			store := generatedStore(b, employees)
+			manager_chains, err := memory.NewManagerChainRepository(store).FindAll(context.Background())
+			if err != nil {
+				b.Fatal(err)
+			}
+			users, err := memory.NewUserRepository(store).FetchEmployeesWithAssociatedProfiles(context.Background())
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := service.BuildCalculationInput(manager_chains, users); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCalculateGoSecurityMatrix measures the streaming calculation, written to an in-memory store,
+// on generated organizations.
+func BenchmarkCalculateGoSecurityMatrix(b *testing.B) {
+	for _, employees := range benchmarkSizes {
+		b.Run(fmt.Sprintf("employees=%d", employees), func(b *testing.B) {
+			calculator := newCalculator(generatedStore(b, employees))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := calculator.CalculateGoSecurityMatrix(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// calculationInputs builds the inputs of CalculateRules from the store.
+func calculationInputs(tb testing.TB, store *memory.Store) ([]*security_model.TupleProfileUser, []*security_model.TupleProfileUser, security_model.SecurityMatrixCalculationFilterInput) {
+	ctx := context.Background()
+
+	manager_chains, err := memory.NewManagerChainRepository(store).FindAll(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	users, err := memory.NewUserRepository(store).FetchEmployeesWithAssociatedProfiles(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	input, err := service.BuildCalculationInput(manager_chains, users)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var managers_profiles_users, employees_profiles_users []*security_model.TupleProfileUser
+	for _, user_profiles := range service.GroupProfilesByUser(users, service.ProfileTypeId) {
+		for _, profile := range user_profiles.ManagerProfiles {
+			managers_profiles_users = append(managers_profiles_users, &security_model.TupleProfileUser{Profile: profile, User: user_profiles.User})
+		}
+		for _, profile := range user_profiles.EmployeeProfiles {
+			employees_profiles_users = append(employees_profiles_users, &security_model.TupleProfileUser{Profile: profile, User: user_profiles.User})
+		}
+	}
+
+	return managers_profiles_users, employees_profiles_users, input
+}
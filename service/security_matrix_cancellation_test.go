@@ -0,0 +1,152 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	filters "github.com/nuno-bastos/gin-gonic-wire-api/service/filters"
+	filters_interface "github.com/nuno-bastos/gin-gonic-wire-api/service/filters/interface"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+// blockingRuleRepository holds the write transaction open until its context is done.
+type blockingRuleRepository struct {
+	interfaces.SecurityMatrixRuleRepository
+}
+
+func (p *blockingRuleRepository) WriteRulesStream(ctx context.Context, rules_channel <-chan []security_model.GoMatrixRule) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// blockingFilter runs until the context of the calculation is done.
+type blockingFilter struct{}
+
+func (f *blockingFilter) Name() string {
+	return "BlockingFilter"
+}
+
+func (f *blockingFilter) ExecuteFilter(ctx context.Context, profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) (security_model.ProfileUserAllowOrDenyRules, error) {
+	<-ctx.Done()
+	return security_model.ProfileUserAllowOrDenyRules{ProfileId: profile.Id, UserId: user.Id, OriginFilter: f.Name()}, nil
+}
+
+// newFilteredCalculator assembles the calculator over the store, writing through rule_repo, running manager_filter for the
+// manager profiles, timing out after the given timeouts and handling the filter failures by the given options.
+func newFilteredCalculator(t *testing.T, rule_repo interfaces.SecurityMatrixRuleRepository, manager_filter filters_interface.IManagerFilter, timeouts service.TimeoutOptions, filter_failures service.FilterFailureOptions, store *memory.Store) services.SecurityMatrixService {
+	t.Helper()
+
+	return service.NewSecurityMatrixCalculatorService(
+		memory.NewManagerChainRepository(store),
+		memory.NewDirectManagerRepository(store),
+		memory.NewUserRepository(store),
+		memory.NewEmployeeRepository(store),
+		rule_repo,
+		memory.NewCalculationRunRepository(store),
+		memory.NewSnapshotRepository(store),
+		[]filters_interface.IManagerFilter{manager_filter},
+		[]filters_interface.IEmployeeFilter{filters.NewAllowSelfFilter()},
+		service.ManagerChainOptions{},
+		service.DataQualityOptions{},
+		service.NewPipelineOptions(&db.Config{Calculation: db.CalculationConfig{Workers: 2}}),
+		service.ProfileTypeId,
+		service.RetryOptions{},
+		timeouts,
+		filter_failures,
+		metrics.NewMetrics(),
+		noop.NewTracerProvider(),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+}
+
+func TestPhaseAndCalculationTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts service.TimeoutOptions
+		stage    string
+	}{
+		{"write phase", service.TimeoutOptions{Write: 20 * time.Millisecond}, "write phase"},
+		{"calculation", service.TimeoutOptions{Total: 20 * time.Millisecond}, "calculation"},
+		{"shortest first", service.TimeoutOptions{Write: time.Minute, Total: 20 * time.Millisecond}, "calculation"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := loadGoldenStore(t)
+			rule_repo := &blockingRuleRepository{memory.NewSecurityMatrixRuleRepository(store)}
+			calculator := newFilteredCalculator(t, rule_repo, filters.NewAllowReportsFilter(), test.timeouts, service.FilterFailureOptions{}, store)
+
+			err := calculator.CalculateGoSecurityMatrix(context.Background())
+
+			var timeout_err *service.TimeoutError
+			require.True(t, errors.As(err, &timeout_err), "%v", err)
+			assert.Equal(t, test.stage, timeout_err.Stage)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+			// Rolled back and recorded as interrupted, like a cancelled calculation
+			require.Len(t, store.Runs(), 1)
+			assert.Equal(t, security_model.RunStatusInterrupted, store.Runs()[0].Status)
+		})
+	}
+}
+
+func TestStreamRulesStopsWhenCancelled(t *testing.T) {
+	store := loadGoldenStore(t)
+	calculator := newFilteredCalculator(t, memory.NewSecurityMatrixRuleRepository(store), &blockingFilter{}, service.TimeoutOptions{}, service.FilterFailureOptions{}, store)
+	users, err := memory.NewUserRepository(store).FetchEmployeesWithAssociatedProfiles(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rules_channel, _ := calculator.StreamRules(ctx, service.GroupProfilesByUser(users, service.ProfileTypeId), security_model.SecurityMatrixCalculationFilterInput{})
+
+	// The channel is closed once the workers stopped, without waiting for the blocked filters of the users left
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, open := <-rules_channel:
+			if !open {
+				return
+			}
+		case <-deadline:
+			t.Fatal("the workers did not stop once cancelled")
+		}
+	}
+}
+
+func TestCalculateRulesStopsWhenCancelled(t *testing.T) {
+	store := loadGoldenStore(t)
+	calculator := newFilteredCalculator(t, memory.NewSecurityMatrixRuleRepository(store), &blockingFilter{}, service.TimeoutOptions{}, service.FilterFailureOptions{}, store)
+	managers_profiles_users, employees_profiles_users, input := calculationInputs(t, store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan []*security_model.ProfileUserAllowOrDenyRules)
+	go func() {
+		rules, _ := calculator.CalculateRules(ctx, managers_profiles_users, employees_profiles_users, input)
+		done <- rules
+	}()
+
+	select {
+	case rules := <-done:
+		assert.Less(t, len(rules), len(managers_profiles_users)+len(employees_profiles_users), "the profile users left are skipped")
+	case <-time.After(5 * time.Second):
+		t.Fatal("CalculateRules did not return once cancelled")
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+// exportBatchSize is the number of rules read from the repository at once while exporting.
+const exportBatchSize = 10000
+
+type securityMatrixExportService struct {
+	ruleRepo interfaces.SecurityMatrixRuleRepository
+}
+
+// NewSecurityMatrixExportService creates a new instance of SecurityMatrixExportService.
+//
+// Parameters:
+// - _ruleRepo: The SecurityMatrixRuleRepository the matrix is read from.
+//
+// Returns:
+// - services.SecurityMatrixExportService: The new instance of SecurityMatrixExportService.
+func NewSecurityMatrixExportService(_ruleRepo interfaces.SecurityMatrixRuleRepository) services.SecurityMatrixExportService {
+	return &securityMatrixExportService{
+		ruleRepo: _ruleRepo,
+	}
+}
+
+// Export streams the stored security matrix, ordered by UserId and EmployeeId, in the format selected by the options.
+// The rules are read and written in batches, so the matrix is never held in memory as a whole.
+//
+// Parameters:
+// - ctx: The context for managing request lifecycle.
+// - writer: Where the matrix is written to.
+// - options: The format, and whether the rules are enriched with the user email and employee number
+// and their access level decoded into a boolean column per bit.
+//
+// Returns:
+// - error: An error if the format is unknown or the matrix cannot be read or written.
+func (p *securityMatrixExportService) Export(ctx context.Context, writer io.Writer, options security_model.ExportOptions) error {
+	encoder, err := newRuleEncoder(writer, options.Format, exportColumns(options))
+	if err != nil {
+		return err
+	}
+
+	if options.Enrich {
+		err = p.ruleRepo.ReadEnrichedRules(ctx, exportBatchSize, encoder.Encode)
+	} else {
+		enriched := make([]security_model.EnrichedMatrixRule, 0, exportBatchSize)
+		err = p.ruleRepo.ReadRules(ctx, exportBatchSize, func(rules []security_model.GoMatrixRule) error {
+			enriched = enriched[:0]
+			for _, rule := range rules {
+				enriched = append(enriched, security_model.EnrichedMatrixRule{GoMatrixRule: rule})
+			}
+			return encoder.Encode(enriched)
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("error exporting the security matrix: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+// UserRules returns the stored rules of one viewer user, ordered by EmployeeId.
+//
+// Parameters:
+// - ctx: The context for managing request lifecycle.
+// - user_id: The UserId of the viewer user.
+//
+// Returns:
+// - []security_model.GoMatrixRule: The rules of the user, empty when the user sees nobody.
+// - error: An error if the matrix cannot be read.
+func (p *securityMatrixExportService) UserRules(ctx context.Context, user_id string) ([]security_model.GoMatrixRule, error) {
+	rules, err := p.ruleRepo.ReadUserRules(ctx, user_id)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the rules of user %s: %w", user_id, err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+)
+
+// exportStore holds three rules, one of them about an unknown employee.
+func exportStore() *memory.Store {
+	return memory.NewStore(memory.Data{
+		Employees: []model.Employee{{Id: 1, EmployeeNumber: "E0001"}, {Id: 2, EmployeeNumber: "E0002"}},
+		Users: []*model.User{
+			{Id: "USR00000000000000001", Email: "first@example.com", EmployeeId: 1},
+			{Id: "USR00000000000000002", Email: "second, \"quoted\"@example.com", EmployeeId: 2},
+		},
+		Rules: []security_model.GoMatrixRule{
+			{UserId: "USR00000000000000002", EmployeeId: 1, AccessLevelRead: 0},
+			{UserId: "USR00000000000000001", EmployeeId: 2, AccessLevelRead: 7},
+			{UserId: "USR00000000000000001", EmployeeId: 3, AccessLevelRead: 2},
+		},
+	})
+}
+
+func export(t *testing.T, options security_model.ExportOptions) string {
+	var output bytes.Buffer
+	exporter := service.NewSecurityMatrixExportService(memory.NewSecurityMatrixRuleRepository(exportStore()))
+	require.NoError(t, exporter.Export(context.Background(), &output, options))
+
+	return output.String()
+}
+
+func TestExportCSV(t *testing.T) {
+	assert.Equal(t, strings.Join([]string{
+		"UserId,EmployeeId,AccessLevelRead",
+		"USR00000000000000001,2,7",
+		"USR00000000000000001,3,2",
+		"USR00000000000000002,1,0",
+		"",
+	}, "\n"), export(t, security_model.ExportOptions{Format: security_model.ExportFormatCSV}))
+
+	assert.Equal(t, strings.Join([]string{
+		"UserId,EmployeeId,AccessLevelRead,UserEmail,EmployeeNumber,AccessBit0,GenericFields,AccessBit2",
+		"USR00000000000000001,2,7,first@example.com,E0002,true,true,true",
+		"USR00000000000000001,3,2,first@example.com,,false,true,false",
+		`USR00000000000000002,1,0,"second, ""quoted""@example.com",E0001,false,false,false`,
+		"",
+	}, "\n"), export(t, security_model.ExportOptions{Format: security_model.ExportFormatCSV, Enrich: true, DecodeAccess: true}))
+}
+
+func TestExportJSONL(t *testing.T) {
+	assert.Equal(t, strings.Join([]string{
+		`{"UserId":"USR00000000000000001","EmployeeId":2,"AccessLevelRead":7,"UserEmail":"first@example.com","EmployeeNumber":"E0002","AccessBit0":true,"GenericFields":true,"AccessBit2":true}`,
+		`{"UserId":"USR00000000000000001","EmployeeId":3,"AccessLevelRead":2,"UserEmail":"first@example.com","EmployeeNumber":"","AccessBit0":false,"GenericFields":true,"AccessBit2":false}`,
+		`{"UserId":"USR00000000000000002","EmployeeId":1,"AccessLevelRead":0,"UserEmail":"second, \"quoted\"@example.com","EmployeeNumber":"E0001","AccessBit0":false,"GenericFields":false,"AccessBit2":false}`,
+		"",
+	}, "\n"), export(t, security_model.ExportOptions{Format: security_model.ExportFormatJSONL, Enrich: true, DecodeAccess: true}))
+}
+
+func TestExportParquet(t *testing.T) {
+	type exportedRule struct {
+		UserId          string `parquet:"UserId"`
+		EmployeeId      uint64 `parquet:"EmployeeId"`
+		AccessLevelRead uint32 `parquet:"AccessLevelRead"`
+		UserEmail       string `parquet:"UserEmail"`
+		EmployeeNumber  string `parquet:"EmployeeNumber"`
+		AccessBit0      bool   `parquet:"AccessBit0"`
+		GenericFields   bool   `parquet:"GenericFields"`
+		AccessBit2      bool   `parquet:"AccessBit2"`
+	}
+
+	output := export(t, security_model.ExportOptions{Format: security_model.ExportFormatParquet, Enrich: true, DecodeAccess: true})
+	rules, err := parquet.Read[exportedRule](strings.NewReader(output), int64(len(output)))
+	require.NoError(t, err)
+	assert.Equal(t, []exportedRule{
+		{"USR00000000000000001", 2, 7, "first@example.com", "E0002", true, true, true},
+		{"USR00000000000000001", 3, 2, "first@example.com", "", false, true, false},
+		{"USR00000000000000002", 1, 0, "second, \"quoted\"@example.com", "E0001", false, false, false},
+	}, rules)
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	exporter := service.NewSecurityMatrixExportService(memory.NewSecurityMatrixRuleRepository(exportStore()))
+	assert.Error(t, exporter.Export(context.Background(), &bytes.Buffer{}, security_model.ExportOptions{Format: "xml"}))
+}
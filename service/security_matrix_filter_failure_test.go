@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	filters "github.com/nuno-bastos/gin-gonic-wire-api/service/filters"
+)
+
+// failingUserId is a manager of the small_org fixture.
+const failingUserId = "USR00000000000000002"
+
+// failingFilter runs the AllowReportsFilter, but panics or fails for the user of the given id.
+type failingFilter struct {
+	user_id string
+	panics  bool
+}
+
+func (f *failingFilter) Name() string {
+	return "FailingFilter"
+}
+
+func (f *failingFilter) ExecuteFilter(ctx context.Context, profile *model.Profile, user *model.User, input security_model.SecurityMatrixCalculationFilterInput) (security_model.ProfileUserAllowOrDenyRules, error) {
+	if user.Id == f.user_id {
+		if f.panics {
+			panic("index out of range")
+		}
+		return security_model.ProfileUserAllowOrDenyRules{}, errors.New("no reports")
+	}
+
+	return filters.NewAllowReportsFilter().ExecuteFilter(ctx, profile, user, input)
+}
+
+func TestNewFilterFailureOptions(t *testing.T) {
+	assert.False(t, service.NewFilterFailureOptions(&db.Config{}).SkipUser, "aborts by default")
+	assert.False(t, service.NewFilterFailureOptions(&db.Config{Calculation: db.CalculationConfig{FilterFailurePolicy: "abort"}}).SkipUser)
+	assert.True(t, service.NewFilterFailureOptions(&db.Config{Calculation: db.CalculationConfig{FilterFailurePolicy: "skip_user"}}).SkipUser)
+}
+
+func TestFilterFailureAbortsTheCalculation(t *testing.T) {
+	for _, panics := range []bool{true, false} {
+		t.Run(fmt.Sprintf("panics=%t", panics), func(t *testing.T) {
+			store := loadGoldenStore(t)
+			filter := &failingFilter{user_id: failingUserId, panics: panics}
+			calculator := newFilteredCalculator(t, memory.NewSecurityMatrixRuleRepository(store), filter, service.TimeoutOptions{}, service.FilterFailureOptions{}, store)
+
+			err := calculator.CalculateGoSecurityMatrix(context.Background())
+
+			var filter_err *service.FilterError
+			require.True(t, errors.As(err, &filter_err), "%v", err)
+			assert.Equal(t, "FailingFilter", filter_err.Filter)
+			assert.Equal(t, failingUserId, filter_err.UserId)
+			assert.Equal(t, "PRFMANAGER0000000001", filter_err.ProfileId)
+			assert.Equal(t, panics, filter_err.Panic)
+			if panics {
+				assert.Contains(t, filter_err.Stack, "failingFilter")
+			}
+
+			// Rolled back, and not recorded as a successful run
+			assert.Empty(t, store.Rules())
+			assert.Empty(t, store.Runs())
+		})
+	}
+}
+
+func TestFilterFailureSkipsTheUser(t *testing.T) {
+	expected := loadGoldenStore(t)
+	require.NoError(t, newFilteredCalculator(t, memory.NewSecurityMatrixRuleRepository(expected), &failingFilter{}, service.TimeoutOptions{}, service.FilterFailureOptions{}, expected).CalculateGoSecurityMatrix(context.Background()))
+	var expected_rules []security_model.GoMatrixRule
+	for _, rule := range expected.Rules() {
+		if rule.UserId != failingUserId {
+			expected_rules = append(expected_rules, rule)
+		}
+	}
+	require.NotEmpty(t, expected_rules)
+
+	store := loadGoldenStore(t)
+	filter := &failingFilter{user_id: failingUserId, panics: true}
+	calculator := newFilteredCalculator(t, memory.NewSecurityMatrixRuleRepository(store), filter, service.TimeoutOptions{}, service.FilterFailureOptions{SkipUser: true}, store)
+
+	require.NoError(t, calculator.CalculateGoSecurityMatrix(context.Background()))
+
+	// Every rule but those of the failing user is written
+	assert.ElementsMatch(t, expected_rules, store.Rules())
+	require.Len(t, store.Runs(), 1)
+	assert.Equal(t, security_model.RunStatusSucceeded, store.Runs()[0].Status)
+	assert.Equal(t, int64(1), store.Runs()[0].SkippedUsers)
+}
+
+func TestCalculateRulesRecoversFilterPanics(t *testing.T) {
+	store := loadGoldenStore(t)
+	managers_profiles_users, employees_profiles_users, input := calculationInputs(t, store)
+	filter := &failingFilter{user_id: failingUserId, panics: true}
+
+	t.Run("abort", func(t *testing.T) {
+		calculator := newFilteredCalculator(t, memory.NewSecurityMatrixRuleRepository(store), filter, service.TimeoutOptions{}, service.FilterFailureOptions{}, store)
+
+		rules, err := calculator.CalculateRules(context.Background(), managers_profiles_users, employees_profiles_users, input)
+
+		var filter_err *service.FilterError
+		require.True(t, errors.As(err, &filter_err), "%v", err)
+		assert.True(t, filter_err.Panic)
+		assert.Equal(t, "FailingFilter", filter_err.Filter)
+		assert.Nil(t, rules)
+	})
+
+	t.Run("skip_user", func(t *testing.T) {
+		calculator := newFilteredCalculator(t, memory.NewSecurityMatrixRuleRepository(store), filter, service.TimeoutOptions{}, service.FilterFailureOptions{SkipUser: true}, store)
+
+		rules, err := calculator.CalculateRules(context.Background(), managers_profiles_users, employees_profiles_users, input)
+
+		require.NoError(t, err)
+		require.NotEmpty(t, rules)
+		for _, result := range rules {
+			assert.NotEqual(t, failingUserId, result.UserId, "the rules of every profile of the user are left out")
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/fixture"
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	filters "github.com/nuno-bastos/gin-gonic-wire-api/service/filters"
+	helpers "github.com/nuno-bastos/gin-gonic-wire-api/service/helpers"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+	"github.com/nuno-bastos/gin-gonic-wire-api/test/golden"
+)
+
+// TestSecurityMatrixGolden runs the full calculation over every fixture of testdata/golden and compares
+// the written matrix to the golden file of the same name. Regenerate the golden files with:
+//
+//	go test ./service -run Golden -update
+func TestSecurityMatrixGolden(t *testing.T) {
+	fixture_paths, err := filepath.Glob("testdata/golden/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixture_paths) == 0 {
+		t.Fatal("no fixtures in testdata/golden")
+	}
+
+	for _, fixture_path := range fixture_paths {
+		name := strings.TrimSuffix(filepath.Base(fixture_path), ".yaml")
+		golden_path := filepath.Join("testdata", "golden", name+".golden")
+
+		t.Run(name, func(t *testing.T) {
+			loaded, err := fixture.Load(fixture_path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store, err := loaded.Store()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			calculator := newCalculator(store)
+			if err := calculator.CalculateGoSecurityMatrix(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			golden.AssertRules(t, golden_path, store.Rules())
+
+			// The batch path (CalculateRules then Flatten) must produce the same matrix as the streaming path
+			t.Run("CalculateRules", func(t *testing.T) {
+				golden.AssertRules(t, golden_path, calculateRules(t, calculator, store))
+			})
+
+			// Inputs built without a LevelIndex deny the same employees, from LevelToEmployees
+			t.Run("DenyProfileLevelsFilterWithoutLevelIndex", func(t *testing.T) {
+				filter := filters.NewDenyProfileLevelsFilter()
+				managers_profiles_users, _, input := calculationInputs(t, store)
+				without_index := input
+				without_index.LevelIndex = nil
+
+				for _, profile_user := range managers_profiles_users {
+					expected, err := filter.ExecuteFilter(context.Background(), profile_user.Profile, profile_user.User, input)
+					if err != nil {
+						t.Fatal(err)
+					}
+					actual, err := filter.ExecuteFilter(context.Background(), profile_user.Profile, profile_user.User, without_index)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !reflect.DeepEqual(expected, actual) {
+						t.Errorf("profile %s of user %s: got %v, want %v", profile_user.Profile.Id, profile_user.User.Id, actual, expected)
+					}
+				}
+			})
+		})
+	}
+}
+
+// newCalculator assembles the calculator service with the production filters on top of the store.
+func newCalculator(store *memory.Store) services.SecurityMatrixService {
+	return service.NewSecurityMatrixCalculatorService(
+		memory.NewManagerChainRepository(store),
+		memory.NewDirectManagerRepository(store),
+		memory.NewUserRepository(store),
+		memory.NewEmployeeRepository(store),
+		memory.NewSecurityMatrixRuleRepository(store),
+		memory.NewCalculationRunRepository(store),
+		memory.NewSnapshotRepository(store),
+		filters.ProvideManagerFilters(filters.NewAllowReportsFilter(), filters.NewDenyManagersFilter(), filters.NewDenyProfileLevelsFilter()),
+		filters.ProvideEmployeeFilters(filters.NewAllowSelfFilter(), filters.NewDenySelfFilter()),
+		service.ManagerChainOptions{},
+		service.DataQualityOptions{},
+		service.NewPipelineOptions(&db.Config{}),
+		service.ProfileTypeId,
+		service.RetryOptions{},
+		service.TimeoutOptions{},
+		service.FilterFailureOptions{},
+		metrics.NewMetrics(),
+		noop.NewTracerProvider(),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+}
+
+// calculateRules calculates the matrix of the store with CalculateRules and Flatten.
+func calculateRules(tb testing.TB, calculator services.SecurityMatrixService, store *memory.Store) []security_model.GoMatrixRule {
+	managers_profiles_users, employees_profiles_users, input := calculationInputs(tb, store)
+	rules, err := calculator.CalculateRules(context.Background(), managers_profiles_users, employees_profiles_users, input)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return service.RemoveRulesWithDefaultValues(helpers.Flatten(rules))
+}
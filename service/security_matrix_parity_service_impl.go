@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+// parityBatchSize is the number of rules read from each matrix at once while comparing them.
+const parityBatchSize = 10000
+
+type securityMatrixParityService struct {
+	managerChainRepo    interfaces.ManagerChainRepository
+	directManagerRepo   interfaces.DirectManagerRepository
+	userRepo            interfaces.UserRepository
+	ruleRepo            interfaces.SecurityMatrixRuleRepository
+	legacyRuleRepo      interfaces.LegacyMatrixRuleRepository
+	calculator          services.SecurityMatrixService
+	managerChainOptions ManagerChainOptions
+	parityOptions       ParityOptions
+	profileTypes        ProfileTypes
+}
+
+// NewParityOptions creates the ParityOptions from the application configuration.
+// Unless configured, 10 mismatches are listed per user.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - ParityOptions: The parity options.
+func NewParityOptions(config *db.Config) ParityOptions {
+	options := ParityOptions{
+		MaxSamplesPerUser: config.Parity.MaxSamplesPerUser,
+	}
+
+	if options.MaxSamplesPerUser <= 0 {
+		options.MaxSamplesPerUser = 10
+	}
+
+	return options
+}
+
+// NewSecurityMatrixParityService creates a new instance of SecurityMatrixParityService.
+//
+// Parameters:
+// - _managerChainRepo: The ManagerChainRepository.
+// - _directManagerRepo: The DirectManagerRepository.
+// - _userRepo: The UserRepository.
+// - _ruleRepo: The SecurityMatrixRuleRepository the stored matrix is read from.
+// - _legacyRuleRepo: The LegacyMatrixRuleRepository the legacy matrix is read from.
+// - _calculator: The SecurityMatrixService calculating fresh matrices and the rules of each filter.
+// - _managerChainOptions: Where the manager chains come from.
+// - _parityOptions: How much detail the reports hold.
+// - _profileTypes: The IDs of the profile types.
+//
+// Returns:
+// - services.SecurityMatrixParityService: The new instance of SecurityMatrixParityService.
+func NewSecurityMatrixParityService(
+	_managerChainRepo interfaces.ManagerChainRepository,
+	_directManagerRepo interfaces.DirectManagerRepository,
+	_userRepo interfaces.UserRepository,
+	_ruleRepo interfaces.SecurityMatrixRuleRepository,
+	_legacyRuleRepo interfaces.LegacyMatrixRuleRepository,
+	_calculator services.SecurityMatrixService,
+	_managerChainOptions ManagerChainOptions,
+	_parityOptions ParityOptions,
+	_profileTypes ProfileTypes,
+) services.SecurityMatrixParityService {
+	// Comparing must never write: the derived manager chains are not materialized
+	_managerChainOptions.Materialize = false
+
+	return &securityMatrixParityService{
+		managerChainRepo:    _managerChainRepo,
+		directManagerRepo:   _directManagerRepo,
+		userRepo:            _userRepo,
+		ruleRepo:            _ruleRepo,
+		legacyRuleRepo:      _legacyRuleRepo,
+		calculator:          _calculator,
+		managerChainOptions: _managerChainOptions,
+		parityOptions:       _parityOptions,
+		profileTypes:        _profileTypes,
+	}
+}
+
+// CheckParity compares the legacy matrix with GoMatrixRule, or with a fresh in-memory calculation,
+// and reports the mismatches grouped by user and by contributing filter.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - source: The matrix compared with the legacy one: the stored GoMatrixRule table or a fresh calculation.
+//
+// Returns:
+// - security_model.ParityReport: The mismatches found.
+// - error: An error if the source is unknown, or loading the inputs or reading either matrix fails.
+//
+// Function Logic:
+// Both matrices are read in (UserId, EmployeeId) order and merged, a pair without rule counts as an access level of 0.
+// The stored and legacy matrices are streamed, so only the calculated matrix is held in memory as a whole.
+// Once every mismatch of a user is known, the filters of that user's profiles run again on the current data
+// and each mismatch is attributed to the filters producing a rule for its employee.
+func (p *securityMatrixParityService) CheckParity(ctx context.Context, source security_model.ParitySource) (security_model.ParityReport, error) {
+	if _, err := security_model.ParseParitySource(string(source)); err != nil {
+		return security_model.ParityReport{}, err
+	}
+
+	manager_chains, err := FetchManagerChains(ctx, p.managerChainRepo, p.directManagerRepo, p.managerChainOptions)
+	if err != nil {
+		return security_model.ParityReport{}, fmt.Errorf("error fetching manager chains: %w", err)
+	}
+	employees_with_profiles, err := p.userRepo.FetchEmployeesWithAssociatedProfiles(ctx)
+	if err != nil {
+		return security_model.ParityReport{}, fmt.Errorf("error fetching users with associated profiles: %w", err)
+	}
+	users_profiles := GroupProfilesByUser(employees_with_profiles, p.profileTypes)
+	input, err := BuildCalculationInput(manager_chains, employees_with_profiles)
+	if err != nil {
+		return security_model.ParityReport{}, err
+	}
+
+	read_rules := func(ctx context.Context, handle func(rules []security_model.GoMatrixRule) error) error {
+		return p.ruleRepo.ReadRules(ctx, parityBatchSize, handle)
+	}
+	if source == security_model.ParitySourceCalculated {
+		rules, err := p.calculate(ctx, users_profiles, input)
+		if err != nil {
+			return security_model.ParityReport{}, err
+		}
+		read_rules = func(ctx context.Context, handle func(rules []security_model.GoMatrixRule) error) error {
+			return handle(rules)
+		}
+	}
+
+	users_profiles_by_id := make(map[string]*security_model.UserProfiles, len(users_profiles))
+	for _, user_profiles := range users_profiles {
+		users_profiles_by_id[user_profiles.User.Id] = user_profiles
+	}
+	comparison := newParityComparison(p.parityOptions, func(user_id string) map[uint][]string {
+		user_profiles, found := users_profiles_by_id[user_id]
+		if !found {
+			return nil
+		}
+		results, err := p.calculator.CalculateUserRules(ctx, user_profiles, input)
+		if err != nil {
+			return nil // The filter failure was logged, no filter is reported
+		}
+		return contributingFilters(results)
+	})
+
+	if err := p.merge(ctx, comparison, read_rules); err != nil {
+		return security_model.ParityReport{}, err
+	}
+
+	report := comparison.complete()
+	report.Source = source
+	report.LegacyTable = p.legacyRuleRepo.Table()
+
+	return report, nil
+}
+
+// calculate runs the calculation pipeline without writing and returns the rules sorted by UserId and EmployeeId.
+func (p *securityMatrixParityService) calculate(
+	ctx context.Context,
+	users_profiles []*security_model.UserProfiles,
+	input security_model.SecurityMatrixCalculationFilterInput,
+) ([]security_model.GoMatrixRule, error) {
+	var rules []security_model.GoMatrixRule
+	rules_channel, wait := p.calculator.StreamRules(ctx, users_profiles, input)
+	for user_rules := range rules_channel {
+		rules = append(rules, user_rules...)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return compareRuleKeys(rules[i], rules[j]) < 0
+	})
+
+	return rules, nil
+}
+
+// merge reads the legacy matrix and the compared one side by side and hands every pair to the comparison.
+// The legacy matrix is read by a goroutine, one batch ahead of the comparison.
+func (p *securityMatrixParityService) merge(
+	ctx context.Context,
+	comparison *parityComparison,
+	read_rules func(ctx context.Context, handle func(rules []security_model.GoMatrixRule) error) error,
+) error {
+	merge_ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	legacy_batches := make(chan []security_model.GoMatrixRule, 1)
+	legacy_err := make(chan error, 1)
+	go func() {
+		defer close(legacy_batches)
+		legacy_err <- p.legacyRuleRepo.ReadRules(merge_ctx, parityBatchSize, func(rules []security_model.GoMatrixRule) error {
+			// The repository may reuse the batch once handled
+			batch := append([]security_model.GoMatrixRule{}, rules...)
+			select {
+			case legacy_batches <- batch:
+				return nil
+			case <-merge_ctx.Done():
+				return merge_ctx.Err()
+			}
+		})
+	}()
+
+	legacy := &ruleIterator{batches: legacy_batches, name: "legacy matrix " + p.legacyRuleRepo.Table()}
+	compared := &ruleIterator{name: "GoMatrixRule"}
+
+	err := read_rules(merge_ctx, func(rules []security_model.GoMatrixRule) error {
+		for _, rule := range rules {
+			if err := compared.check(rule); err != nil {
+				return err
+			}
+			comparison.report.Rules++
+
+			for {
+				legacy_rule, found, err := legacy.peek()
+				if err != nil {
+					return err
+				}
+				if !found || compareRuleKeys(legacy_rule, rule) > 0 {
+					comparison.compare(rule.UserId, rule.EmployeeId, 0, rule.AccessLevelRead)
+					break
+				}
+
+				legacy.next()
+				comparison.report.LegacyRules++
+				if compareRuleKeys(legacy_rule, rule) == 0 {
+					comparison.compare(rule.UserId, rule.EmployeeId, legacy_rule.AccessLevelRead, rule.AccessLevelRead)
+					break
+				}
+				comparison.compare(legacy_rule.UserId, legacy_rule.EmployeeId, legacy_rule.AccessLevelRead, 0)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error reading GoMatrixRule: %w", err)
+	}
+
+	// The legacy rules left have no counterpart
+	for {
+		legacy_rule, found, err := legacy.peek()
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+
+		legacy.next()
+		comparison.report.LegacyRules++
+		comparison.compare(legacy_rule.UserId, legacy_rule.EmployeeId, legacy_rule.AccessLevelRead, 0)
+	}
+
+	if err := <-legacy_err; err != nil {
+		return fmt.Errorf("error reading the legacy matrix: %w", err)
+	}
+
+	return nil
+}
+
+// ruleIterator walks the batches of rules received from a channel and checks they are sorted,
+// which the merge relies on: a database collation ordering ids differently from Go would break it.
+type ruleIterator struct {
+	batches <-chan []security_model.GoMatrixRule
+	name    string
+	batch   []security_model.GoMatrixRule
+	last    *security_model.GoMatrixRule
+}
+
+// peek returns the current rule, found is false once every batch has been walked.
+func (it *ruleIterator) peek() (rule security_model.GoMatrixRule, found bool, err error) {
+	for len(it.batch) == 0 {
+		batch, open := <-it.batches
+		if !open {
+			return rule, false, nil
+		}
+		it.batch = batch
+	}
+
+	rule = it.batch[0]
+	return rule, true, it.check(rule)
+}
+
+// next moves past the current rule.
+func (it *ruleIterator) next() {
+	it.batch = it.batch[1:]
+}
+
+// check returns an error when the rule is not after the previous one.
+func (it *ruleIterator) check(rule security_model.GoMatrixRule) error {
+	if it.last != nil && compareRuleKeys(*it.last, rule) > 0 {
+		return fmt.Errorf("%s is not ordered by UserId and EmployeeId like the comparison expects: (%s, %d) comes after (%s, %d)",
+			it.name, rule.UserId, rule.EmployeeId, it.last.UserId, it.last.EmployeeId)
+	}
+
+	it.last = &rule
+	return nil
+}
+
+// compareRuleKeys compares the (UserId, EmployeeId) keys of two rules.
+func compareRuleKeys(a, b security_model.GoMatrixRule) int {
+	switch {
+	case a.UserId < b.UserId:
+		return -1
+	case a.UserId > b.UserId:
+		return 1
+	case a.EmployeeId < b.EmployeeId:
+		return -1
+	case a.EmployeeId > b.EmployeeId:
+		return 1
+	default:
+		return 0
+	}
+}
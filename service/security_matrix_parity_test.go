@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/fixture"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+func newParityChecker(store *memory.Store, options service.ParityOptions) services.SecurityMatrixParityService {
+	return service.NewSecurityMatrixParityService(
+		memory.NewManagerChainRepository(store),
+		memory.NewDirectManagerRepository(store),
+		memory.NewUserRepository(store),
+		memory.NewSecurityMatrixRuleRepository(store),
+		memory.NewLegacyMatrixRuleRepository(store),
+		newCalculator(store),
+		service.ManagerChainOptions{},
+		options,
+		service.ProfileTypeId,
+	)
+}
+
+// paritySetup calculates the matrix of the small organization and derives a legacy matrix from it
+// with one rule missing, one access level changed and one rule of an unknown user added.
+func paritySetup(t *testing.T) (memory.Data, []security_model.GoMatrixRule, []security_model.GoMatrixRule) {
+	loaded, err := fixture.Load("testdata/golden/small_org.yaml")
+	require.NoError(t, err)
+	data, err := loaded.Data()
+	require.NoError(t, err)
+
+	store := memory.NewStore(data)
+	require.NoError(t, newCalculator(store).CalculateGoSecurityMatrix(context.Background()))
+	rules := store.Rules()
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].UserId != rules[j].UserId {
+			return rules[i].UserId < rules[j].UserId
+		}
+		return rules[i].EmployeeId < rules[j].EmployeeId
+	})
+	require.Greater(t, len(rules), 2)
+
+	legacy := append([]security_model.GoMatrixRule{}, rules[1:]...)
+	legacy[0].AccessLevelRead ^= uint32(security_model.GenericFields)
+	if legacy[0].AccessLevelRead == 0 {
+		legacy[0].AccessLevelRead = uint32(security_model.All)
+	}
+	legacy = append(legacy,
+		security_model.GoMatrixRule{UserId: "USR99999999999999999", EmployeeId: 1, AccessLevelRead: 7},
+		security_model.GoMatrixRule{UserId: "USR99999999999999999", EmployeeId: 2, AccessLevelRead: 0}, // Same as no rule
+	)
+
+	return data, rules, legacy
+}
+
+func TestCheckParity(t *testing.T) {
+	data, rules, legacy := paritySetup(t)
+	data.Rules, data.LegacyRules = rules, legacy
+
+	for _, source := range []security_model.ParitySource{security_model.ParitySourceStored, security_model.ParitySourceCalculated} {
+		t.Run(string(source), func(t *testing.T) {
+			report, err := newParityChecker(memory.NewStore(data), service.ParityOptions{MaxSamplesPerUser: 1}).CheckParity(context.Background(), source)
+			require.NoError(t, err)
+
+			assert.Equal(t, source, report.Source)
+			assert.Equal(t, len(rules), report.Rules)
+			assert.Equal(t, len(legacy), report.LegacyRules)
+			assert.Equal(t, 3, report.Mismatches)
+			assert.True(t, report.HasMismatches())
+
+			by_user := make(map[string]security_model.ParityUserMismatches)
+			total := 0
+			for _, user := range report.ByUser {
+				by_user[user.UserId] = user
+				total += user.Mismatches
+				assert.LessOrEqual(t, len(user.Samples), 1)
+			}
+			assert.Equal(t, 3, total)
+
+			// The missing and changed rules are attributed to the filters producing them
+			require.Equal(t, rules[0].UserId, rules[1].UserId)
+			changed := by_user[rules[0].UserId]
+			assert.Equal(t, 2, changed.Mismatches)
+			assert.Equal(t, map[security_model.ParityMismatchType]int{security_model.UnexpectedRule: 1, security_model.AccessLevelMismatch: 1}, changed.ByType)
+			require.Len(t, changed.Samples, 1)
+			assert.Equal(t, security_model.UnexpectedRule, changed.Samples[0].Type)
+			assert.Equal(t, rules[0].AccessLevelRead, changed.Samples[0].AccessLevelRead)
+			assert.NotContains(t, changed.Samples[0].Filters, security_model.NoContributingFilter)
+
+			// No filter produces rules for an unknown user
+			unknown := by_user["USR99999999999999999"]
+			assert.Equal(t, 1, unknown.Mismatches)
+			assert.Equal(t, []security_model.ParityMismatch{{
+				UserId: "USR99999999999999999", EmployeeId: 1, Type: security_model.MissingRule,
+				LegacyAccessLevelRead: 7, Filters: []string{security_model.NoContributingFilter},
+			}}, unknown.Samples)
+
+			filters := make(map[string]int)
+			for _, filter := range report.ByFilter {
+				filters[filter.Filter] = filter.Mismatches
+			}
+			assert.Equal(t, 1, filters[security_model.NoContributingFilter])
+			assert.Greater(t, len(filters), 1)
+		})
+	}
+}
+
+func TestCheckParityMatchingMatrices(t *testing.T) {
+	data, rules, _ := paritySetup(t)
+	data.LegacyRules = rules
+
+	// Nothing is stored yet: the stored matrix misses every legacy rule, the calculated one matches
+	checker := newParityChecker(memory.NewStore(data), service.NewParityOptions(&db.Config{}))
+
+	report, err := checker.CheckParity(context.Background(), security_model.ParitySourceStored)
+	require.NoError(t, err)
+	assert.Equal(t, len(rules), report.Mismatches)
+
+	report, err = checker.CheckParity(context.Background(), security_model.ParitySourceCalculated)
+	require.NoError(t, err)
+	assert.False(t, report.HasMismatches())
+	assert.Empty(t, report.ByUser)
+	assert.Empty(t, report.ByFilter)
+
+	_, err = checker.CheckParity(context.Background(), "legacy")
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/fixture"
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	filters "github.com/nuno-bastos/gin-gonic-wire-api/service/filters"
+	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+)
+
+// deadlockVictim is the error of a SQL Server transaction chosen as deadlock victim.
+var deadlockVictim = mssql.Error{Number: 1205, Message: "Transaction was deadlocked on lock resources with another process and has been chosen as the deadlock victim."}
+
+// flakyRuleRepository fails the first writes with the given errors, after reading part of the rules.
+type flakyRuleRepository struct {
+	interfaces.SecurityMatrixRuleRepository
+	errs   []error
+	writes int
+}
+
+func (p *flakyRuleRepository) WriteRulesStream(ctx context.Context, rules_channel <-chan []security_model.GoMatrixRule) error {
+	p.writes++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		<-rules_channel // The transaction fails midway
+		return err
+	}
+
+	return p.SecurityMatrixRuleRepository.WriteRulesStream(ctx, rules_channel)
+}
+
+// flakyUserRepository fails the first fetches with the given errors.
+type flakyUserRepository struct {
+	interfaces.UserRepository
+	errs    []error
+	fetches int
+}
+
+func (p *flakyUserRepository) FetchEmployeesWithAssociatedProfiles(ctx context.Context) ([]*model.User, error) {
+	p.fetches++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+
+	return p.UserRepository.FetchEmployeesWithAssociatedProfiles(ctx)
+}
+
+// newRetryingCalculator assembles the calculator over the store, writing through rule_repo and reading the users from
+// user_repo, retrying up to 3 times without waiting.
+func newRetryingCalculator(t *testing.T, rule_repo interfaces.SecurityMatrixRuleRepository, user_repo interfaces.UserRepository, store *memory.Store) services.SecurityMatrixService {
+	t.Helper()
+
+	return service.NewSecurityMatrixCalculatorService(
+		memory.NewManagerChainRepository(store),
+		memory.NewDirectManagerRepository(store),
+		user_repo,
+		memory.NewEmployeeRepository(store),
+		rule_repo,
+		memory.NewCalculationRunRepository(store),
+		memory.NewSnapshotRepository(store),
+		filters.ProvideManagerFilters(filters.NewAllowReportsFilter(), filters.NewDenyManagersFilter(), filters.NewDenyProfileLevelsFilter()),
+		filters.ProvideEmployeeFilters(filters.NewAllowSelfFilter(), filters.NewDenySelfFilter()),
+		service.ManagerChainOptions{},
+		service.DataQualityOptions{},
+		service.NewPipelineOptions(&db.Config{}),
+		service.ProfileTypeId,
+		service.RetryOptions{Policy: db.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond}},
+		service.TimeoutOptions{},
+		service.FilterFailureOptions{},
+		metrics.NewMetrics(),
+		noop.NewTracerProvider(),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+}
+
+// loadGoldenStore loads the small organization of the golden tests into a new store.
+func loadGoldenStore(t *testing.T) *memory.Store {
+	t.Helper()
+
+	loaded, err := fixture.Load("testdata/golden/small_org.yaml")
+	require.NoError(t, err)
+	store, err := loaded.Store()
+	require.NoError(t, err)
+
+	return store
+}
+
+func TestTransientWriteErrorsAreRetried(t *testing.T) {
+	store := loadGoldenStore(t)
+	expected := loadGoldenStore(t)
+	require.NoError(t, newCalculator(expected).CalculateGoSecurityMatrix(context.Background()))
+
+	rule_repo := &flakyRuleRepository{SecurityMatrixRuleRepository: memory.NewSecurityMatrixRuleRepository(store), errs: []error{deadlockVictim, driver.ErrBadConn}}
+	calculator := newRetryingCalculator(t, rule_repo, memory.NewUserRepository(store), store)
+
+	require.NoError(t, calculator.CalculateGoSecurityMatrix(context.Background()))
+	assert.Equal(t, 3, rule_repo.writes)
+	assert.ElementsMatch(t, expected.Rules(), store.Rules()) // Every rule calculated again on the last attempt
+	require.Len(t, store.Runs(), 1)
+	assert.Equal(t, int64(len(expected.Rules())), store.Runs()[0].Rules)
+}
+
+func TestRetriesAreBounded(t *testing.T) {
+	store := loadGoldenStore(t)
+	rule_repo := &flakyRuleRepository{SecurityMatrixRuleRepository: memory.NewSecurityMatrixRuleRepository(store), errs: []error{deadlockVictim, deadlockVictim, deadlockVictim, deadlockVictim}}
+	calculator := newRetryingCalculator(t, rule_repo, memory.NewUserRepository(store), store)
+
+	var sql_err mssql.Error
+	require.ErrorAs(t, calculator.CalculateGoSecurityMatrix(context.Background()), &sql_err)
+	assert.Equal(t, int32(1205), sql_err.Number)
+	assert.Equal(t, 3, rule_repo.writes)
+	assert.Empty(t, store.Runs())
+}
+
+func TestOnlyTransientErrorsAreRetried(t *testing.T) {
+	store := loadGoldenStore(t)
+	permission_denied := mssql.Error{Number: 229, Message: "The INSERT permission was denied on the object 'GoMatrixRule'."}
+	rule_repo := &flakyRuleRepository{SecurityMatrixRuleRepository: memory.NewSecurityMatrixRuleRepository(store), errs: []error{permission_denied}}
+	calculator := newRetryingCalculator(t, rule_repo, memory.NewUserRepository(store), store)
+
+	var sql_err mssql.Error
+	require.ErrorAs(t, calculator.CalculateGoSecurityMatrix(context.Background()), &sql_err)
+	assert.Equal(t, int32(229), sql_err.Number)
+	assert.Equal(t, 1, rule_repo.writes)
+}
+
+func TestTransientReadErrorsAreRetried(t *testing.T) {
+	store := loadGoldenStore(t)
+	user_repo := &flakyUserRepository{UserRepository: memory.NewUserRepository(store), errs: []error{io.ErrUnexpectedEOF}}
+	calculator := newRetryingCalculator(t, memory.NewSecurityMatrixRuleRepository(store), user_repo, store)
+
+	require.NoError(t, calculator.CalculateGoSecurityMatrix(context.Background()))
+	assert.Equal(t, 2, user_repo.fetches)
+	assert.NotEmpty(t, store.Rules())
+}
+
+func TestRetriesStopWhenCancelled(t *testing.T) {
+	store := loadGoldenStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	user_repo := &flakyUserRepository{UserRepository: memory.NewUserRepository(store), errs: []error{deadlockVictim}}
+	calculator := newRetryingCalculator(t, memory.NewSecurityMatrixRuleRepository(store), user_repo, store)
+	cancel()
+
+	err := calculator.CalculateGoSecurityMatrix(ctx)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.Equal(t, 1, user_repo.fetches)
+}
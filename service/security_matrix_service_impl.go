@@ -2,201 +2,1093 @@ package service
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"runtime"
+	"runtime/debug"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	logging "github.com/nuno-bastos/gin-gonic-wire-api/logging"
+	metrics "github.com/nuno-bastos/gin-gonic-wire-api/metrics"
+	model "github.com/nuno-bastos/gin-gonic-wire-api/model"
 	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
 	interfaces "github.com/nuno-bastos/gin-gonic-wire-api/repo/interface"
 	filters_interface "github.com/nuno-bastos/gin-gonic-wire-api/service/filters/interface"
 	helpers "github.com/nuno-bastos/gin-gonic-wire-api/service/helpers"
 	services "github.com/nuno-bastos/gin-gonic-wire-api/service/interface"
+	tracing "github.com/nuno-bastos/gin-gonic-wire-api/tracing"
 )
 
+// interruptedRunRecordTimeout bounds the record of an interrupted calculation run, once its context is cancelled.
+const interruptedRunRecordTimeout = 5 * time.Second
+
 type securityMatrixCalculatorService struct {
-	managerChainRepo interfaces.ManagerChainRepository
-	userRepo         interfaces.UserRepository
-	ruleRepo         interfaces.SecurityMatrixRuleRepository
-	managerFilters   []filters_interface.IManagerFilter
-	employeeFilters  []filters_interface.IEmployeeFilter
+	managerChainRepo     interfaces.ManagerChainRepository
+	directManagerRepo    interfaces.DirectManagerRepository
+	userRepo             interfaces.UserRepository
+	employeeRepo         interfaces.EmployeeRepository
+	ruleRepo             interfaces.SecurityMatrixRuleRepository
+	runRepo              interfaces.CalculationRunRepository
+	snapshotRepo         interfaces.SnapshotRepository
+	managerFilters       []filters_interface.IManagerFilter
+	employeeFilters      []filters_interface.IEmployeeFilter
+	managerChainOptions  ManagerChainOptions
+	dataQualityOptions   DataQualityOptions
+	pipelineOptions      PipelineOptions
+	profileTypes         ProfileTypes
+	retryOptions         RetryOptions
+	timeoutOptions       TimeoutOptions
+	filterFailureOptions FilterFailureOptions
+	metrics              *metrics.Metrics
+	tracer               trace.Tracer
+	logger               *slog.Logger
+}
+
+// PipelineOptions defines the concurrency settings of the calculation pipeline.
+type PipelineOptions struct {
+	Workers            int // Number of worker goroutines
+	UsersChannelBuffer int // Buffer of the channel feeding users (or profile users) to workers
+	RulesChannelBuffer int // Buffer of the channel receiving the calculated rules
+}
+
+// NewPipelineOptions creates the PipelineOptions from the application configuration.
+// Unset values default to one worker per available CPU core and channel buffers of one item per worker.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - PipelineOptions: The pipeline options.
+func NewPipelineOptions(config *db.Config) PipelineOptions {
+	options := PipelineOptions{
+		Workers:            config.Calculation.Workers,
+		UsersChannelBuffer: config.Calculation.UsersChannelBuffer,
+		RulesChannelBuffer: config.Calculation.RulesChannelBuffer,
+	}
+
+	if options.Workers <= 0 {
+		options.Workers = runtime.NumCPU()
+	}
+	if options.UsersChannelBuffer <= 0 {
+		options.UsersChannelBuffer = options.Workers
+	}
+	if options.RulesChannelBuffer <= 0 {
+		options.RulesChannelBuffer = options.Workers
+	}
+
+	return options
+}
+
+// RetryOptions bounds the retries of the read phase and of the write transaction of the calculation, when they fail
+// with a transient database error (see db.IsTransientError).
+type RetryOptions struct {
+	Policy db.RetryConfig
+}
+
+// TimeoutOptions bounds the duration of the calculation and of its phases, retries included. Zero disables a timeout.
+type TimeoutOptions struct {
+	Read  time.Duration // Reading the inputs from their snapshot
+	Write time.Duration // Calculating the rules and writing them
+	Total time.Duration // The whole calculation
+}
+
+// NewTimeoutOptions creates the TimeoutOptions from the application configuration. Nothing times out by default.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - TimeoutOptions: The timeout options.
+func NewTimeoutOptions(config *db.Config) TimeoutOptions {
+	return TimeoutOptions{
+		Read:  config.Calculation.Timeouts.Read,
+		Write: config.Calculation.Timeouts.Write,
+		Total: config.Calculation.Timeouts.Total,
+	}
+}
+
+// TimeoutError is the cause of a calculation, or of one of its phases, cancelled once its timeout elapsed.
+// It matches context.DeadlineExceeded.
+type TimeoutError struct {
+	Stage   string // "calculation", "read phase" or "write phase"
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("the %s timed out after %s", e.Stage, e.Timeout)
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// NewRetryOptions creates the RetryOptions from the application configuration.
+// Unset values default to 3 attempts, waiting 1s after the first failed one, doubled after each other one up to 30s.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - RetryOptions: The retry options.
+func NewRetryOptions(config *db.Config) RetryOptions {
+	return RetryOptions{
+		Policy: config.Calculation.Retry.WithDefaults(3, time.Second, 30*time.Second),
+	}
+}
+
+// DataQualityOptions defines whether the data quality checks are enforced before the calculation runs.
+type DataQualityOptions struct {
+	// Enforce aborts the calculation with a *DataQualityError when any issue is found.
+	Enforce bool
+}
+
+// NewDataQualityOptions creates the DataQualityOptions from the application configuration.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - DataQualityOptions: The data quality options.
+func NewDataQualityOptions(config *db.Config) DataQualityOptions {
+	return DataQualityOptions{
+		Enforce: config.EnforceDataQuality,
+	}
 }
 
-// ProfileTypeId defines the IDs for different types of profiles.var ProfileTypeId = struct {
-var ProfileTypeId = struct {
+// ProfileTypes defines the IDs for different types of profiles.
+type ProfileTypes struct {
 	ADMIN    string
 	MANAGER  string
 	EMPLOYEE string
-}{
+}
+
+// ProfileTypeId holds the default IDs of the profile types, see NewProfileTypes for the configured ones.
+var ProfileTypeId = ProfileTypes{
 	ADMIN:    "PFT11111111111111111",
 	MANAGER:  "PFT22222222222222222",
 	EMPLOYEE: "PFT33333333333333333",
 }
 
+// NewProfileTypes creates the ProfileTypes from the application configuration, ProfileTypeId unless configured.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - ProfileTypes: The IDs of the profile types.
+func NewProfileTypes(config *db.Config) ProfileTypes {
+	profile_types := ProfileTypeId
+
+	if config.ProfileTypes.Admin != "" {
+		profile_types.ADMIN = config.ProfileTypes.Admin
+	}
+	if config.ProfileTypes.Manager != "" {
+		profile_types.MANAGER = config.ProfileTypes.Manager
+	}
+	if config.ProfileTypes.Employee != "" {
+		profile_types.EMPLOYEE = config.ProfileTypes.Employee
+	}
+
+	return profile_types
+}
+
 // NewSecurityMatrixCalculatorService creates a new instance of SecurityMatrixCalculatorService.
 //
 // Parameters:
 // - _managerChainRepo: The ManagerChainRepository.
+// - _directManagerRepo: The DirectManagerRepository.
 // - _userRepo: The UserRepository.
+// - _employeeRepo: The EmployeeRepository.
 // - _ruleRepo: The SecurityMatrixRuleRepository.
+// - _runRepo: The CalculationRunRepository recording the successful calculations.
+// - _snapshotRepo: The SnapshotRepository reading every input of a calculation from the same snapshot.
 // - _managerFilters: The list of manager filters.
 // - _employeeFilters: The list of employee filters.
+// - _managerChainOptions: Where the manager chains come from.
+// - _dataQualityOptions: Whether the data quality checks are enforced.
+// - _pipelineOptions: The concurrency settings of the calculation pipeline.
+// - _profileTypes: The IDs of the MANAGER and EMPLOYEE profile types, selecting the filters of each profile.
+// - _retryOptions: The retries of the read phase and of the write transaction failing with a transient error.
+// - _timeoutOptions: The timeouts of the calculation and of its phases.
+// - _filterFailureOptions: Whether a failed filter aborts the calculation or skips its user.
+// - _metrics: The metrics recording the stages, rules and outcome of each calculation.
+// - _tracerProvider: The provider of the spans of each stage, worker and filter.
+// - _logger: The logger of the start and outcome of each calculation.
 //
 // Returns:
 // - services.SecurityMatrixService: The new instance of SecurityMatrixService.
 func NewSecurityMatrixCalculatorService(
 	_managerChainRepo interfaces.ManagerChainRepository,
+	_directManagerRepo interfaces.DirectManagerRepository,
 	_userRepo interfaces.UserRepository,
+	_employeeRepo interfaces.EmployeeRepository,
 	_ruleRepo interfaces.SecurityMatrixRuleRepository,
+	_runRepo interfaces.CalculationRunRepository,
+	_snapshotRepo interfaces.SnapshotRepository,
 	_managerFilters []filters_interface.IManagerFilter,
 	_employeeFilters []filters_interface.IEmployeeFilter,
+	_managerChainOptions ManagerChainOptions,
+	_dataQualityOptions DataQualityOptions,
+	_pipelineOptions PipelineOptions,
+	_profileTypes ProfileTypes,
+	_retryOptions RetryOptions,
+	_timeoutOptions TimeoutOptions,
+	_filterFailureOptions FilterFailureOptions,
+	_metrics *metrics.Metrics,
+	_tracerProvider trace.TracerProvider,
+	_logger *slog.Logger,
 ) services.SecurityMatrixService {
 	return &securityMatrixCalculatorService{
-		managerChainRepo: _managerChainRepo,
-		userRepo:         _userRepo,
-		ruleRepo:         _ruleRepo,
-		managerFilters:   _managerFilters,
-		employeeFilters:  _employeeFilters,
+		managerChainRepo:     _managerChainRepo,
+		directManagerRepo:    _directManagerRepo,
+		userRepo:             _userRepo,
+		employeeRepo:         _employeeRepo,
+		ruleRepo:             _ruleRepo,
+		runRepo:              _runRepo,
+		snapshotRepo:         _snapshotRepo,
+		managerFilters:       _managerFilters,
+		employeeFilters:      _employeeFilters,
+		managerChainOptions:  _managerChainOptions,
+		dataQualityOptions:   _dataQualityOptions,
+		pipelineOptions:      _pipelineOptions,
+		profileTypes:         _profileTypes,
+		retryOptions:         _retryOptions,
+		timeoutOptions:       _timeoutOptions,
+		filterFailureOptions: _filterFailureOptions,
+		metrics:              _metrics,
+		tracer:               _tracerProvider.Tracer(tracing.TracerName),
+		logger:               _logger,
 	}
 }
 
 // CalculateGoSecurityMatrix calculates and writes the security matrix rules to the database.
-// Replicates the original service Handle function.
+// Replicates the original service Handle function. Once the rules are written, the run is recorded
+// with its start and end times and the number of rules written, which the freshness report relies on.
+// Every run is counted by outcome in the metrics and traced, each stage being a child span.
+// Every run is identified by the run ID of the context, a new one if it carries none, found on its log lines and spans.
+// When the context is cancelled (e.g. by a shutdown), or the calculation or one of its phases exceeds its timeout,
+// the rules written so far are rolled back and the run is recorded as interrupted. The read phase and the write
+// transaction are retried from the start when they fail with a transient database error (a deadlock, a dropped
+// connection), within the retry options. A filter that fails, returning an error or panicking, aborts the calculation
+// unless the filter failure options skip its user, the skipped users being counted on the run.
 //
 // Parameters:
 // - ctx: The context for the operation.
-func (p *securityMatrixCalculatorService) CalculateGoSecurityMatrix(ctx context.Context) {
-	// Fetch manager chains from repository
-	manager_chains, err := p.managerChainRepo.FindAll(ctx)
+//
+// Returns:
+// - error: An error if any stage fails, a *DataQualityError when data quality is enforced and issues are found,
+// wrapping a *TimeoutError when a timeout elapsed, or wrapping the *FilterError that aborted the calculation.
+func (p *securityMatrixCalculatorService) CalculateGoSecurityMatrix(ctx context.Context) (err error) {
+	started_at := time.Now().UTC()
+	if logging.RunId(ctx) == "" {
+		ctx = logging.WithRunId(ctx, uuid.NewString())
+	}
+	ctx, span := p.tracer.Start(ctx, "CalculateGoSecurityMatrix", trace.WithAttributes(attribute.String("run.id", logging.RunId(ctx))))
+	p.logger.InfoContext(ctx, "Security matrix calculation started")
+
+	if p.timeoutOptions.Total > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, p.timeoutOptions.Total, &TimeoutError{Stage: "calculation", Timeout: p.timeoutOptions.Total})
+		defer cancel()
+	}
+
+	var rule_count int64
+	var skipped []string
+	var snapshot_at *model.Timestamp
+	defer func() {
+		err = timeoutCause(ctx, err)
+		outcome := runOutcome(ctx, err)
+		p.metrics.Runs.WithLabelValues(outcome).Inc()
+		tracing.End(span, err)
+
+		duration := time.Since(started_at)
+		switch outcome {
+		case metrics.OutcomeSuccess:
+			p.logger.InfoContext(ctx, "Security matrix calculation finished", "rules", rule_count, "skipped_users", len(skipped), "duration", duration)
+		case metrics.OutcomeCancelled:
+			p.logger.WarnContext(ctx, "Security matrix calculation interrupted, its rules were rolled back", "duration", duration, "error", err)
+			p.recordInterruptedRun(ctx, started_at, snapshot_at)
+		case metrics.OutcomeError:
+			p.logger.ErrorContext(ctx, "Security matrix calculation failed", "outcome", outcome, "duration", duration, "error", err)
+		default:
+			p.logger.WarnContext(ctx, "Security matrix calculation failed", "outcome", outcome, "duration", duration, "error", err)
+		}
+	}()
+
+	// Fetch the manager chains and the users from the same snapshot, and enforce the data quality checks
+	var manager_chains []model.ManagerChain
+	var employees_with_profiles []*model.User
+	err = p.withTimeout(ctx, metrics.PhaseRead, p.timeoutOptions.Read, func(ctx context.Context) error {
+		return p.retry(ctx, metrics.PhaseRead, func(ctx context.Context) (err error) {
+			manager_chains, employees_with_profiles, snapshot_at, err = p.readInputs(ctx)
+			return err
+		})
+	})
 	if err != nil {
-		log.Fatalf("Error fetching manager chains: %v", err)
+		return err
 	}
+	span.SetAttributes(attribute.String("snapshot.at", snapshot_at.Format(time.RFC3339Nano)))
 
-	// Fetch users who are employees and have associated profiles
-	employees_with_profiles, err := p.userRepo.FetchEmployeesWithAssociatedProfiles(ctx)
+	// a List of the Manager and Employee profiles held by each user (there should be only one Employee profile)
+	users_profiles := GroupProfilesByUser(employees_with_profiles, p.profileTypes)
+
+	// Security Matrix Calculation Input
+	_, stage_span := p.tracer.Start(ctx, "BuildCalculationInput")
+	input, err := BuildCalculationInput(manager_chains, employees_with_profiles)
+	tracing.End(stage_span, err)
 	if err != nil {
-		log.Fatalf("Error fetching users with associated profiles: %v", err)
+		return err
 	}
 
-	// a List of Tuple<Profile, User> where the profile is of type Manager
-	var managers_profiles_users []*security_model.TupleProfileUser
-	// a List of Tuple<Profile, User> where the profile is of type Employee (there should be only one such profile)
-	var employees_profiles_users []*security_model.TupleProfileUser
+	// Calculate and write the rules, again from the start when the write transaction is rolled back by a transient error
+	stage_started_at := time.Now()
+	err = p.withTimeout(ctx, metrics.PhaseWrite, p.timeoutOptions.Write, func(ctx context.Context) error {
+		return p.retry(ctx, metrics.PhaseWrite, func(ctx context.Context) (err error) {
+			rule_count, skipped, err = p.writeRules(ctx, users_profiles, input)
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("error writing rules to database: %w", err)
+	}
+	p.metrics.ObserveStage(metrics.StageWriteRules, stage_started_at)
+	p.metrics.RulesWritten.Add(float64(rule_count))
 
-	for _, user := range employees_with_profiles {
-		for _, profile := range user.Profiles {
-			if profile.ProfileTypeId == ProfileTypeId.MANAGER {
-				managers_profiles_users = append(managers_profiles_users, &security_model.TupleProfileUser{
-					Profile: profile,
-					User:    user,
-				})
-			} else if profile.ProfileTypeId == ProfileTypeId.EMPLOYEE {
-				employees_profiles_users = append(employees_profiles_users, &security_model.TupleProfileUser{
-					Profile: profile,
-					User:    user,
-				})
+	run := &security_model.GoMatrixCalculationRun{
+		RunId:        logging.RunId(ctx),
+		Status:       security_model.RunStatusSucceeded,
+		StartedAt:    model.Timestamp{Time: started_at},
+		FinishedAt:   model.Timestamp{Time: time.Now().UTC()},
+		Rules:        rule_count,
+		SkippedUsers: int64(len(skipped)),
+		SnapshotAt:   snapshot_at,
+	}
+	span.SetAttributes(attribute.Int64("rules", rule_count), attribute.Int("skipped_users", len(skipped)))
+	if err := p.runRepo.RecordRun(ctx, run); err != nil {
+		return fmt.Errorf("the security matrix was written but the calculation run could not be recorded: %w", err)
+	}
+
+	return nil
+}
+
+// retry runs a phase of the calculation until it succeeds, within the retry options when it fails with a transient
+// database error. Each retry is counted in the metrics and logged.
+//
+// Parameters:
+// - ctx: The context of the calculation, whose cancellation stops the retries.
+// - phase: The retried phase, metrics.PhaseRead or metrics.PhaseWrite.
+// - operation: The phase, rerun from the start on each attempt.
+//
+// Returns:
+// - error: The error of the last attempt, nil once an attempt succeeds.
+func (p *securityMatrixCalculatorService) retry(ctx context.Context, phase string, operation func(ctx context.Context) error) error {
+	return db.Retry(ctx, p.retryOptions.Policy, db.IsTransientError, func(attempt int, backoff time.Duration, err error) {
+		p.metrics.Retries.WithLabelValues(phase).Inc()
+		p.logger.WarnContext(ctx, "Transient database error, retrying", "phase", phase, "attempt", attempt, "backoff", backoff, "error", err)
+	}, operation)
+}
+
+// withTimeout runs a phase of the calculation, cancelled once its timeout elapsed.
+//
+// Parameters:
+// - ctx: The context of the calculation.
+// - phase: The phase, metrics.PhaseRead or metrics.PhaseWrite.
+// - timeout: The timeout of the phase, none when not positive.
+// - operation: The phase.
+//
+// Returns:
+// - error: The error of the phase, wrapping a *TimeoutError when its timeout elapsed.
+func (p *securityMatrixCalculatorService) withTimeout(ctx context.Context, phase string, timeout time.Duration, operation func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return operation(ctx)
+	}
+
+	phase_ctx, cancel := context.WithTimeoutCause(ctx, timeout, &TimeoutError{Stage: phase + " phase", Timeout: timeout})
+	defer cancel()
+
+	return timeoutCause(phase_ctx, operation(phase_ctx))
+}
+
+// timeoutCause wraps the error with the *TimeoutError that cancelled the context, unless it already wraps one.
+//
+// Parameters:
+// - ctx: The context the failed operation ran with.
+// - err: The error of the operation, nil if it succeeded.
+//
+// Returns:
+// - error: The error, possibly wrapped.
+func timeoutCause(ctx context.Context, err error) error {
+	var timeout_err *TimeoutError
+	if err == nil || errors.As(err, &timeout_err) || !errors.As(context.Cause(ctx), &timeout_err) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", timeout_err, err)
+}
+
+// readInputs fetches the manager chains and the users who are employees with their associated profiles, and enforces
+// the data quality checks when enabled, all within one read-only snapshot of the database. The derived manager chains
+// are materialized afterwards, when enabled, as the snapshot cannot be written.
+//
+// Parameters:
+// - ctx: The context for the operation, the snapshot is bound to it.
+//
+// Returns:
+// - []model.ManagerChain: The manager chains.
+// - []*model.User: The users who are employees, with their associated profiles.
+// - *model.Timestamp: When the snapshot was taken.
+// - error: An error if any fetch fails, or a *DataQualityError when data quality is enforced and issues are found.
+func (p *securityMatrixCalculatorService) readInputs(ctx context.Context) ([]model.ManagerChain, []*model.User, *model.Timestamp, error) {
+	var manager_chains []model.ManagerChain
+	var employees_with_profiles []*model.User
+
+	snapshot_at, err := p.snapshotRepo.ReadSnapshot(ctx, func(ctx context.Context) error {
+		// Fetch manager chains from repository
+		read_options := p.managerChainOptions
+		read_options.Materialize = false
+		stage_started_at := time.Now()
+		stage_ctx, stage_span := p.tracer.Start(ctx, "FetchManagerChains")
+		var err error
+		manager_chains, err = FetchManagerChains(stage_ctx, p.managerChainRepo, p.directManagerRepo, read_options)
+		tracing.End(stage_span, err)
+		if err != nil {
+			return fmt.Errorf("error fetching manager chains: %w", err)
+		}
+		p.metrics.ObserveStage(metrics.StageFindAll, stage_started_at)
+
+		// Fetch users who are employees and have associated profiles
+		stage_started_at = time.Now()
+		stage_ctx, stage_span = p.tracer.Start(ctx, "FetchEmployeesWithAssociatedProfiles")
+		employees_with_profiles, err = p.userRepo.FetchEmployeesWithAssociatedProfiles(stage_ctx)
+		tracing.End(stage_span, err)
+		if err != nil {
+			return fmt.Errorf("error fetching users with associated profiles: %w", err)
+		}
+		p.metrics.ObserveStage(metrics.StageFetchEmployeesWithAssociatedProfiles, stage_started_at)
+
+		// Refuse to calculate from inconsistent data when enforcement is enabled
+		if p.dataQualityOptions.Enforce {
+			return p.enforceDataQuality(ctx, employees_with_profiles, manager_chains)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Write the derived closure back to Core.ManagerChains
+	if p.managerChainOptions.DeriveFromDirectManagers && p.managerChainOptions.Materialize {
+		if err := p.managerChainRepo.ReplaceAll(ctx, manager_chains); err != nil {
+			return nil, nil, nil, fmt.Errorf("error materializing manager chains: %w", err)
+		}
+	}
+
+	return manager_chains, employees_with_profiles, &model.Timestamp{Time: snapshot_at}, nil
+}
+
+// writeRules streams the condensed rules of each user straight into the database (see WriteRulesStream), so that
+// only the rules of the users currently being processed are held in memory.
+//
+// Parameters:
+// - ctx: The context for the operation. When it is cancelled the stored matrix is left untouched.
+// - users_profiles: The users with their Manager and Employee profiles.
+// - input: The filter input.
+//
+// Returns:
+// - int64: The number of rules written.
+// - []string: The ids of the users skipped, whose rules were not written.
+// - error: An error if the rules cannot be written or a filter failure aborted the calculation, the stored
+// matrix being left untouched.
+func (p *securityMatrixCalculatorService) writeRules(
+	ctx context.Context,
+	users_profiles []*security_model.UserProfiles,
+	input security_model.SecurityMatrixCalculationFilterInput,
+) (int64, []string, error) {
+	stream_ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rules_channel, wait := p.StreamRules(stream_ctx, users_profiles, input)
+	write_ctx, write_span := p.tracer.Start(stream_ctx, "WriteRules")
+
+	// Count the rules on their way to the repository; once cancelled, the rules left are drained instead
+	var rule_count int64
+	counted_channel := make(chan []security_model.GoMatrixRule)
+	go func() {
+		defer close(counted_channel)
+		for rules := range rules_channel {
+			select {
+			case counted_channel <- rules:
+				rule_count += int64(len(rules))
+				p.metrics.ChannelDepth.WithLabelValues(metrics.ChannelRules).Set(float64(len(rules_channel)))
+			case <-stream_ctx.Done():
 			}
 		}
+		// A calculation aborted by a filter failure must not be committed
+		if _, err := wait(); err != nil {
+			cancel()
+		}
+	}()
+
+	write_err := p.ruleRepo.WriteRulesStream(write_ctx, counted_channel)
+
+	// Stop the workers (if the write failed early) and wait for them to exit
+	cancel()
+	for range counted_channel {
 	}
+	skipped, stream_err := wait()
+	if stream_err != nil {
+		write_err = stream_err
+	}
+	p.metrics.ChannelDepth.WithLabelValues(metrics.ChannelRules).Set(0)
+	write_span.SetAttributes(attribute.Int64("rules", rule_count))
+	tracing.End(write_span, write_err)
 
-	// Security Matrix Calculation Input
-	// 3 Dictionaries derived from managerChains
+	return rule_count, skipped, write_err
+}
+
+// recordInterruptedRun records the cancelled calculation started at the given time as interrupted.
+// The record outlives the cancellation of the context, within interruptedRunRecordTimeout.
+//
+// Parameters:
+// - ctx: The cancelled context of the calculation.
+// - started_at: When the calculation started.
+// - snapshot_at: When the snapshot of its inputs was taken, nil if it was interrupted while reading them.
+func (p *securityMatrixCalculatorService) recordInterruptedRun(ctx context.Context, started_at time.Time, snapshot_at *model.Timestamp) {
+	record_ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), interruptedRunRecordTimeout)
+	defer cancel()
+
+	run := &security_model.GoMatrixCalculationRun{
+		RunId:      logging.RunId(ctx),
+		Status:     security_model.RunStatusInterrupted,
+		StartedAt:  model.Timestamp{Time: started_at},
+		FinishedAt: model.Timestamp{Time: time.Now().UTC()},
+		SnapshotAt: snapshot_at,
+	}
+	if err := p.runRepo.RecordRun(record_ctx, run); err != nil {
+		p.logger.ErrorContext(ctx, "The interrupted calculation run could not be recorded", "error", err)
+	}
+}
+
+// enforceDataQuality fetches the employees and returns a *DataQualityError when the data quality checks find issues.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - employees_with_profiles: The users who are employees, with their associated profiles.
+// - manager_chains: The manager chains.
+//
+// Returns:
+// - error: An error if the employees cannot be fetched, a *DataQualityError if any issue is found, nil otherwise.
+func (p *securityMatrixCalculatorService) enforceDataQuality(ctx context.Context, employees_with_profiles []*model.User, manager_chains []model.ManagerChain) (err error) {
+	ctx, span := p.tracer.Start(ctx, "CheckDataQuality")
+	defer func() { tracing.End(span, err) }()
+
+	stage_started_at := time.Now()
+	employees, err := p.employeeRepo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching employees: %w", err)
+	}
+	p.metrics.ObserveStage(metrics.StageFindAll, stage_started_at)
+
+	if report := CheckDataQuality(employees_with_profiles, employees, manager_chains, p.profileTypes); report.HasIssues() {
+		return &DataQualityError{Report: report}
+	}
+
+	return nil
+}
+
+// runOutcome classifies the result of a calculation for the runs metric.
+//
+// Parameters:
+// - ctx: The context of the calculation.
+// - err: The error returned by the calculation, nil if it succeeded.
+//
+// Returns:
+// - string: One of the metrics.Outcome constants.
+func runOutcome(ctx context.Context, err error) string {
+	var data_quality_error *DataQualityError
+
+	switch {
+	case err == nil:
+		return metrics.OutcomeSuccess
+	case errors.As(err, &data_quality_error):
+		return metrics.OutcomeDataQuality
+	case ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded):
+		return metrics.OutcomeCancelled
+	default:
+		return metrics.OutcomeError
+	}
+}
+
+// BuildCalculationInput builds the SecurityMatrixCalculationFilterInput shared by every filter, once per calculation:
+// the manager and report indexes, the employees of each level and the level index.
+//
+// Parameters:
+// - manager_chains: The manager chains.
+// - employees_with_profiles: The users who are employees, with their associated profiles.
+//
+// Returns:
+// - security_model.SecurityMatrixCalculationFilterInput: The filter input.
+// - error: An error if any of the maps cannot be generated.
+func BuildCalculationInput(manager_chains []model.ManagerChain, employees_with_profiles []*model.User) (security_model.SecurityMatrixCalculationFilterInput, error) {
+	// 3 Dictionaries derived from managerChains and the users' profiles
 	managers_map, err := helpers.GetEmployeeToManagersLevelMap(manager_chains)
 	if err != nil {
-		log.Fatalf("Error generating managers map: %v", err)
+		return security_model.SecurityMatrixCalculationFilterInput{}, fmt.Errorf("error generating managers map: %w", err)
 	}
 	reports_map, err := helpers.GetEmployeeToReportsLevelMap(manager_chains)
 	if err != nil {
-		log.Fatalf("Error generating reports map: %v", err)
+		return security_model.SecurityMatrixCalculationFilterInput{}, fmt.Errorf("error generating reports map: %w", err)
 	}
 	levels_map, err := helpers.GetLevelToEmployeesMap(employees_with_profiles)
 	if err != nil {
-		log.Fatalf("Error generating level to employees map: %v", err)
+		return security_model.SecurityMatrixCalculationFilterInput{}, fmt.Errorf("error generating level to employees map: %w", err)
 	}
 
-	input := security_model.SecurityMatrixCalculationFilterInput{
+	return security_model.SecurityMatrixCalculationFilterInput{
 		EmployeeToManagers: managers_map,
 		EmployeeToReports:  reports_map,
 		LevelToEmployees:   levels_map,
+		LevelIndex:         security_model.NewLevelIndex(levels_map),
+	}, nil
+}
+
+// GroupProfilesByUser groups the Manager and Employee profiles of each user, skipping users without any.
+//
+// Parameters:
+// - users: List of users with their associated profiles.
+// - profile_types: The IDs of the profile types.
+//
+// Returns:
+// - []*security_model.UserProfiles: The profiles of each user, in the order of the input.
+func GroupProfilesByUser(users []*model.User, profile_types ProfileTypes) []*security_model.UserProfiles {
+	var users_profiles []*security_model.UserProfiles
+
+	for _, user := range users {
+		user_profiles := &security_model.UserProfiles{User: user}
+		for _, profile := range user.Profiles {
+			if profile.ProfileTypeId == profile_types.MANAGER {
+				user_profiles.ManagerProfiles = append(user_profiles.ManagerProfiles, profile)
+			} else if profile.ProfileTypeId == profile_types.EMPLOYEE {
+				user_profiles.EmployeeProfiles = append(user_profiles.EmployeeProfiles, profile)
+			}
+		}
+
+		if len(user_profiles.ManagerProfiles) > 0 || len(user_profiles.EmployeeProfiles) > 0 {
+			users_profiles = append(users_profiles, user_profiles)
+		}
+	}
+
+	return users_profiles
+}
+
+// StreamRules calculates, condenses and emits the final security matrix rules one user at a time.
+//
+// Parameters:
+// - ctx: The context for the operation, cancelling it stops the workers.
+// - usersProfiles: List of users with their manager and employee profiles.
+// - input: The SecurityMatrixCalculationFilterInput.
+//
+// Returns:
+// - <-chan []security_model.GoMatrixRule: A channel receiving the final rules of each user. It is closed
+// once every user has been processed, the context is cancelled or a filter failure aborted the calculation.
+// - func() ([]string, error): Returns, once the channel is closed, the ids of the users skipped and the *FilterError
+// that aborted the calculation, nil if none did.
+//
+// Function Logic:
+// Rules are sharded by viewer user: every rule produced by a user's profiles has that user as UserId,
+// so the rules of one user can be flattened and cleaned of default values independently from the others.
+// Worker goroutines (PipelineOptions.Workers, one per available CPU core by default) receive users from a bounded channel, run every filter of
+// each of the user's profiles, flatten the result and send it to a bounded rules channel.
+// Peak memory is therefore proportional to the fan-out of the users being processed, not to the whole matrix.
+// A filter returning an error or panicking fails its user: the panic is recovered by the worker, attributed to the filter,
+// counted in the metrics and logged. The calculation is then aborted, or the user is skipped under the skip_user policy.
+// The duration of each stage, the busy workers and the depth of both channels are recorded in the metrics.
+// The pipeline is traced as a StreamRules span, with a child span per worker batch and per filter execution.
+func (p *securityMatrixCalculatorService) StreamRules(
+	ctx context.Context,
+	usersProfiles []*security_model.UserProfiles,
+	input security_model.SecurityMatrixCalculationFilterInput,
+) (<-chan []security_model.GoMatrixRule, func() ([]string, error)) {
+	// Declare a wait group to synchronize goroutines
+	var wg sync.WaitGroup
+
+	// Determine the number of worker goroutines from the pipeline options
+	num_workers := p.pipelineOptions.Workers
+
+	// Bounded channels: users waiting to be processed and condensed rules waiting to be written
+	users_channel := make(chan *security_model.UserProfiles, p.pipelineOptions.UsersChannelBuffer)
+	rules_channel := make(chan []security_model.GoMatrixRule, p.pipelineOptions.RulesChannelBuffer)
+
+	ctx, span := p.tracer.Start(ctx, "StreamRules", trace.WithAttributes(
+		attribute.Int("users", len(usersProfiles)),
+		attribute.Int("workers", num_workers),
+	))
+
+	// The first filter failure cancels the workers, unless the users are skipped
+	ctx, abort := context.WithCancelCause(ctx)
+	failures := &filterFailures{skip_user: p.filterFailureOptions.SkipUser, abort: abort}
+
+	// Define the worker function
+	worker := func(worker_id int) {
+		defer wg.Done() // Notify the wait group when the worker finishes
+
+		worker_ctx, worker_span := p.tracer.Start(ctx, "worker", trace.WithAttributes(attribute.Int("worker", worker_id)))
+		users, rules := 0, 0
+		defer func() {
+			worker_span.SetAttributes(attribute.Int("users", users), attribute.Int("rules", rules))
+			worker_span.End()
+		}()
+
+		for user_profiles := range users_channel {
+			if ctx.Err() != nil {
+				return // The users left are not calculated
+			}
+			p.metrics.ChannelDepth.WithLabelValues(metrics.ChannelUsers).Set(float64(len(users_channel)))
+			p.metrics.BusyWorkers.Inc()
+			user_rules, err := p.condenseUserRules(worker_ctx, user_profiles, input)
+			p.metrics.BusyWorkers.Dec()
+			if err != nil {
+				if ctx.Err() != nil {
+					return // Cancelled while calculating the user
+				}
+				p.failUser(worker_ctx, failures, user_profiles.User, err)
+				continue
+			}
+			users, rules = users+1, rules+len(user_rules)
+			if len(user_rules) == 0 {
+				continue
+			}
+
+			select {
+			case rules_channel <- user_rules:
+				p.metrics.ChannelDepth.WithLabelValues(metrics.ChannelRules).Set(float64(len(rules_channel)))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	// Create worker goroutines
+	p.metrics.Workers.Set(float64(num_workers))
+	for i := 0; i < num_workers; i++ {
+		wg.Add(1) // Increment the wait group counter for each worker
+		go worker(i)
+	}
+
+	// Distribute users to workers until done or cancelled
+	go func() {
+		defer close(users_channel)
+		for _, user_profiles := range usersProfiles {
+			select {
+			case users_channel <- user_profiles:
+				p.metrics.ChannelDepth.WithLabelValues(metrics.ChannelUsers).Set(float64(len(users_channel)))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait() // Wait for all workers to finish
+		p.metrics.Workers.Set(0)
+		p.metrics.ChannelDepth.WithLabelValues(metrics.ChannelUsers).Set(0)
+		tracing.End(span, context.Cause(ctx))
+		abort(nil)
+		close(rules_channel) // Close the rules channel after all rules are calculated
+	}()
+
+	return rules_channel, failures.result
+}
+
+// condenseUserRules calculates the rules of a single user, flattens them and removes those with default values,
+// timing each stage. A panic is recovered and returned as a *FilterError of the user.
+//
+// Parameters:
+// - ctx: The context of the worker.
+// - userProfiles: The user with their manager and employee profiles.
+// - input: The SecurityMatrixCalculationFilterInput.
+//
+// Returns:
+// - []security_model.GoMatrixRule: The final rules of the user.
+// - error: The *FilterError of the failed filter, or the error of ctx once cancelled.
+func (p *securityMatrixCalculatorService) condenseUserRules(
+	ctx context.Context,
+	userProfiles *security_model.UserProfiles,
+	input security_model.SecurityMatrixCalculationFilterInput,
+) (user_rules []security_model.GoMatrixRule, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			user_rules, err = nil, newPanicError("", nil, userProfiles.User, recovered, debug.Stack())
+		}
+	}()
+
+	started_at := time.Now()
+	rules, err := p.CalculateUserRules(ctx, userProfiles, input)
+	p.metrics.ObserveStage(metrics.StageCalculateRules, started_at)
+	if err != nil {
+		return nil, err
+	}
+
+	started_at = time.Now()
+	flattened := helpers.Flatten(rules)
+	p.metrics.ObserveStage(metrics.StageFlatten, started_at)
+
+	started_at = time.Now()
+	user_rules = RemoveRulesWithDefaultValues(flattened)
+	p.metrics.ObserveStage(metrics.StageRemoveRulesWithDefaultValues, started_at)
+
+	return user_rules, nil
+}
+
+// failUser counts, logs and records the failure of the rules of a user, aborting the calculation unless the user is skipped.
+//
+// Parameters:
+// - ctx: The context of the worker.
+// - failures: The filter failures of the calculation.
+// - user: The user whose rules failed.
+// - err: The failure, a *FilterError unless it happened outside of the filters.
+func (p *securityMatrixCalculatorService) failUser(ctx context.Context, failures *filterFailures, user *model.User, err error) {
+	var filter_err *FilterError
+	if !errors.As(err, &filter_err) {
+		filter_err = &FilterError{UserId: user.Id, Err: err}
+	}
+
+	kind := metrics.FailureError
+	if filter_err.Panic {
+		kind = metrics.FailurePanic
 	}
+	p.metrics.FilterFailures.WithLabelValues(filter_err.Filter, kind).Inc()
 
-	rules_list := p.CalculateRules(managers_profiles_users, employees_profiles_users, input)
-	flattened_rules := helpers.Flatten(rules_list)
-	final_rules := RemoveRulesWithDefaultValues(flattened_rules)
-	if err := p.ruleRepo.WriteRules(ctx, final_rules); err != nil {
-		log.Fatalf("Error writing rules to database: %v", err)
+	message := "Filter failed, the calculation is aborted"
+	if p.filterFailureOptions.SkipUser {
+		message = "Filter failed, the rules of the user are skipped"
 	}
+	attributes := []any{"filter", filter_err.Filter, "user_id", filter_err.UserId, "profile_id", filter_err.ProfileId, "kind", kind, "error", filter_err.Err}
+	if filter_err.Panic {
+		attributes = append(attributes, "stack", filter_err.Stack)
+	}
+	p.logger.ErrorContext(ctx, message, attributes...)
+
+	failures.add(filter_err)
+}
+
+// CalculateUserRules runs the manager filters for each manager profile and the employee filters
+// for each employee profile of a single user.
+//
+// Parameters:
+// - ctx: The context of the calculation, parent of the filter spans.
+// - userProfiles: The user with their manager and employee profiles.
+// - input: The SecurityMatrixCalculationFilterInput.
+//
+// Returns:
+// - []*security_model.ProfileUserAllowOrDenyRules: List of calculated rules for the user.
+// - error: The *FilterError of the first filter that returned an error or panicked, or the error of ctx once cancelled.
+//
+// The rules emitted by each filter are counted in the metrics. Once ctx is cancelled, the filters left are skipped.
+func (p *securityMatrixCalculatorService) CalculateUserRules(
+	ctx context.Context,
+	userProfiles *security_model.UserProfiles,
+	input security_model.SecurityMatrixCalculationFilterInput,
+) ([]*security_model.ProfileUserAllowOrDenyRules, error) {
+	var rules_list []*security_model.ProfileUserAllowOrDenyRules
+
+	for _, profile := range userProfiles.ManagerProfiles {
+		for _, filter := range p.managerFilters {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			result, filter_err := p.executeFilter(ctx, filter, profile, userProfiles.User, input)
+			if filter_err != nil {
+				return nil, filter_err
+			}
+			rules_list = append(rules_list, &result)
+		}
+	}
+	for _, profile := range userProfiles.EmployeeProfiles {
+		for _, filter := range p.employeeFilters {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			result, filter_err := p.executeFilter(ctx, filter, profile, userProfiles.User, input)
+			if filter_err != nil {
+				return nil, filter_err
+			}
+			rules_list = append(rules_list, &result)
+		}
+	}
+
+	return rules_list, nil
+}
+
+// executeFilter runs a filter in its own span and counts the rules it emits. A panic of the filter is recovered.
+//
+// Parameters:
+// - ctx: The context of the calculation, parent of the filter span.
+// - filter: The manager or employee filter.
+// - profile: The profile the filter is run for.
+// - user: The user holding the profile.
+// - input: The SecurityMatrixCalculationFilterInput.
+//
+// Returns:
+// - security_model.ProfileUserAllowOrDenyRules: The rules emitted by the filter.
+// - *FilterError: The error returned by the filter or its recovered panic, nil if it succeeded.
+func (p *securityMatrixCalculatorService) executeFilter(
+	ctx context.Context,
+	filter filters_interface.SecurityMatrixCalculationFilter,
+	profile *model.Profile,
+	user *model.User,
+	input security_model.SecurityMatrixCalculationFilterInput,
+) (result security_model.ProfileUserAllowOrDenyRules, filter_err *FilterError) {
+	ctx, span := p.tracer.Start(ctx, "ExecuteFilter")
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result, filter_err = security_model.ProfileUserAllowOrDenyRules{}, newPanicError(filter.Name(), profile, user, recovered, debug.Stack())
+		}
+		if filter_err != nil {
+			tracing.End(span, filter_err)
+		} else {
+			span.End()
+		}
+	}()
+
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("filter", filter.Name()),
+			attribute.String("profile.id", profile.Id),
+			attribute.String("user.id", user.Id),
+		)
+	}
+
+	result, err := filter.ExecuteFilter(ctx, profile, user, input)
+	if err != nil {
+		return security_model.ProfileUserAllowOrDenyRules{}, &FilterError{Filter: filter.Name(), UserId: user.Id, ProfileId: profile.Id, Err: err}
+	}
+	p.metrics.RulesEmitted.WithLabelValues(result.OriginFilter).Add(float64(len(result.AllowOrDenyRules)))
+	span.SetAttributes(attribute.Int("rules", len(result.AllowOrDenyRules)))
+
+	return result, nil
 }
 
 // CalculateRules calculates security matrix rules for managers and employees by applying filters.
 //
 // Parameters:
+// - ctx: The context of the calculation, parent of the worker and filter spans.
 // - managersProfilesUsers: List of manager profiles and users.
 // - employeesProfilesUsers: List of employee profiles and users.
 // - input: The SecurityMatrixCalculationFilterInput.
 //
 // Returns:
 // - []*security_model.ProfileUserAllowOrDenyRules: List of calculated rules.
+// - error: The *FilterError that aborted the calculation, nil under the skip_user policy, the rules of the users
+// whose filters failed being left out instead.
 //
 // Function Logic:
 // It concurrently calculates security matrix rules for managers and employees using filters.
-// It creates worker goroutines to process the input data concurrently. The number of workers is determined by the number of available CPU cores.
+// It creates worker goroutines to process the input data concurrently. The number of workers is set by the PipelineOptions
+// (the number of available CPU cores by default).
 // Each worker receives a channel of TupleProfileUser and sends calculated rules to the rules channel.
 // The profile user data is distributed to workers through a channel.
 // Once all data is processed, the rules channel is closed and the collected rules are returned.
+// A filter returning an error or panicking fails its user, the panic being recovered, as in StreamRules.
+// Once ctx is cancelled, the distribution and the workers stop and only the rules calculated so far are returned.
 func (p *securityMatrixCalculatorService) CalculateRules(
+	ctx context.Context,
 	managersProfilesUsers, employeesProfilesUsers []*security_model.TupleProfileUser,
 	input security_model.SecurityMatrixCalculationFilterInput,
-) []*security_model.ProfileUserAllowOrDenyRules {
+) (rules_list []*security_model.ProfileUserAllowOrDenyRules, err error) {
 	// Declare a wait group to synchronize goroutines
 	var wg sync.WaitGroup
 
-	// Create a channel to receive calculated rules
-	rules_channel := make(chan *security_model.ProfileUserAllowOrDenyRules, len(managersProfilesUsers)+len(employeesProfilesUsers))
+	// Determine the number of worker goroutines from the pipeline options
+	num_workers := p.pipelineOptions.Workers
+
+	// Create a bounded channel to receive calculated rules
+	rules_channel := make(chan *security_model.ProfileUserAllowOrDenyRules, p.pipelineOptions.RulesChannelBuffer)
+
+	// Create a bounded channel to distribute profile user data to workers
+	profile_user_channel := make(chan *security_model.TupleProfileUser, p.pipelineOptions.UsersChannelBuffer)
 
-	// Create a channel to distribute profile user data to workers
-	profile_user_channel := make(chan *security_model.TupleProfileUser, len(managersProfilesUsers)+len(employeesProfilesUsers))
+	ctx, span := p.tracer.Start(ctx, "CalculateRules", trace.WithAttributes(
+		attribute.Int("profile_users", len(managersProfilesUsers)+len(employeesProfilesUsers)),
+		attribute.Int("workers", num_workers),
+	))
+	defer func() { tracing.End(span, err) }()
 
-	// Determine the number of worker goroutines based on available CPU cores
-	num_workers := runtime.NumCPU()
+	// The first filter failure cancels the workers, unless the users are skipped
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	failures := &filterFailures{skip_user: p.filterFailureOptions.SkipUser, abort: abort}
 
 	// Define the worker function
-	worker := func(profile_user_channel <-chan *security_model.TupleProfileUser, rules_channel chan<- *security_model.ProfileUserAllowOrDenyRules) {
+	worker := func(worker_id int, profile_user_channel <-chan *security_model.TupleProfileUser, rules_channel chan<- *security_model.ProfileUserAllowOrDenyRules) {
 		defer wg.Done() // Notify the wait group when the worker finishes
 
+		worker_ctx, worker_span := p.tracer.Start(ctx, "worker", trace.WithAttributes(attribute.Int("worker", worker_id)))
+		defer worker_span.End()
+
 		// Process each profile user received from the channel
 		for profile_user := range profile_user_channel {
-			// Execute filters based on the profile type and send the calculated rules to the rules channel
-			if profile_user.Profile.ProfileTypeId == ProfileTypeId.MANAGER {
+			if worker_ctx.Err() != nil {
+				return // The profile users left are not calculated
+			}
+
+			// Select the filters based on the profile type
+			var profile_filters []filters_interface.SecurityMatrixCalculationFilter
+			if profile_user.Profile.ProfileTypeId == p.profileTypes.MANAGER {
 				for _, filter := range p.managerFilters {
-					result := filter.ExecuteFilter(profile_user.Profile, profile_user.User, input)
-					rules_channel <- &result
+					profile_filters = append(profile_filters, filter)
 				}
-			} else if profile_user.Profile.ProfileTypeId == ProfileTypeId.EMPLOYEE {
+			} else if profile_user.Profile.ProfileTypeId == p.profileTypes.EMPLOYEE {
 				for _, filter := range p.employeeFilters {
-					result := filter.ExecuteFilter(profile_user.Profile, profile_user.User, input)
-					rules_channel <- &result
+					profile_filters = append(profile_filters, filter)
 				}
 			}
+
+			// Execute the filters and send the calculated rules to the rules channel
+			for _, filter := range profile_filters {
+				result, filter_err := p.executeFilter(worker_ctx, filter, profile_user.Profile, profile_user.User, input)
+				if filter_err != nil {
+					if worker_ctx.Err() == nil {
+						p.failUser(worker_ctx, failures, profile_user.User, filter_err)
+					}
+					break // The filters left are not run for the profile
+				}
+				rules_channel <- &result
+			}
 		}
 	}
 
 	// Create worker goroutines
 	for i := 0; i < num_workers; i++ {
 		wg.Add(1) // Increment the wait group counter for each worker
-		go worker(profile_user_channel, rules_channel)
+		go worker(i, profile_user_channel, rules_channel)
 	}
 
 	// Distribute profile_user data to workers
 	go func() {
-		// Send profile user data to the profile user channel
-		for _, profile_user := range append(managersProfilesUsers, employeesProfilesUsers...) {
-			profile_user_channel <- profile_user
+		defer close(profile_user_channel) // Close the channel after sending all data
+
+		// Send profile user data to the profile user channel until done or cancelled
+		for _, profile_users := range [][]*security_model.TupleProfileUser{managersProfilesUsers, employeesProfilesUsers} {
+			for _, profile_user := range profile_users {
+				select {
+				case profile_user_channel <- profile_user:
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
-		close(profile_user_channel) // Close the channel after sending all data
 	}()
 
 	// Collect calculated rules from the rules channel
-	var rules_list []*security_model.ProfileUserAllowOrDenyRules
 	go func() {
 		wg.Wait()            // Wait for all workers to finish
 		close(rules_channel) // Close the rules channel after all rules are calculated
@@ -207,8 +1099,22 @@ func (p *securityMatrixCalculatorService) CalculateRules(
 		rules_list = append(rules_list, result)
 	}
 
+	// Abort, or leave out the rules of the users skipped
+	if _, err := failures.result(); err != nil {
+		return nil, err
+	}
+	if skipped_users := failures.skippedUsers(); len(skipped_users) > 0 {
+		kept := rules_list[:0]
+		for _, result := range rules_list {
+			if !skipped_users[result.UserId] {
+				kept = append(kept, result)
+			}
+		}
+		rules_list = kept
+	}
+
 	// Return the collected rules
-	return rules_list
+	return rules_list, nil
 }
 
 // RemoveRulesWithDefaultValues removes rules with default access level values.
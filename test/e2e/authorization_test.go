@@ -0,0 +1,170 @@
+package e2e
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	middleware "github.com/nuno-bastos/gin-gonic-wire-api/api/middleware"
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/fixture"
+	service "github.com/nuno-bastos/gin-gonic-wire-api/service"
+	"github.com/nuno-bastos/gin-gonic-wire-api/test/jwkstest"
+	wire_di "github.com/nuno-bastos/gin-gonic-wire-api/wire_di"
+)
+
+const (
+	adminUser   = "USR00000000000000007" // ADMIN and MANAGER, not an employee
+	managerUser = "USR00000000000000002" // MANAGER of employees 4 and 5
+)
+
+// noAuth disables the authentication of the servers of the tests that are not about it.
+var noAuth = db.AuthConfig{Disabled: true}
+
+// authConfig accepts an API key per user of small_org.yaml, one without user, and tokens of the JWKS.
+func authConfig(jwks *jwkstest.Server) *db.Config {
+	return &db.Config{Auth: db.AuthConfig{
+		APIKeys: []db.APIKeyConfig{
+			{Name: "admin", Key: "admin-key", UserId: adminUser},
+			{Name: "manager", Key: "manager-key", UserId: managerUser},
+			{Name: "orphan", Key: "orphan-key"},
+		},
+		JWT: db.JWTConfig{JWKSURL: jwks.URL(), Issuer: "https://issuer.example.com", Audience: "security-matrix-api"},
+	}}
+}
+
+func serveAs(handler http.Handler, method string, path string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, basePath+path, nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func apiKey(key string) map[string]string {
+	return map[string]string{middleware.APIKeyHeader: key}
+}
+
+func TestAuthorization(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	handler, store := newServer(t, "testdata/small_org.yaml", authConfig(jwks))
+
+	// Nothing is written without credentials, or by callers who are not administrators
+	for _, headers := range []map[string]string{nil, apiKey("wrong-key")} {
+		assert.Equal(t, http.StatusUnauthorized, serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", headers).Code)
+	}
+	assert.Equal(t, http.StatusForbidden, serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", apiKey("orphan-key")).Code)
+	for _, path := range []string{"/DataQualityReport", "/ExportGoSecurityMatrix", "/SecurityMatrixParity"} {
+		assert.Equal(t, http.StatusForbidden, serveAs(handler, http.MethodGet, path, apiKey("manager-key")).Code, path)
+	}
+	response := serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", apiKey("manager-key"))
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	assert.Empty(t, store.Rules())
+
+	response = serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", apiKey("admin-key"))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	require.NotEmpty(t, store.Rules())
+	assert.Equal(t, http.StatusOK, serveAs(handler, http.MethodGet, "/DataQualityReport", apiKey("admin-key")).Code)
+
+	// Managers query their own access only, administrators anyone's
+	response = serveAs(handler, http.MethodGet, "/SecurityMatrixRules/"+managerUser, apiKey("manager-key"))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	var lookup struct {
+		UserId string `json:"user_id"`
+		Rules  []struct {
+			EmployeeId      uint
+			AccessLevelRead uint32
+		} `json:"rules"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &lookup))
+	assert.Equal(t, managerUser, lookup.UserId)
+	access_levels := accessLevels(store.Rules())[managerUser]
+	require.Len(t, lookup.Rules, len(access_levels))
+	for _, rule := range lookup.Rules {
+		assert.Equal(t, access_levels[rule.EmployeeId], rule.AccessLevelRead)
+	}
+
+	assert.Equal(t, http.StatusForbidden, serveAs(handler, http.MethodGet, "/SecurityMatrixRules/USR00000000000000001", apiKey("manager-key")).Code)
+	assert.Equal(t, http.StatusOK, serveAs(handler, http.MethodGet, "/SecurityMatrixRules/USR00000000000000001", apiKey("admin-key")).Code)
+}
+
+func TestAuthorizationWithBearerTokens(t *testing.T) {
+	jwks := jwkstest.NewServer(t)
+	handler, store := newServer(t, "testdata/small_org.yaml", authConfig(jwks))
+
+	token := func(email string) map[string]string {
+		now := time.Now()
+		claims := jwt.MapClaims{
+			"sub":            "subject-of-" + email,
+			"email":          email,
+			"email_verified": true,
+			"iss":            "https://issuer.example.com",
+			"aud":            "security-matrix-api",
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Hour).Unix(),
+		}
+		return map[string]string{"Authorization": "Bearer " + jwks.Sign(t, jwks.Keys()[0], claims)}
+	}
+
+	// Tokens are mapped to the user with the email of their "email" claim, once verified
+	assert.Equal(t, http.StatusForbidden, serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", token("user2@example.com")).Code)
+	assert.Equal(t, http.StatusForbidden, serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", token("stranger@example.com")).Code)
+	response := serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", token("user7@example.com"))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.NotEmpty(t, store.Rules())
+
+	assert.Equal(t, http.StatusOK, serveAs(handler, http.MethodGet, "/SecurityMatrixRules/"+managerUser, token("user2@example.com")).Code)
+	assert.Equal(t, http.StatusForbidden, serveAs(handler, http.MethodGet, "/SecurityMatrixRules/"+adminUser, token("user2@example.com")).Code)
+}
+
+func TestServerRefusesToStartWithoutAuthentication(t *testing.T) {
+	loaded, err := fixture.Load("testdata/small_org.yaml")
+	require.NoError(t, err)
+	store, err := loaded.Store()
+	require.NoError(t, err)
+
+	_, _, err = wire_di.InjectInMemory(&db.Config{}, store, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.ErrorContains(t, err, "no authenticator is configured")
+}
+
+func TestOpenAPIWhenAuthenticationIsDisabled(t *testing.T) {
+	handler, _ := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth})
+
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/SecurityMatrixRules/"+managerUser).Code)
+}
+
+func TestConfiguredBasePathAndProfileTypes(t *testing.T) {
+	config := authConfig(jwkstest.NewServer(t))
+	config.Server.BasePath = "/security-matrix/v1"
+	config.ProfileTypes.Admin = service.ProfileTypeId.EMPLOYEE // Every employee administers the API
+	handler, store := newServer(t, "testdata/small_org.yaml", config)
+
+	calculate := func(path string, key string) int {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, path+"/CalculateGoSecurityMatrix", nil)
+		request.Header.Set(middleware.APIKeyHeader, key)
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, calculate(basePath, "manager-key"))
+	assert.Equal(t, http.StatusForbidden, calculate("/security-matrix/v1", "admin-key"))
+	assert.Empty(t, store.Rules())
+	assert.Equal(t, http.StatusOK, calculate("/security-matrix/v1", "manager-key"))
+	assert.NotEmpty(t, store.Rules())
+
+	// The probes stay at the root
+	assert.Equal(t, http.StatusOK, probe(handler, "/healthz").Code)
+}
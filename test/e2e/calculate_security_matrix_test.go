@@ -0,0 +1,239 @@
+// Package e2e runs end-to-end HTTP tests of the API, assembled by Wire on top of the in-memory repositories.
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/fixture"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/repo/memory"
+	wire_di "github.com/nuno-bastos/gin-gonic-wire-api/wire_di"
+)
+
+const basePath = "/github.com/nuno-bastos/gin-gonic-wire-api"
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newServer loads the fixture into an in-memory store and assembles the server on top of it, without tracing.
+func newServer(t *testing.T, fixture_path string, config *db.Config) (http.Handler, *memory.Store) {
+	t.Helper()
+
+	return newTracedServer(t, fixture_path, config, nil)
+}
+
+// newTracedServer is newServer handing the spans to the given processor.
+func newTracedServer(t *testing.T, fixture_path string, config *db.Config, processor sdktrace.SpanProcessor) (http.Handler, *memory.Store) {
+	t.Helper()
+
+	return newInstrumentedServer(t, fixture_path, config, processor, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// newInstrumentedServer is newServer handing the spans to the given processor and logging with the given logger.
+func newInstrumentedServer(t *testing.T, fixture_path string, config *db.Config, processor sdktrace.SpanProcessor, logger *slog.Logger) (http.Handler, *memory.Store) {
+	t.Helper()
+
+	loaded, err := fixture.Load(fixture_path)
+	require.NoError(t, err)
+	store, err := loaded.Store()
+	require.NoError(t, err)
+
+	server, cleanup, err := wire_di.InjectInMemory(config, store, processor, logger)
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	return server.GetEngine(), store
+}
+
+func serve(handler http.Handler, method string, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, basePath+path, nil))
+
+	return recorder
+}
+
+// accessLevels indexes the rules by viewer user and employee.
+func accessLevels(rules []security_model.GoMatrixRule) map[string]map[uint]uint32 {
+	access_levels := make(map[string]map[uint]uint32)
+	for _, rule := range rules {
+		if access_levels[rule.UserId] == nil {
+			access_levels[rule.UserId] = make(map[uint]uint32)
+		}
+		access_levels[rule.UserId][rule.EmployeeId] = rule.AccessLevelRead
+	}
+
+	return access_levels
+}
+
+func TestCalculateGoSecurityMatrix(t *testing.T) {
+	handler, store := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth})
+
+	response := serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	access_levels := accessLevels(store.Rules())
+
+	// Managers see their whole reporting line, including inactive employees
+	for _, employee_id := range []uint{2, 3, 4, 5, 6} {
+		assert.Contains(t, access_levels["USR00000000000000001"], employee_id)
+	}
+	assert.Contains(t, access_levels["USR00000000000000002"], uint(4))
+	assert.Contains(t, access_levels["USR00000000000000002"], uint(5))
+	assert.Contains(t, access_levels["USR00000000000000003"], uint(6))
+
+	// But nobody outside of it, nor their own managers
+	assert.NotContains(t, access_levels["USR00000000000000002"], uint(6))
+	assert.NotContains(t, access_levels["USR00000000000000002"], uint(1))
+	assert.NotContains(t, access_levels["USR00000000000000003"], uint(4))
+
+	// Employees only see themselves
+	for _, user_id := range []string{"USR00000000000000004", "USR00000000000000005", "USR00000000000000006"} {
+		assert.Len(t, access_levels[user_id], 1, user_id)
+	}
+
+	// Users who are not employees get no rules
+	assert.NotContains(t, access_levels, "USR00000000000000007")
+
+	// The inputs were read from a snapshot taken during the calculation
+	last_run := freshness(t, handler).LastCalculation
+	require.NotNil(t, last_run)
+	require.NotNil(t, last_run.SnapshotAt)
+	assert.False(t, last_run.SnapshotAt.Before(last_run.StartedAt.Time) || last_run.SnapshotAt.After(last_run.FinishedAt.Time), "snapshot at %v", last_run.SnapshotAt)
+}
+
+func TestCalculateGoSecurityMatrixReplacesPreviousRules(t *testing.T) {
+	handler, store := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth})
+	require.NoError(t, memory.NewSecurityMatrixRuleRepository(store).WriteRules(context.Background(), []security_model.GoMatrixRule{
+		{UserId: "USR00000000000000099", EmployeeId: 1, AccessLevelRead: 7},
+	}))
+
+	response := serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	assert.NotContains(t, accessLevels(store.Rules()), "USR00000000000000099")
+}
+
+func TestInterruptedCalculationIsRolledBackAndRecorded(t *testing.T) {
+	handler, store := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth})
+	previous_rules := []security_model.GoMatrixRule{{UserId: "USR00000000000000099", EmployeeId: 1, AccessLevelRead: 7}}
+	require.NoError(t, memory.NewSecurityMatrixRuleRepository(store).WriteRules(context.Background(), previous_rules))
+
+	// As when the server interrupts the requests still running at the end of its shutdown timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, basePath+"/CalculateGoSecurityMatrix", nil).WithContext(ctx))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code, recorder.Body.String())
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Contains(t, body["error"], "interrupted")
+
+	// The previous matrix is kept, and the run is recorded as interrupted without counting as a calculation
+	assert.Equal(t, previous_rules, store.Rules())
+	runs := store.Runs()
+	require.Len(t, runs, 1)
+	assert.Equal(t, security_model.RunStatusInterrupted, runs[0].Status)
+	assert.Equal(t, body["run_id"], runs[0].RunId)
+	assert.NotNil(t, runs[0].SnapshotAt, "interrupted once its inputs were read")
+	assert.Nil(t, freshness(t, handler).LastCalculation)
+}
+
+func TestTimedOutCalculationIsRolledBack(t *testing.T) {
+	handler, store := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth, Calculation: db.CalculationConfig{Timeouts: db.TimeoutsConfig{Total: time.Nanosecond}}})
+
+	response := serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix")
+	require.Equal(t, http.StatusGatewayTimeout, response.Code, response.Body.String())
+	assert.Contains(t, response.Body.String(), "the calculation timed out after 1ns")
+
+	assert.Empty(t, store.Rules())
+	require.Len(t, store.Runs(), 1)
+	assert.Equal(t, security_model.RunStatusInterrupted, store.Runs()[0].Status)
+}
+
+func TestCalculateGoSecurityMatrixFromDirectManagers(t *testing.T) {
+	handler, store := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth})
+	require.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+
+	derived_handler, derived_store := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth, ManagerChainSource: "direct_managers"})
+	require.Equal(t, http.StatusOK, serve(derived_handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+
+	assert.Equal(t, sortedRules(store.Rules()), sortedRules(derived_store.Rules()))
+}
+
+func TestCalculateGoSecurityMatrixEnforcesDataQuality(t *testing.T) {
+	handler, store := newServer(t, "testdata/data_quality_issues.json", &db.Config{Auth: noAuth, EnforceDataQuality: true})
+
+	response := serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix")
+	require.Equal(t, http.StatusUnprocessableEntity, response.Code, response.Body.String())
+
+	var body struct {
+		Error  string                           `json:"error"`
+		Report security_model.DataQualityReport `json:"report"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.NotEmpty(t, body.Error)
+	assert.Equal(t, []security_model.DataQualityIssueType{
+		security_model.ManagerFlagWithoutManagerProfile,
+		security_model.ManagerFlagWithoutReports,
+		security_model.UnknownEmployee,
+	}, issueTypes(body.Report))
+
+	// Nothing is written
+	assert.Empty(t, store.Rules())
+}
+
+func TestDataQualityReport(t *testing.T) {
+	handler, _ := newServer(t, "testdata/data_quality_issues.json", &db.Config{Auth: noAuth})
+
+	response := serve(handler, http.MethodGet, "/DataQualityReport")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	var report security_model.DataQualityReport
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+	require.Len(t, report.Issues, 3)
+	assert.Equal(t, "USR00000000000000009", report.Issues[2].UserId)
+	assert.Equal(t, uint(9), report.Issues[2].EmployeeId)
+
+	// A clean fixture has no issues
+	handler, _ = newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth})
+	response = serve(handler, http.MethodGet, "/DataQualityReport")
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+	assert.Empty(t, report.Issues)
+}
+
+func issueTypes(report security_model.DataQualityReport) []security_model.DataQualityIssueType {
+	var issue_types []security_model.DataQualityIssueType
+	for _, issue := range report.Issues {
+		issue_types = append(issue_types, issue.Type)
+	}
+
+	return issue_types
+}
+
+func sortedRules(rules []security_model.GoMatrixRule) []security_model.GoMatrixRule {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].UserId != rules[j].UserId {
+			return rules[i].UserId < rules[j].UserId
+		}
+		return rules[i].EmployeeId < rules[j].EmployeeId
+	})
+
+	return rules
+}
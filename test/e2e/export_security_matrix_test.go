@@ -0,0 +1,52 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+func TestExportGoSecurityMatrix(t *testing.T) {
+	handler, store := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth})
+	require.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+
+	response := serve(handler, http.MethodGet, "/ExportGoSecurityMatrix?format=jsonl&enrich=true&decode_access=1")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, "application/jsonl", response.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="GoMatrixRule.jsonl"`, response.Header().Get("Content-Disposition"))
+
+	lines := strings.Split(strings.TrimSuffix(response.Body.String(), "\n"), "\n")
+	require.Len(t, lines, len(store.Rules()))
+
+	access_levels := accessLevels(store.Rules())
+	for _, line := range lines {
+		var rule struct {
+			UserId          string
+			EmployeeId      uint
+			AccessLevelRead uint32
+			UserEmail       string
+			GenericFields   bool
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &rule))
+		assert.Equal(t, access_levels[rule.UserId][rule.EmployeeId], rule.AccessLevelRead)
+		assert.Equal(t, "user"+strings.TrimLeft(rule.UserId[3:], "0")+"@example.com", rule.UserEmail)
+		assert.Equal(t, rule.AccessLevelRead&2 != 0, rule.GenericFields)
+	}
+
+	// CSV is the default format
+	response = serve(handler, http.MethodGet, "/ExportGoSecurityMatrix")
+	require.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "text/csv", response.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(response.Body.String(), "UserId,EmployeeId,AccessLevelRead\n"))
+
+	for _, query := range []string{"?format=xml", "?enrich=maybe"} {
+		response = serve(handler, http.MethodGet, "/ExportGoSecurityMatrix"+query)
+		assert.Equal(t, http.StatusBadRequest, response.Code, query)
+	}
+}
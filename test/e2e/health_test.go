@@ -0,0 +1,85 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+	"github.com/nuno-bastos/gin-gonic-wire-api/test/jwkstest"
+)
+
+// probe requests a path outside the base API URL.
+func probe(handler http.Handler, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return recorder
+}
+
+func freshness(t *testing.T, handler http.Handler) security_model.FreshnessReport {
+	t.Helper()
+
+	response := probe(handler, "/freshness")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	var report security_model.FreshnessReport
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+	return report
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	// The probes need no credentials, even when the API does
+	handler, store := newServer(t, "testdata/small_org.yaml", authConfig(jwkstest.NewServer(t)))
+
+	response := probe(handler, "/healthz")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, response.Body.String())
+
+	response = probe(handler, "/readyz")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"status":"ready","checks":[{"name":"database"},{"name":"migrations"}]}`, response.Body.String())
+
+	// Never calculated: stale
+	report := freshness(t, handler)
+	assert.Nil(t, report.LastCalculation)
+	assert.True(t, report.Stale)
+	assert.Equal(t, "24h0m0s", report.StalenessThreshold)
+
+	before := time.Now().UTC()
+	require.Equal(t, http.StatusOK, serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", apiKey("admin-key")).Code)
+
+	report = freshness(t, handler)
+	require.NotNil(t, report.LastCalculation)
+	assert.False(t, report.Stale)
+	assert.Equal(t, int64(len(store.Rules())), report.LastCalculation.Rules)
+	assert.False(t, report.LastCalculation.StartedAt.Before(before))
+	assert.False(t, report.LastCalculation.FinishedAt.Before(report.LastCalculation.StartedAt.Time))
+}
+
+func TestFreshnessReportsStaleMatrix(t *testing.T) {
+	handler, _ := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth, StalenessThreshold: time.Nanosecond})
+	require.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+
+	time.Sleep(time.Millisecond)
+	report := freshness(t, handler)
+	require.NotNil(t, report.LastCalculation)
+	assert.True(t, report.Stale)
+	assert.Greater(t, report.AgeSeconds, 0.0)
+	assert.Equal(t, "1ns", report.StalenessThreshold)
+}
+
+func TestFailedCalculationIsNotRecorded(t *testing.T) {
+	handler, _ := newServer(t, "testdata/data_quality_issues.json", &db.Config{Auth: noAuth, EnforceDataQuality: true})
+	require.Equal(t, http.StatusUnprocessableEntity, serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+
+	report := freshness(t, handler)
+	assert.Nil(t, report.LastCalculation)
+	assert.True(t, report.Stale)
+}
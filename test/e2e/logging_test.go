@@ -0,0 +1,92 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	middleware "github.com/nuno-bastos/gin-gonic-wire-api/api/middleware"
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	logging "github.com/nuno-bastos/gin-gonic-wire-api/logging"
+)
+
+// logRecords parses the JSON log lines.
+func logRecords(t *testing.T, output *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestCalculationLogLinesCarryTheRequestAndRunIds(t *testing.T) {
+	var output bytes.Buffer
+	logger, err := logging.NewLoggerTo(&output, &db.Config{})
+	require.NoError(t, err)
+	handler, store := newInstrumentedServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth}, nil, logger)
+	output.Reset()
+
+	response := serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", map[string]string{middleware.RequestIdHeader: "scheduler-1"})
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, "scheduler-1", response.Header().Get(middleware.RequestIdHeader))
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	require.NotEmpty(t, body["run_id"])
+
+	by_message := make(map[string]map[string]any)
+	for _, record := range logRecords(t, &output) {
+		assert.Equal(t, "scheduler-1", record["request_id"], record["msg"])
+		by_message[record["msg"].(string)] = record
+	}
+
+	for _, message := range []string{"Security matrix calculation requested", "Security matrix calculation started", "Security matrix calculation finished"} {
+		require.Contains(t, by_message, message)
+		assert.Equal(t, body["run_id"], by_message[message]["run_id"], message)
+	}
+	assert.EqualValues(t, len(store.Rules()), by_message["Security matrix calculation finished"]["rules"])
+
+	require.Contains(t, by_message, "Request served")
+	assert.EqualValues(t, http.StatusOK, by_message["Request served"]["status"])
+	assert.NotContains(t, by_message["Request served"], "run_id") // Logged with the request context only
+}
+
+func TestFailedCalculationIsLogged(t *testing.T) {
+	var output bytes.Buffer
+	logger, err := logging.NewLoggerTo(&output, &db.Config{})
+	require.NoError(t, err)
+	handler, _ := newInstrumentedServer(t, "testdata/data_quality_issues.json", &db.Config{Auth: noAuth, EnforceDataQuality: true}, nil, logger)
+	output.Reset()
+
+	response := serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix")
+	require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	_, err = uuid.Parse(response.Header().Get(middleware.RequestIdHeader))
+	require.NoError(t, err)
+
+	var failed map[string]any
+	for _, record := range logRecords(t, &output) {
+		if record["msg"] == "Security matrix calculation failed" {
+			failed = record
+		}
+	}
+	require.NotNil(t, failed)
+	assert.Equal(t, "WARN", failed["level"])
+	assert.Equal(t, "data_quality", failed["outcome"])
+	assert.Equal(t, response.Header().Get(middleware.RequestIdHeader), failed["request_id"])
+	assert.NotEmpty(t, failed["run_id"])
+	assert.NotEmpty(t, failed["error"])
+}
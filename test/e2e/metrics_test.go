@@ -0,0 +1,68 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	"github.com/nuno-bastos/gin-gonic-wire-api/test/jwkstest"
+)
+
+// scrape returns the metrics in the Prometheus text format.
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	response := probe(handler, "/metrics")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	return response.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	// Like the probes, the metrics need no credentials
+	handler, store := newServer(t, "testdata/small_org.yaml", authConfig(jwkstest.NewServer(t)))
+	require.Equal(t, http.StatusOK, serveAs(handler, http.MethodPost, "/CalculateGoSecurityMatrix", apiKey("admin-key")).Code)
+	require.Equal(t, http.StatusNotFound, probe(handler, "/no/such/route").Code)
+
+	metrics := scrape(t, handler)
+
+	assert.Contains(t, metrics, `security_matrix_runs_total{outcome="success"} 1`)
+	assert.Contains(t, metrics, fmt.Sprintf("security_matrix_rules_written_total %d\n", len(store.Rules())))
+
+	for _, stage := range []string{"FetchEmployeesWithAssociatedProfiles", "FindAll", "WriteRules"} {
+		assert.Contains(t, metrics, fmt.Sprintf(`security_matrix_stage_duration_seconds_count{stage="%s"} 1`, stage))
+	}
+	// Once per user
+	for _, stage := range []string{"CalculateRules", "Flatten", "RemoveRulesWithDefaultValues"} {
+		assert.Contains(t, metrics, fmt.Sprintf(`security_matrix_stage_duration_seconds_count{stage="%s"}`, stage))
+	}
+	for _, filter := range []string{"AllowReportsFilter", "AllowSelfFilter", "DenyManagersFilter", "DenyProfileLevelsFilter", "DenySelfFilter"} {
+		assert.Contains(t, metrics, fmt.Sprintf(`security_matrix_rules_emitted_total{origin_filter="%s"}`, filter))
+	}
+
+	// The pipeline is idle again
+	assert.Contains(t, metrics, "security_matrix_workers 0\n")
+	assert.Contains(t, metrics, "security_matrix_busy_workers 0\n")
+	assert.Contains(t, metrics, `security_matrix_channel_depth{channel="rules"} 0`)
+	assert.Contains(t, metrics, `security_matrix_channel_depth{channel="users"} 0`)
+
+	// Requests are labelled by route template, unknown paths share a single series
+	assert.Contains(t, metrics, fmt.Sprintf(`http_requests_total{method="POST",route="%s/CalculateGoSecurityMatrix",status="200"} 1`, basePath))
+	assert.Contains(t, metrics, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, metrics, fmt.Sprintf(`http_request_duration_seconds_count{method="POST",route="%s/CalculateGoSecurityMatrix",status="200"} 1`, basePath))
+	assert.Contains(t, metrics, "http_requests_in_flight 1\n") // The scrape itself
+	assert.Contains(t, metrics, "go_goroutines")
+}
+
+func TestMetricsCountRunsByOutcome(t *testing.T) {
+	handler, _ := newServer(t, "testdata/data_quality_issues.json", &db.Config{Auth: noAuth, EnforceDataQuality: true})
+	require.Equal(t, http.StatusUnprocessableEntity, serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+
+	metrics := scrape(t, handler)
+	assert.Contains(t, metrics, `security_matrix_runs_total{outcome="data_quality"} 1`)
+	assert.NotContains(t, metrics, `security_matrix_runs_total{outcome="success"}`)
+	assert.Contains(t, metrics, "security_matrix_rules_written_total 0\n")
+}
@@ -0,0 +1,35 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+func TestSecurityMatrixParity(t *testing.T) {
+	handler, store := newServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth})
+	require.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+
+	// The store holds no legacy matrix: every rule is unexpected
+	for _, query := range []string{"", "?source=stored", "?source=calculated"} {
+		response := serve(handler, http.MethodGet, "/SecurityMatrixParity"+query)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+		var report security_model.ParityReport
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+		assert.Equal(t, len(store.Rules()), report.Mismatches, query)
+		assert.Zero(t, report.LegacyRules, query)
+		for _, user := range report.ByUser {
+			assert.Equal(t, map[security_model.ParityMismatchType]int{security_model.UnexpectedRule: user.Mismatches}, user.ByType)
+		}
+	}
+
+	response := serve(handler, http.MethodGet, "/SecurityMatrixParity?source=legacy")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
@@ -0,0 +1,107 @@
+package e2e
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// remoteTraceParent is the W3C trace context of a caller, continued by the server span.
+const remoteTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// spansByName indexes the recorded spans by name.
+func spansByName(spans tracetest.SpanStubs) map[string][]tracetest.SpanStub {
+	by_name := make(map[string][]tracetest.SpanStub)
+	for _, span := range spans {
+		by_name[span.Name] = append(by_name[span.Name], span)
+	}
+
+	return by_name
+}
+
+// assertChildOf asserts that every span is a child of the parent span.
+func assertChildOf(t *testing.T, parent tracetest.SpanStub, children ...tracetest.SpanStub) {
+	t.Helper()
+
+	for _, child := range children {
+		assert.Equal(t, parent.SpanContext.SpanID(), child.Parent.SpanID(), "%s should be a child of %s", child.Name, parent.Name)
+	}
+}
+
+func TestCalculationIsTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	handler, _ := newTracedServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth}, sdktrace.NewSimpleSpanProcessor(exporter))
+
+	request := httptest.NewRequest(http.MethodPost, basePath+"/CalculateGoSecurityMatrix", nil)
+	request.Header.Set("traceparent", remoteTraceParent)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	spans := spansByName(exporter.GetSpans())
+
+	// The server span, named after the route, continues the trace of the caller
+	require.Len(t, spans[basePath+"/CalculateGoSecurityMatrix"], 1)
+	server_span := spans[basePath+"/CalculateGoSecurityMatrix"][0]
+	assert.Equal(t, trace.SpanKindServer, server_span.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server_span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server_span.Parent.SpanID().String())
+
+	require.Len(t, spans["CalculateGoSecurityMatrix"], 1)
+	calculation := spans["CalculateGoSecurityMatrix"][0]
+	assertChildOf(t, server_span, calculation)
+
+	for _, stage := range []string{"FetchManagerChains", "FetchEmployeesWithAssociatedProfiles", "BuildCalculationInput", "StreamRules", "WriteRules"} {
+		require.Len(t, spans[stage], 1, stage)
+		assertChildOf(t, calculation, spans[stage][0])
+	}
+
+	// Worker batches under the pipeline, filter executions under the worker that ran them
+	require.NotEmpty(t, spans["worker"])
+	assertChildOf(t, spans["StreamRules"][0], spans["worker"]...)
+	require.NotEmpty(t, spans["ExecuteFilter"])
+	worker_ids := make(map[trace.SpanID]bool)
+	for _, worker := range spans["worker"] {
+		worker_ids[worker.SpanContext.SpanID()] = true
+	}
+	for _, filter := range spans["ExecuteFilter"] {
+		assert.True(t, worker_ids[filter.Parent.SpanID()], "ExecuteFilter should be a child of a worker")
+	}
+}
+
+func TestProbesAreNotTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	handler, _ := newTracedServer(t, "testdata/small_org.yaml", &db.Config{Auth: noAuth}, sdktrace.NewSimpleSpanProcessor(exporter))
+
+	for _, path := range []string{"/healthz", "/readyz", "/metrics"} {
+		require.Equal(t, http.StatusOK, probe(handler, path).Code, path)
+	}
+	assert.Empty(t, exporter.GetSpans())
+
+	require.Equal(t, http.StatusOK, probe(handler, "/freshness").Code)
+	assert.Contains(t, spansByName(exporter.GetSpans()), "/freshness")
+}
+
+func TestFailedCalculationSpanRecordsTheError(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	handler, _ := newTracedServer(t, "testdata/data_quality_issues.json", &db.Config{Auth: noAuth, EnforceDataQuality: true}, sdktrace.NewSimpleSpanProcessor(exporter))
+	require.Equal(t, http.StatusUnprocessableEntity, serve(handler, http.MethodPost, "/CalculateGoSecurityMatrix").Code)
+
+	spans := spansByName(exporter.GetSpans())
+	require.Len(t, spans["CheckDataQuality"], 1)
+	require.Len(t, spans["CalculateGoSecurityMatrix"], 1)
+	assertChildOf(t, spans["CalculateGoSecurityMatrix"][0], spans["CheckDataQuality"][0])
+	for _, name := range []string{"CheckDataQuality", "CalculateGoSecurityMatrix"} {
+		assert.Equal(t, "Error", spans[name][0].Status.Code.String(), name)
+		assert.NotEmpty(t, spans[name][0].Events, name) // The recorded error
+	}
+	assert.NotContains(t, spans, "StreamRules")
+}
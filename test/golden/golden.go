@@ -0,0 +1,171 @@
+// Package golden compares security matrices to checked-in golden files.
+//
+// A golden file lists the sorted (UserId, EmployeeId, AccessLevelRead) rules of a matrix, one per line.
+// Run the tests with -update to regenerate the golden files from the current output:
+//
+//	go test ./service -run Golden -update
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	security_model "github.com/nuno-bastos/gin-gonic-wire-api/model/security"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden files instead of comparing them")
+
+const header = "# UserId\tEmployeeId\tAccessLevelRead\n"
+
+// maxDiffLines bounds the differences reported on failure.
+const maxDiffLines = 50
+
+// AssertRules compares the rules, in any order, to the golden file at path, or rewrites the golden
+// file when the tests run with -update.
+//
+// Parameters:
+// - t: The test.
+// - path: The path of the golden file.
+// - rules: The calculated rules.
+func AssertRules(t testing.TB, path string, rules []security_model.GoMatrixRule) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, FormatRules(rules), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist, run the test with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := ParseRules(content)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+
+	if diff := DiffRules(expected, rules); diff != "" {
+		t.Errorf("security matrix differs from %s (run the test with -update to accept the changes):\n%s", path, diff)
+	}
+}
+
+// FormatRules formats the rules, sorted by UserId and EmployeeId, as the content of a golden file.
+func FormatRules(rules []security_model.GoMatrixRule) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString(header)
+	for _, rule := range sortedRules(rules) {
+		fmt.Fprintf(&buffer, "%s\t%d\t%d\n", rule.UserId, rule.EmployeeId, rule.AccessLevelRead)
+	}
+
+	return buffer.Bytes()
+}
+
+// ParseRules parses the content of a golden file. Blank lines and lines starting with # are ignored.
+func ParseRules(content []byte) ([]security_model.GoMatrixRule, error) {
+	var rules []security_model.GoMatrixRule
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line_number := 1; scanner.Scan(); line_number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected UserId, EmployeeId and AccessLevelRead, got %q", line_number, line)
+		}
+		employee_id, err := strconv.ParseUint(fields[1], 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid EmployeeId: %w", line_number, err)
+		}
+		access_level_read, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid AccessLevelRead: %w", line_number, err)
+		}
+
+		rules = append(rules, security_model.GoMatrixRule{
+			UserId:          fields[0],
+			EmployeeId:      uint(employee_id),
+			AccessLevelRead: uint32(access_level_read),
+		})
+	}
+
+	return rules, scanner.Err()
+}
+
+// DiffRules describes, one rule per line, how the actual rules differ from the expected ones.
+//
+// Returns:
+// - string: the changed, missing and unexpected rules, or "" when both matrices are equal.
+func DiffRules(expected, actual []security_model.GoMatrixRule) string {
+	type ruleKey struct {
+		userId     string
+		employeeId uint
+	}
+
+	expected_access := make(map[ruleKey]uint32, len(expected))
+	for _, rule := range expected {
+		expected_access[ruleKey{rule.UserId, rule.EmployeeId}] = rule.AccessLevelRead
+	}
+	actual_access := make(map[ruleKey]uint32, len(actual))
+	for _, rule := range actual {
+		actual_access[ruleKey{rule.UserId, rule.EmployeeId}] = rule.AccessLevelRead
+	}
+
+	var lines []string
+	for _, rule := range sortedRules(expected) {
+		access_level_read, found := actual_access[ruleKey{rule.UserId, rule.EmployeeId}]
+		switch {
+		case !found:
+			lines = append(lines, fmt.Sprintf("  missing     %s -> employee %d: access %d", rule.UserId, rule.EmployeeId, rule.AccessLevelRead))
+		case access_level_read != rule.AccessLevelRead:
+			lines = append(lines, fmt.Sprintf("  changed     %s -> employee %d: access %d, want %d", rule.UserId, rule.EmployeeId, access_level_read, rule.AccessLevelRead))
+		}
+	}
+	for _, rule := range sortedRules(actual) {
+		if _, found := expected_access[ruleKey{rule.UserId, rule.EmployeeId}]; !found {
+			lines = append(lines, fmt.Sprintf("  unexpected  %s -> employee %d: access %d", rule.UserId, rule.EmployeeId, rule.AccessLevelRead))
+		}
+	}
+	if len(actual) != len(actual_access) {
+		lines = append(lines, fmt.Sprintf("  duplicated  %d rules share the same user and employee", len(actual)-len(actual_access)))
+	}
+
+	if len(lines) > maxDiffLines {
+		lines = append(lines[:maxDiffLines], fmt.Sprintf("  ... and %d more differences", len(lines)-maxDiffLines))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// sortedRules returns a copy of the rules sorted by UserId and EmployeeId.
+func sortedRules(rules []security_model.GoMatrixRule) []security_model.GoMatrixRule {
+	sorted := append([]security_model.GoMatrixRule{}, rules...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].UserId != sorted[j].UserId {
+			return sorted[i].UserId < sorted[j].UserId
+		}
+		return sorted[i].EmployeeId < sorted[j].EmployeeId
+	})
+
+	return sorted
+}
@@ -0,0 +1,175 @@
+// Package jwkstest is a local stand-in for the JWKS endpoint of an identity provider:
+// it serves the public keys of freshly generated RSA and EC signing keys, and signs tokens with them.
+package jwkstest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a signing key of the Server.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	signer crypto.Signer
+}
+
+// Server serves a JSON Web Key Set over HTTP until the test ends.
+type Server struct {
+	server  *httptest.Server
+	mutex   sync.Mutex
+	keys    []*Key
+	fetches atomic.Int64
+	delay   atomic.Int64 // time.Duration before answering
+	status  atomic.Int64 // HTTP status answered instead of the keys, 0 to serve them
+	next    int
+}
+
+// NewServer starts a Server holding an RS256 and an ES256 key.
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+
+	server := &Server{}
+	server.AddKey(t, jwt.SigningMethodRS256)
+	server.AddKey(t, jwt.SigningMethodES256)
+
+	server.server = httptest.NewServer(http.HandlerFunc(server.serveKeys))
+	t.Cleanup(server.server.Close)
+
+	return server
+}
+
+// URL returns the URL of the key set.
+func (s *Server) URL() string {
+	return s.server.URL + "/.well-known/jwks.json"
+}
+
+// Fetches returns how many times the key set was fetched.
+func (s *Server) Fetches() int {
+	return int(s.fetches.Load())
+}
+
+// SetDelay makes the Server wait for the given duration before answering, as a slow identity provider.
+func (s *Server) SetDelay(delay time.Duration) {
+	s.delay.Store(int64(delay))
+}
+
+// SetStatus makes the Server answer with the given HTTP status instead of the keys, 0 serving them again.
+func (s *Server) SetStatus(status int) {
+	s.status.Store(int64(status))
+}
+
+// Keys returns the keys currently served.
+func (s *Server) Keys() []*Key {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]*Key{}, s.keys...)
+}
+
+// AddKey generates a key signing with the given method (RS256 or ES256) and serves it from now on.
+func (s *Server) AddKey(t *testing.T, method jwt.SigningMethod) *Key {
+	t.Helper()
+
+	var signer crypto.Signer
+	var err error
+	switch method {
+	case jwt.SigningMethodRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		t.Fatalf("jwkstest: unsupported signing method %s", method.Alg())
+	}
+	if err != nil {
+		t.Fatalf("jwkstest: generating a %s key: %v", method.Alg(), err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.next++
+	key := &Key{ID: fmt.Sprintf("key-%d", s.next), Method: method, signer: signer}
+	s.keys = append(s.keys, key)
+
+	return key
+}
+
+// Rotate replaces every served key with a new one signing with the same method, as identity providers do.
+func (s *Server) Rotate(t *testing.T) {
+	t.Helper()
+
+	old_keys := s.Keys()
+	s.mutex.Lock()
+	s.keys = nil
+	s.mutex.Unlock()
+
+	for _, key := range old_keys {
+		s.AddKey(t, key.Method)
+	}
+}
+
+// Sign returns a token holding the claims, signed with the key.
+func (s *Server) Sign(t *testing.T, key *Key, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	signed, err := token.SignedString(key.signer)
+	if err != nil {
+		t.Fatalf("jwkstest: signing a token: %v", err)
+	}
+
+	return signed
+}
+
+func (s *Server) serveKeys(w http.ResponseWriter, r *http.Request) {
+	s.fetches.Add(1)
+
+	time.Sleep(time.Duration(s.delay.Load()))
+	if status := int(s.status.Load()); status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	var web_keys []map[string]string
+	for _, key := range s.Keys() {
+		web_key := map[string]string{"kid": key.ID, "use": "sig", "alg": key.Method.Alg()}
+		switch public_key := key.signer.Public().(type) {
+		case *rsa.PublicKey:
+			web_key["kty"] = "RSA"
+			web_key["n"] = encode(public_key.N)
+			web_key["e"] = encode(big.NewInt(int64(public_key.E)))
+		case *ecdsa.PublicKey:
+			web_key["kty"] = "EC"
+			web_key["crv"] = public_key.Curve.Params().Name
+			size := (public_key.Curve.Params().BitSize + 7) / 8
+			web_key["x"] = base64.RawURLEncoding.EncodeToString(public_key.X.FillBytes(make([]byte, size)))
+			web_key["y"] = base64.RawURLEncoding.EncodeToString(public_key.Y.FillBytes(make([]byte, size)))
+		}
+		web_keys = append(web_keys, web_key)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": web_keys})
+}
+
+func encode(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
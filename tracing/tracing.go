@@ -0,0 +1,139 @@
+// Package tracing sets up the OpenTelemetry tracer provider of the application and its span exporters.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+// Exporters accepted by the tracing.exporter setting.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// TracerName is the instrumentation scope of the spans started by the application itself.
+const TracerName = "github.com/nuno-bastos/gin-gonic-wire-api"
+
+// DefaultServiceName is the service.name of the spans when tracing.service_name is not set.
+const DefaultServiceName = "gin-gonic-wire-api"
+
+// shutdownTimeout bounds the export of the spans still buffered when the application stops.
+const shutdownTimeout = 5 * time.Second
+
+// Propagator reads and writes the W3C trace context and baggage of the HTTP requests.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// NewSpanProcessor creates the span processor exporting the spans as configured by tracing.exporter:
+// in batches to an OTLP/HTTP collector, pretty-printed on stdout, or nowhere.
+//
+// Parameters:
+// - config: The application configuration.
+//
+// Returns:
+// - sdktrace.SpanProcessor: The span processor, nil when tracing is disabled.
+// - error: An error if the exporter is unknown or cannot be created.
+func NewSpanProcessor(config *db.Config) (sdktrace.SpanProcessor, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch config.Tracing.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if config.Tracing.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Tracing.Endpoint))
+		}
+		if config.Tracing.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q: expected %s, %s or %s", config.Tracing.Exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating the %s span exporter: %w", config.Tracing.Exporter, err)
+	}
+
+	return sdktrace.NewBatchSpanProcessor(exporter), nil
+}
+
+// NewTracerProvider creates the tracer provider handing the spans to the given processor.
+// New traces are sampled at tracing.sample_ratio, spans whose parent was sampled are always recorded.
+//
+// Parameters:
+// - config: The application configuration.
+// - processor: The span processor, nil when tracing is disabled.
+// - _logger: The logger of the spans that could not be flushed.
+//
+// Returns:
+// - trace.TracerProvider: The tracer provider, a no-op provider when the processor is nil.
+// - func(): Flushes the buffered spans and stops the processor.
+// - error: An error if the sample ratio is out of range.
+func NewTracerProvider(config *db.Config, processor sdktrace.SpanProcessor, _logger *slog.Logger) (trace.TracerProvider, func(), error) {
+	if processor == nil {
+		return noop.NewTracerProvider(), func() {}, nil
+	}
+
+	sample_ratio := config.Tracing.SampleRatio
+	if sample_ratio == 0 {
+		sample_ratio = 1
+	}
+	if sample_ratio < 0 || sample_ratio > 1 {
+		return nil, nil, fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %v", config.Tracing.SampleRatio)
+	}
+
+	service_name := config.Tracing.ServiceName
+	if service_name == "" {
+		service_name = DefaultServiceName
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service_name))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sample_ratio))),
+		sdktrace.WithSpanProcessor(processor),
+	)
+
+	cleanup := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := provider.Shutdown(ctx); err != nil {
+			_logger.Error("Error flushing the spans", "error", err)
+		}
+	}
+
+	return provider, cleanup, nil
+}
+
+// End ends the span, recording the error and setting the span status to Error when err is not nil.
+//
+// Parameters:
+// - span: The span to end.
+// - err: The outcome of the operation traced by the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	db "github.com/nuno-bastos/gin-gonic-wire-api/db"
+)
+
+func TestTracingIsDisabledByDefault(t *testing.T) {
+	config := &db.Config{}
+
+	processor, err := NewSpanProcessor(config)
+	require.NoError(t, err)
+	assert.Nil(t, processor)
+
+	provider, cleanup, err := NewTracerProvider(config, processor, slog.Default())
+	require.NoError(t, err)
+	defer cleanup()
+
+	_, span := provider.Tracer(TracerName).Start(context.Background(), "span")
+	assert.False(t, span.IsRecording())
+}
+
+func TestNewSpanProcessorRejectsUnknownExporters(t *testing.T) {
+	_, err := NewSpanProcessor(&db.Config{Tracing: db.TracingConfig{Exporter: "jaeger"}})
+	assert.ErrorContains(t, err, "jaeger")
+}
+
+// keptExporter keeps the exported spans once shut down, tracetest.InMemoryExporter forgets them.
+type keptExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (keptExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestNewTracerProviderFlushesOnCleanup(t *testing.T) {
+	exporter := keptExporter{tracetest.NewInMemoryExporter()}
+	processor := sdktrace.NewBatchSpanProcessor(exporter)
+
+	_, _, err := NewTracerProvider(&db.Config{Tracing: db.TracingConfig{SampleRatio: 1.5}}, processor, slog.Default())
+	assert.ErrorContains(t, err, "sample_ratio")
+
+	provider, cleanup, err := NewTracerProvider(&db.Config{Tracing: db.TracingConfig{ServiceName: "matrix"}}, processor, slog.Default())
+	require.NoError(t, err)
+
+	_, span := provider.Tracer(TracerName).Start(context.Background(), "span")
+	require.True(t, span.IsRecording())
+	span.End()
+	assert.Empty(t, exporter.GetSpans()) // Still batched
+
+	cleanup()
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Contains(t, spans[0].Resource.Attributes(), attribute.String("service.name", "matrix"))
+}